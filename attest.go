@@ -0,0 +1,161 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Attestation is a signed statement of the migrations applied to a database
+// after a run, which deployment systems can store to verify later that the
+// state of the database matches a release.
+type Attestation struct {
+	// RunID identifies the run.
+	RunID string `json:"run_id"`
+	// CreatedAt is the time the run completed.
+	CreatedAt time.Time `json:"created_at"`
+	// Database identifies the database, ie. "app@10.0.0.12:5432".
+	Database string `json:"database"`
+	// Migrations are the migrations applied to the database once the run
+	// completed, sorted by ID.
+	Migrations []AttestedMigration `json:"migrations"`
+	// Signature is the signature of the other fields encoded as JSON.
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// AttestedMigration is an applied migration of an Attestation.
+type AttestedMigration struct {
+	ID string `json:"id"`
+	// Checksum is the checksum of the migration when it is known.
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// payload returns the signed content of the attestation.
+func (a *Attestation) payload() ([]byte, error) {
+	unsigned := *a
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// VerifyAttestation verifies the signature of an attestation with the public
+// key of the signer. Ed25519, ECDSA and RSA PKCS #1 v1.5 keys are supported.
+func VerifyAttestation(a *Attestation, pub crypto.PublicKey) error {
+	payload, err := a.payload()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(payload)
+
+	var ok bool
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, payload, a.Signature)
+	case *ecdsa.PublicKey:
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(a.Signature, &sig); err != nil {
+			return ErrInvalidAttestation
+		}
+		ok = ecdsa.Verify(key, digest[:], sig.R, sig.S)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], a.Signature) == nil
+	default:
+		return fmt.Errorf("sqlxmigrate: Unsupported public key type %T", pub)
+	}
+	if !ok {
+		return ErrInvalidAttestation
+	}
+	return nil
+}
+
+// attest adds the attestation of the state of the database to the result of
+// a successful run, when Options.AttestationSigner is set.
+func (g *Sqlxmigrate) attest(ctx context.Context, res *Result) (*Result, error) {
+	signer := g.options.AttestationSigner
+	if signer == nil {
+		return res, nil
+	}
+
+	database, err := g.databaseIdentity(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	applied, err := g.appliedIDs(ctx)
+	if err != nil {
+		return res, err
+	}
+	sort.Strings(applied)
+
+	a := &Attestation{
+		RunID:     g.runID,
+		CreatedAt: time.Now().UTC(),
+		Database:  database,
+	}
+	metadata, err := g.appliedMetadata(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	for _, id := range applied {
+		m := AttestedMigration{ID: id, Checksum: metadata[id].checksum}
+		if id == initSchemaMigrationID {
+			m.Checksum = g.initChecksum
+		}
+		a.Migrations = append(a.Migrations, m)
+	}
+
+	payload, err := a.payload()
+	if err != nil {
+		return res, err
+	}
+
+	if a.Signature, err = sign(signer, payload); err != nil {
+		return res, err
+	}
+
+	res.Attestation = a
+	return res, nil
+}
+
+// sign signs the payload of an attestation. Ed25519 signs the payload
+// itself, the other algorithms its SHA-256 digest.
+func sign(signer crypto.Signer, payload []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(payload)
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// databaseIdentity returns the name and address of the database.
+func (g *Sqlxmigrate) databaseIdentity(ctx context.Context) (string, error) {
+	var query string
+	switch {
+	case g.isPostgres():
+		query = "SELECT current_database() || '@' || coalesce(host(inet_server_addr()), 'localhost') || ':' || coalesce(inet_server_port(), 0)"
+	case g.isMySQL():
+		query = "SELECT CONCAT(DATABASE(), '@', @@hostname, ':', @@port)"
+	case g.isSQLServer():
+		query = "SELECT CONCAT(DB_NAME(), '@', @@SERVERNAME)"
+	default:
+		return g.db.DriverName(), nil
+	}
+	g.logf("databaseIdentity %s", query)
+
+	var identity string
+	if err := g.db.QueryRowContext(ctx, query).Scan(&identity); err != nil {
+		err = queryError(err, query)
+		return "", err
+	}
+	return identity, nil
+}
@@ -0,0 +1,63 @@
+package sqlxmigrate
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestation(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		options := *DefaultOptions
+		options.AttestationSigner = priv
+		m := New(db, &options, migrations)
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		require.NotNil(t, res.Attestation)
+
+		a := res.Attestation
+		assert.Len(t, a.RunID, 32)
+		assert.NotEmpty(t, a.Database)
+		assert.Equal(t, []AttestedMigration{{ID: "201608301400"}, {ID: "201608301430"}}, a.Migrations)
+		assert.NoError(t, VerifyAttestation(a, pub))
+
+		a.Migrations = a.Migrations[:1]
+		assert.Equal(t, ErrInvalidAttestation, VerifyAttestation(a, pub))
+	})
+}
+
+func TestAttestationDisabled(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Nil(t, res.Attestation)
+	})
+}
+
+func TestVerifyAttestationECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	a := &Attestation{RunID: "run", Database: "db", Migrations: []AttestedMigration{{ID: "201608301400"}}}
+	payload, err := a.payload()
+	require.NoError(t, err)
+	a.Signature, err = sign(priv, payload)
+	require.NoError(t, err)
+
+	assert.NoError(t, VerifyAttestation(a, &priv.PublicKey))
+
+	a.Database = "other"
+	assert.Equal(t, ErrInvalidAttestation, VerifyAttestation(a, &priv.PublicKey))
+}
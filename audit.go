@@ -0,0 +1,94 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	auditMigrate  = "migrate"
+	auditRollback = "rollback"
+	// The migrations recorded as applied or rolled back without running
+	// them, by MarkApplied and MarkRolledBack.
+	auditMarkApplied    = "mark_applied"
+	auditMarkRolledBack = "mark_rolled_back"
+	// The migrations recorded as applied by Baseline.
+	auditBaseline = "baseline"
+)
+
+// auditUsageColumns are the columns of the audit table recording the Usage
+// of the migrations, added to the tables created before them.
+var auditUsageColumns = []string{"wall_time_ms", "db_time_ms", "rows_read", "rows_written", "lock_wait_ms"}
+
+func (g *Sqlxmigrate) createAuditTableIfNotExists(ctx context.Context) error {
+	if g.options.AuditTableName == "" {
+		return nil
+	}
+	if ok, err := g.tableExists(ctx, g.options.AuditTableName); err != nil {
+		return err
+	} else if ok {
+		return g.upgradeAuditTable(ctx)
+	}
+
+	usageColumns := ""
+	for _, c := range auditUsageColumns {
+		usageColumns += ", " + c + " BIGINT NULL"
+	}
+	sql := fmt.Sprintf("CREATE TABLE %s (%s VARCHAR(%d) NOT NULL, direction VARCHAR(16) NOT NULL, statements INTEGER NOT NULL, rows_affected BIGINT NOT NULL, executed_at %s%s)",
+		g.options.AuditTableName, g.options.IDColumnName, g.options.IDColumnSize, g.columnType("TIMESTAMP NULL"), usageColumns)
+	g.logf("createAuditTableIfNotExists %s", sql)
+
+	if _, err := g.db.ExecContext(ctx, sql); err != nil {
+		err = queryError(err, sql)
+		return err
+	}
+	return nil
+}
+
+// upgradeAuditTable adds the usage columns missing from the audit table.
+func (g *Sqlxmigrate) upgradeAuditTable(ctx context.Context) error {
+	for _, c := range auditUsageColumns {
+		ok, err := g.hasColumn(ctx, g.options.AuditTableName, c)
+		if err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+
+		sql := g.dialect().AddColumnSQL(g.options.AuditTableName, c, "BIGINT NULL")
+		g.logf("upgradeAuditTable %s", sql)
+
+		if _, err := g.db.ExecContext(ctx, sql); err != nil {
+			err = queryError(err, sql)
+			return err
+		}
+	}
+	return nil
+}
+
+// insertAudit records a migration or a rollback in the audit table, as part
+// of the current transaction. The usage columns are NULL without a usage.
+func (g *Sqlxmigrate) insertAudit(ctx context.Context, id, direction string, stats Stats, usage *Usage) error {
+	if g.options.AuditTableName == "" {
+		return nil
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (%s, direction, statements, rows_affected, executed_at, %s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		g.options.AuditTableName, g.options.IDColumnName, strings.Join(auditUsageColumns, ", "))
+	sql = g.rebind(sql)
+	g.logf("Migration %s - %s", id, sql)
+
+	args := []interface{}{id, direction, stats.Statements, stats.RowsAffected, time.Now().UTC()}
+	if usage != nil {
+		args = append(args, usage.WallTime.Milliseconds(), usage.DBTime.Milliseconds(), usage.RowsRead, usage.RowsWritten, usage.LockWait.Milliseconds())
+	} else {
+		args = append(args, nil, nil, nil, nil, nil)
+	}
+	if _, err := g.tx.ExecContext(ctx, sql, args...); err != nil {
+		err = queryError(err, sql)
+		return err
+	}
+	return nil
+}
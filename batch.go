@@ -0,0 +1,179 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// BatchOptions configures the batches of Batched.
+type BatchOptions struct {
+	// Table is the table whose keys are split in batches.
+	Table string
+	// KeyColumn is the integer key of the table, ie. its primary key.
+	KeyColumn string
+	// BatchSize is the number of consecutive keys per batch. Defaults to
+	// 1000.
+	BatchSize int64
+	// Pause is waited between two batches, to leave room for the other
+	// writes and for the replicas to catch up.
+	Pause time.Duration
+	// Progress is called after each batch.
+	Progress func(BatchProgress)
+	// MaxReplicaLag pauses the batches while the replication lag of the
+	// replicas is over it, resuming once they caught up. The lag is read by
+	// ReplicaLag, from pg_stat_replication on PostgreSQL when nil. Only
+	// BatchedDB supports it, Batched fails with ErrReplicaLagInTransaction.
+	// Disabled when 0.
+	MaxReplicaLag time.Duration
+	// ReplicaLag returns the replication lag for MaxReplicaLag, ie.
+	// MySQLReplicaLag with the connections to the replicas.
+	ReplicaLag ReplicaLagFunc
+	// ReplicaLagPollInterval is how often the replication lag is read while
+	// it is over MaxReplicaLag. Defaults to 1s.
+	ReplicaLagPollInterval time.Duration
+	// WALBudget is the bytes of WAL, or binary log on MySQL, the batches may
+	// generate: they stop with a *WALBudgetError once it is exceeded, to
+	// protect the replicas and the WAL archive. MySQL writes the binary log
+	// of a transaction when it commits, so the budget only applies to
+	// BatchedDB there. Only PostgreSQL and MySQL are supported. Disabled
+	// when 0.
+	WALBudget int64
+}
+
+// BatchProgress is the progress of Batched after a batch.
+type BatchProgress struct {
+	// Batch is the number of batches executed.
+	Batch int
+	// Key is the last key of the batch.
+	Key int64
+	// MaxKey is the last key of the table when Batched started.
+	MaxKey int64
+	// RowsAffected is the number of rows affected by the batches so far.
+	RowsAffected int64
+}
+
+// Batched executes a statement on consecutive ranges of keys of a table, so
+// a large table is backfilled with short statements instead of one locking
+// it for minutes. The statement has two ? bindvars, the key the range
+// starts after and the last key of the range:
+//
+//	UPDATE people SET name_lower = lower(name) WHERE id > ? AND id <= ?
+//
+// The batches go up to the last key of the table when Batched starts, the
+// rows inserted afterwards being expected to be handled by the application.
+// They stop when the context of the run is done. Batched returns the number
+// of rows affected by the batches.
+//
+// The batches run in the transaction of the migration, which holds the locks
+// of the rows until it commits: a migration disabling the transaction uses
+// BatchedDB to commit each batch on its own. The replicas don't receive the
+// batches before the migration commits, so BatchOptions.MaxReplicaLag is
+// rejected with ErrReplicaLagInTransaction.
+func Batched(tx *Tx, query string, opts *BatchOptions) (int64, error) {
+	if opts != nil && opts.MaxReplicaLag > 0 {
+		return 0, ErrReplicaLagInTransaction
+	}
+	logf := func(format string, args ...interface{}) {
+		tx.g.logf("Migration %s - "+format, append([]interface{}{tx.migrationID}, args...)...)
+	}
+	return batched(tx.ctx, tx, tx.g.db.DriverName(), tx.g.rebind, logf, query, opts)
+}
+
+// BatchedDB is Batched outside of a transaction, each batch being committed
+// on its own, ie. in the MigrateNoTx of a migration disabling the
+// transaction.
+func BatchedDB(ctx context.Context, db *sqlx.DB, query string, opts *BatchOptions) (int64, error) {
+	return batched(ctx, db, db.DriverName(), db.Rebind, func(string, ...interface{}) {}, query, opts)
+}
+
+func batched(ctx context.Context, e Execer, driverName string, rebind func(string) string, logf func(string, ...interface{}), query string, opts *BatchOptions) (int64, error) {
+	if opts == nil || opts.Table == "" || opts.KeyColumn == "" {
+		return 0, errors.New("sqlxmigrate: Batched requires the Table and KeyColumn of BatchOptions")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	var minKey, maxKey sql.NullInt64
+	bounds := fmt.Sprintf("SELECT min(%[1]s), max(%[1]s) FROM %[2]s", opts.KeyColumn, opts.Table)
+	if err := e.QueryRowContext(ctx, bounds).Scan(&minKey, &maxKey); err != nil {
+		err = queryError(err, bounds)
+		return 0, err
+	}
+	if !minKey.Valid {
+		return 0, nil
+	}
+
+	lagFunc := opts.ReplicaLag
+	if opts.MaxReplicaLag > 0 && lagFunc == nil {
+		if !isPostgresDriver(driverName) {
+			return 0, ErrUnsupportedDriver
+		}
+		lagFunc = postgresReplicaLag(e)
+	}
+
+	var walStart int64
+	if opts.WALBudget > 0 {
+		var err error
+		if walStart, err = walPosition(ctx, e, driverName); err != nil {
+			return 0, err
+		}
+	}
+
+	query = rebind(query)
+	progress := BatchProgress{MaxKey: maxKey.Int64}
+	for last := minKey.Int64 - 1; last < maxKey.Int64; last += batchSize {
+		if progress.Batch > 0 && opts.Pause > 0 {
+			select {
+			case <-time.After(opts.Pause):
+			case <-ctx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return progress.RowsAffected, err
+		}
+		if progress.Batch > 0 && opts.MaxReplicaLag > 0 {
+			if err := waitReplicaLag(ctx, lagFunc, opts, logf, progress.Batch); err != nil {
+				return progress.RowsAffected, err
+			}
+		}
+
+		upper := last + batchSize
+		if upper > maxKey.Int64 {
+			upper = maxKey.Int64
+		}
+		res, err := e.ExecContext(ctx, query, last, upper)
+		if err != nil {
+			return progress.RowsAffected, err
+		}
+		// Not every driver reports the number of affected rows.
+		if n, err := res.RowsAffected(); err == nil {
+			progress.RowsAffected += n
+		}
+
+		progress.Batch++
+		progress.Key = upper
+		logf("batch %d - keys up to %d of %d, %d rows affected", progress.Batch, upper, maxKey.Int64, progress.RowsAffected)
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+
+		if opts.WALBudget > 0 {
+			pos, err := walPosition(ctx, e, driverName)
+			if err != nil {
+				return progress.RowsAffected, err
+			}
+			if pos-walStart > opts.WALBudget {
+				logf("batch %d - %d bytes of WAL generated, over the budget of %d", progress.Batch, pos-walStart, opts.WALBudget)
+				return progress.RowsAffected, &WALBudgetError{Table: opts.Table, Bytes: pos - walStart, Budget: opts.WALBudget}
+			}
+		}
+	}
+	return progress.RowsAffected, nil
+}
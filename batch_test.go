@@ -0,0 +1,139 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatched(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		_, err := db.Exec(`CREATE TABLE cars (id int PRIMARY KEY, name varchar(20))`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO cars (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e')`)
+		require.NoError(t, err)
+
+		// The batches stay under the maximum of rows affected by a statement.
+		options := *DefaultOptions
+		options.MaxRowsAffected = 2
+
+		var progress []BatchProgress
+		var affected int64
+		backfill := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				var err error
+				affected, err = Batched(tx, "UPDATE cars SET name = 'backfilled' WHERE id > ? AND id <= ?", &BatchOptions{
+					Table:     "cars",
+					KeyColumn: "id",
+					BatchSize: 2,
+					Progress: func(p BatchProgress) {
+						progress = append(progress, p)
+					},
+				})
+				return err
+			},
+			RollbackTx: func(tx *Tx) error {
+				return nil
+			},
+		}
+		m := New(db, &options, []*Migration{backfill})
+		require.NoError(t, m.Migrate())
+
+		assert.Equal(t, int64(5), affected)
+		require.Len(t, progress, 3)
+		assert.Equal(t, BatchProgress{Batch: 3, Key: progress[2].MaxKey, MaxKey: progress[2].MaxKey, RowsAffected: 5}, progress[2])
+		var count int
+		require.NoError(t, db.Get(&count, "SELECT count(*) FROM cars WHERE name = 'backfilled'"))
+		assert.Equal(t, 5, count)
+
+		require.NoError(t, m.RollbackLast())
+	})
+}
+
+func TestBatchedDB(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		_, err := db.Exec(`CREATE TABLE cars (id int PRIMARY KEY, name varchar(20))`)
+		require.NoError(t, err)
+
+		opts := &BatchOptions{Table: "cars", KeyColumn: "id"}
+		query := "UPDATE cars SET name = 'backfilled' WHERE id > ? AND id <= ?"
+		affected, err := BatchedDB(context.Background(), db, query, opts)
+		require.NoError(t, err)
+		assert.Zero(t, affected)
+
+		_, err = db.Exec(`INSERT INTO cars (id, name) VALUES (1, 'a'), (2, 'b')`)
+		require.NoError(t, err)
+		affected, err = BatchedDB(context.Background(), db, query, opts)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), affected)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = BatchedDB(ctx, db, query, opts)
+		assert.Error(t, err)
+
+		_, err = BatchedDB(context.Background(), db, query, &BatchOptions{Table: "cars"})
+		assert.Error(t, err)
+	})
+}
+
+func TestBatchedReplicaLag(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		_, err := db.Exec(`CREATE TABLE cars (id int PRIMARY KEY, name varchar(20))`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO cars (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c')`)
+		require.NoError(t, err)
+
+		// The replicas fall behind after the first batch, then catch up.
+		lags := []time.Duration{3 * time.Second, 2 * time.Second, 0, 0}
+		var polls int
+		opts := &BatchOptions{
+			Table:                  "cars",
+			KeyColumn:              "id",
+			BatchSize:              1,
+			MaxReplicaLag:          time.Second,
+			ReplicaLagPollInterval: time.Millisecond,
+			ReplicaLag: func(ctx context.Context) (time.Duration, error) {
+				lag := lags[polls]
+				polls++
+				return lag, nil
+			},
+		}
+		query := "UPDATE cars SET name = 'backfilled' WHERE id > ? AND id <= ?"
+		affected, err := BatchedDB(context.Background(), db, query, opts)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), affected)
+		assert.Equal(t, 4, polls)
+
+		errLag := errors.New("replica unreachable")
+		opts.ReplicaLag = func(ctx context.Context) (time.Duration, error) {
+			return 0, errLag
+		}
+		affected, err = BatchedDB(context.Background(), db, query, opts)
+		assert.True(t, errors.Is(err, errLag))
+		assert.Equal(t, int64(1), affected)
+
+		// The lag is only read from pg_stat_replication on PostgreSQL.
+		opts.ReplicaLag = nil
+		_, err = BatchedDB(context.Background(), db, query, opts)
+		assert.True(t, errors.Is(err, ErrUnsupportedDriver))
+
+		// The batches in the transaction of the migration can't wait for the
+		// replicas.
+		m := New(db, DefaultOptions, []*Migration{{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				_, err := Batched(tx, query, opts)
+				return err
+			},
+		}})
+		assert.True(t, errors.Is(m.Migrate(), ErrReplicaLagInTransaction))
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+	}, "sqlite3", "mysql", "sqlserver")
+}
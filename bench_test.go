@@ -0,0 +1,131 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+// benchMigrations returns n migrations that don't change anything.
+func benchMigrations(n int) []*Migration {
+	migrations := make([]*Migration, n)
+	for i := range migrations {
+		migrations[i] = &Migration{
+			ID: fmt.Sprintf("%012d", i+1),
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+	}
+	return migrations
+}
+
+func newBenchMigrator(db *sqlx.DB, migrations []*Migration) *Sqlxmigrate {
+	options := *DefaultOptions
+	m := New(db, &options, migrations)
+	m.SetLogger(log.New(ioutil.Discard, "", 0))
+	return m
+}
+
+// BenchmarkPlan1k measures planning a large history with nothing pending, as
+// on the startup of an application.
+func BenchmarkPlan1k(b *testing.B) {
+	migrations := benchMigrations(1000)
+	history := &memoryHistory{}
+	for _, m := range migrations {
+		history.ids = append(history.ids, m.ID)
+	}
+	m := newBenchMigrator(nil, migrations)
+	m.SetHistoryStore(history)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		planned, err := m.planMigrations(context.Background(), "")
+		if err != nil || len(planned) != 0 {
+			b.Fatal(planned, err)
+		}
+	}
+}
+
+// BenchmarkPending1k measures listing the pending migrations of a large
+// history.
+func BenchmarkPending1k(b *testing.B) {
+	migrations := benchMigrations(1000)
+	history := &memoryHistory{}
+	for _, m := range migrations[:999] {
+		history.ids = append(history.ids, m.ID)
+	}
+	m := newBenchMigrator(nil, migrations)
+	m.SetHistoryStore(history)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pending, err := m.Pending()
+		if err != nil || len(pending) != 1 {
+			b.Fatal(pending, err)
+		}
+	}
+}
+
+// BenchmarkAppliedIDs1k measures loading a large history from the migration
+// table.
+func BenchmarkAppliedIDs1k(b *testing.B) {
+	forEachDatabase(b, func(db *sqlx.DB) {
+		m := newBenchMigrator(db, benchMigrations(1000))
+		require.NoError(b, m.Migrate())
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			ids, err := m.appliedIDs(context.Background())
+			if err != nil || len(ids) != 1000 {
+				b.Fatal(len(ids), err)
+			}
+		}
+	})
+}
+
+// BenchmarkMigrate100 measures the overhead of each migration of a run.
+func BenchmarkMigrate100(b *testing.B) {
+	forEachDatabase(b, func(db *sqlx.DB) {
+		migrations := benchMigrations(100)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			require.NoError(b, dropTableIfExists(db, "migrations"))
+			m := newBenchMigrator(db, migrations)
+			b.StartTimer()
+
+			if err := m.Migrate(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkPendingCount1k measures the readiness check of a large history.
+func BenchmarkPendingCount1k(b *testing.B) {
+	forEachDatabase(b, func(db *sqlx.DB) {
+		m := newBenchMigrator(db, benchMigrations(1000))
+		require.NoError(b, m.Migrate())
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			n, err := m.PendingCount()
+			if err != nil || n != 0 {
+				b.Fatal(n, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// BloatEstimate is the estimated bloat of a table and its indexes.
+type BloatEstimate struct {
+	Table string
+	// Size is the size of the table and its indexes, in bytes.
+	Size int64
+	// Ratio is the share of the table that is bloat: the dead rows on
+	// PostgreSQL, the free space of the table and its indexes on MySQL.
+	Ratio float64
+}
+
+// The bloat queries return the size and the bloat ratio of a table.
+const (
+	postgresBloatQuery = `SELECT pg_total_relation_size(relid),
+			CASE WHEN n_live_tup + n_dead_tup = 0 THEN 0 ELSE n_dead_tup::float / (n_live_tup + n_dead_tup) END
+		FROM pg_stat_user_tables
+		WHERE schemaname = current_schema() AND relname = ?`
+	mysqlBloatQuery = `SELECT data_length + index_length,
+			CASE WHEN data_length + index_length + data_free = 0 THEN 0 ELSE data_free / (data_length + index_length + data_free) END
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = ?`
+)
+
+// BloatEstimates returns the estimated bloat of tables, from the dead rows
+// counted by pg_stat_user_tables on PostgreSQL and the free space of
+// information_schema.tables on MySQL. The statistics are updated
+// asynchronously, so the estimates can lag behind the last statements. Only
+// PostgreSQL and MySQL are supported.
+func (g *Sqlxmigrate) BloatEstimates(tables ...string) ([]BloatEstimate, error) {
+	return g.bloatEstimates(context.Background(), tables)
+}
+
+func (g *Sqlxmigrate) bloatEstimates(ctx context.Context, tables []string) ([]BloatEstimate, error) {
+	var query string
+	switch {
+	case g.isPostgres():
+		query = postgresBloatQuery
+	case g.isMySQL():
+		query = mysqlBloatQuery
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+	query = g.rebind(query)
+
+	var estimates []BloatEstimate
+	for _, table := range tables {
+		e := BloatEstimate{Table: table}
+		err := g.db.QueryRowContext(ctx, query, table).Scan(&e.Size, &e.Ratio)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			err = queryError(err, query)
+			return nil, err
+		}
+		estimates = append(estimates, e)
+	}
+	return estimates, nil
+}
+
+// vacuumSQL returns the statement reclaiming the bloat of a table.
+func (g *Sqlxmigrate) vacuumSQL(table string) string {
+	if g.isMySQL() {
+		return fmt.Sprintf("OPTIMIZE TABLE %s", table)
+	}
+	return fmt.Sprintf("VACUUM (ANALYZE) %s", table)
+}
+
+// adviseBloat checks the tables of Migration.HeavyDMLTables once the
+// migrations of the run applied: they are vacuumed with
+// Options.VacuumHeavyDML, otherwise their bloat above Options.BloatThreshold
+// is reported as a warning. Nothing is done on the databases other than
+// PostgreSQL and MySQL.
+func (g *Sqlxmigrate) adviseBloat(ctx context.Context, res *Result) error {
+	if g.options.BloatThreshold <= 0 && !g.options.VacuumHeavyDML {
+		return nil
+	}
+	if !g.isPostgres() && !g.isMySQL() {
+		return nil
+	}
+
+	// The tables are checked once, for the first migration of the run
+	// flagging them.
+	heavy := make(map[string][]string)
+	for _, m := range g.migrations {
+		if len(m.HeavyDMLTables) > 0 {
+			heavy[m.ID] = m.HeavyDMLTables
+		}
+	}
+	var tables []string
+	migrationIDs := make(map[string]string)
+	for _, r := range res.Migrations {
+		for _, table := range heavy[r.ID] {
+			if _, ok := migrationIDs[table]; !ok {
+				migrationIDs[table] = r.ID
+				tables = append(tables, table)
+			}
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	if g.options.VacuumHeavyDML {
+		for _, table := range tables {
+			sql := g.vacuumSQL(table)
+			g.logf("Migration %s - %s", migrationIDs[table], sql)
+			if _, err := g.db.ExecContext(ctx, sql); err != nil {
+				err = queryError(err, sql)
+				return err
+			}
+		}
+		return nil
+	}
+
+	estimates, err := g.bloatEstimates(ctx, tables)
+	if err != nil {
+		return err
+	}
+	for _, e := range estimates {
+		if e.Ratio < g.options.BloatThreshold {
+			continue
+		}
+		g.warn(res, Warning{
+			Code:        WarningTableBloat,
+			MigrationID: migrationIDs[e.Table],
+			Message:     fmt.Sprintf("table %s of %d bytes is an estimated %.0f%% bloat, consider %s", e.Table, e.Size, e.Ratio*100, g.vacuumSQL(e.Table)),
+		})
+	}
+	return nil
+}
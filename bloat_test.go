@@ -0,0 +1,67 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// heavyDMLMigrations create the cars table then delete most of its rows.
+var heavyDMLMigrations = []*Migration{
+	{
+		ID:          "201608301400",
+		MigrateSQL:  "CREATE TABLE cars (id int PRIMARY KEY, name varchar(50));\nINSERT INTO cars (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd')",
+		RollbackSQL: "DROP TABLE cars",
+	},
+	{
+		ID:             "201608301430",
+		MigrateSQL:     "DELETE FROM cars WHERE id > 1",
+		HeavyDMLTables: []string{"cars"},
+		RollbackTx: func(tx *Tx) error {
+			return nil
+		},
+	},
+}
+
+func TestVacuumHeavyDML(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.VacuumHeavyDML = true
+
+		m := New(db, &options, heavyDMLMigrations)
+		require.NoError(t, m.Migrate())
+
+		estimates, err := m.BloatEstimates("cars", "unknown")
+		require.NoError(t, err)
+		require.Len(t, estimates, 1)
+		assert.Equal(t, "cars", estimates[0].Table)
+		assert.True(t, estimates[0].Size > 0)
+
+		require.NoError(t, m.RollbackLast())
+		require.NoError(t, m.RollbackLast())
+	}, "postgres", "mysql")
+}
+
+func TestBloatThresholdUnsupported(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.BloatThreshold = 0.01
+		options.VacuumHeavyDML = true
+
+		m := New(db, &options, heavyDMLMigrations)
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		for _, w := range res.Warnings {
+			assert.NotEqual(t, WarningTableBloat, w.Code)
+		}
+
+		_, err = m.BloatEstimates("cars")
+		assert.True(t, errors.Is(err, ErrUnsupportedDriver))
+
+		require.NoError(t, m.RollbackLast())
+		require.NoError(t, m.RollbackLast())
+	}, "sqlite3", "sqlserver")
+}
@@ -0,0 +1,92 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// appliedCache caches the applied IDs of each migration table of the
+// process, when Options.CacheAppliedIDs is set.
+var appliedCache = &idCache{entries: make(map[string]*idCacheEntry)}
+
+type idCache struct {
+	mu      sync.Mutex
+	entries map[string]*idCacheEntry
+}
+
+type idCacheEntry struct {
+	ids   []string
+	valid bool
+	// generation is incremented by each invalidation, so a read started
+	// before a write doesn't store stale IDs.
+	generation uint64
+}
+
+// get returns the cached IDs of a key, or the generation to store them with.
+func (c *idCache) get(key string) ([]string, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		e = &idCacheEntry{}
+		c.entries[key] = e
+	}
+	if !e.valid {
+		return nil, e.generation, false
+	}
+	return append([]string(nil), e.ids...), e.generation, true
+}
+
+// put stores the IDs of a key, unless it was invalidated since the
+// generation was read.
+func (c *idCache) put(key string, generation uint64, ids []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok && e.generation == generation {
+		e.ids = append([]string(nil), ids...)
+		e.valid = true
+	}
+}
+
+func (c *idCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.ids = nil
+		e.valid = false
+		e.generation++
+	}
+}
+
+// cacheKey identifies the migration table of the database.
+func (g *Sqlxmigrate) cacheKey() string {
+	return fmt.Sprintf("%p/%s", g.db, g.options.TableName)
+}
+
+// cachedAppliedIDs returns the IDs of the applied migrations from the cache,
+// reading them from the history once.
+func (g *Sqlxmigrate) cachedAppliedIDs(ctx context.Context) ([]string, error) {
+	key := g.cacheKey()
+	ids, generation, ok := appliedCache.get(key)
+	if ok {
+		return ids, nil
+	}
+
+	ids, err := g.readAppliedIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appliedCache.put(key, generation, ids)
+	return ids, nil
+}
+
+// InvalidateCache drops the cached applied IDs of the migration table, ie.
+// once another process applied migrations. The cache is invalidated by the
+// runs and rollbacks of the process.
+func (g *Sqlxmigrate) InvalidateCache() {
+	appliedCache.invalidate(g.cacheKey())
+}
@@ -0,0 +1,84 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingHistory counts the reads of the applied migrations.
+type countingHistory struct {
+	memoryHistory
+	reads int
+}
+
+func (h *countingHistory) Applied(ctx context.Context) ([]string, error) {
+	h.reads++
+	return h.memoryHistory.Applied(ctx)
+}
+
+func TestCacheAppliedIDs(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.CacheAppliedIDs = true
+
+		history := &countingHistory{}
+		m := New(db, &options, migrations)
+		m.SetHistoryStore(history)
+		defer m.InvalidateCache()
+
+		require.NoError(t, m.Migrate())
+
+		// The run read the applied IDs once committed, for the version of
+		// its result.
+		reads := history.reads
+		for i := 0; i < 3; i++ {
+			pending, err := m.Pending()
+			require.NoError(t, err)
+			assert.Empty(t, pending)
+		}
+		assert.Equal(t, reads, history.reads)
+
+		require.NoError(t, m.RollbackLast())
+		pending, err := m.Pending()
+		require.NoError(t, err)
+		assert.Len(t, pending, 1)
+
+		// Changes made by other processes are seen once invalidated.
+		history.ids = append(history.ids, "201608301430")
+		pending, err = m.Pending()
+		require.NoError(t, err)
+		assert.Len(t, pending, 1)
+
+		m.InvalidateCache()
+		pending, err = m.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestIDCacheGeneration(t *testing.T) {
+	c := &idCache{entries: make(map[string]*idCacheEntry)}
+
+	_, generation, ok := c.get("db/migrations")
+	assert.False(t, ok)
+
+	// A read started before an invalidation isn't stored.
+	c.invalidate("db/migrations")
+	c.put("db/migrations", generation, []string{"1"})
+	_, generation, ok = c.get("db/migrations")
+	assert.False(t, ok)
+
+	c.put("db/migrations", generation, []string{"1"})
+	ids, _, ok := c.get("db/migrations")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"1"}, ids)
+
+	// The returned IDs are copies.
+	ids[0] = "2"
+	ids, _, _ = c.get("db/migrations")
+	assert.Equal(t, []string{"1"}, ids)
+}
@@ -0,0 +1,114 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"strings"
+)
+
+// LockStrategy is how Options.UseLock serializes the runs.
+type LockStrategy string
+
+const (
+	// LockAdvisory is a PostgreSQL session advisory lock, or the lock of
+	// the LockSQL of a registered dialect.
+	LockAdvisory LockStrategy = "advisory"
+	// LockAdvisoryTransaction is a PostgreSQL transaction advisory lock,
+	// used behind a transaction pooler.
+	LockAdvisoryTransaction LockStrategy = "advisory_transaction"
+	// LockNamed is a MySQL named lock or a SQL Server application lock.
+	LockNamed LockStrategy = "named"
+	// LockRow is a lock row in the migration table.
+	LockRow LockStrategy = "row"
+)
+
+// Capabilities describes the database the migrations run against, to
+// diagnose the behaviors depending on the environment.
+type Capabilities struct {
+	// Driver is the name of the database driver.
+	Driver string
+	// ServerVersion is the version reported by the server, empty when the
+	// driver isn't known.
+	ServerVersion string
+	// CockroachDB is whether the PostgreSQL server is CockroachDB.
+	CockroachDB bool
+	// TransactionalDDL is whether schema changes are rolled back with the
+	// transaction of a failed migration.
+	TransactionalDDL bool
+	// Pooled is whether the database is reached through a transaction
+	// pooler.
+	Pooled bool
+	// Lock is the strategy serializing the runs, empty unless
+	// Options.UseLock is set.
+	Lock LockStrategy
+}
+
+// Capabilities probes the database once, which the first run does and logs.
+func (g *Sqlxmigrate) Capabilities() (*Capabilities, error) {
+	return g.probeCapabilities(context.Background())
+}
+
+func (g *Sqlxmigrate) probeCapabilities(ctx context.Context) (*Capabilities, error) {
+	if g.capabilities != nil {
+		return g.capabilities, nil
+	}
+
+	pooled, err := g.BehindPooler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := g.serverVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cockroach, err := g.detectCockroachDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Capabilities{
+		Driver:           g.db.DriverName(),
+		ServerVersion:    version,
+		CockroachDB:      cockroach,
+		TransactionalDDL: g.transactionalDDL(),
+		Pooled:           pooled,
+	}
+	g.capabilities = c
+	if g.options.UseLock {
+		c.Lock = g.lockStrategy(pooled)
+	}
+
+	g.logf("Capabilities - driver %s, server version %s, CockroachDB %t, transactional DDL %t, pooled %t, lock %s",
+		c.Driver, c.ServerVersion, c.CockroachDB, c.TransactionalDDL, c.Pooled, c.Lock)
+
+	return c, nil
+}
+
+// transactionalDDL returns whether schema changes are part of transactions,
+// MySQL committing them implicitly.
+func (g *Sqlxmigrate) transactionalDDL() bool {
+	return !g.isMySQL()
+}
+
+func (g *Sqlxmigrate) serverVersion(ctx context.Context) (string, error) {
+	var query string
+	switch {
+	case g.isPostgres():
+		query = "SHOW server_version"
+	case g.isMySQL():
+		query = "SELECT VERSION()"
+	case strings.Contains(g.db.DriverName(), "sqlite"):
+		query = "SELECT sqlite_version()"
+	case g.isSQLServer():
+		query = "SELECT CAST(SERVERPROPERTY('ProductVersion') AS NVARCHAR(128))"
+	default:
+		return "", nil
+	}
+	g.logf("serverVersion %s", query)
+
+	var version string
+	if err := g.db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		err = queryError(err, query)
+		return "", err
+	}
+	return version, nil
+}
@@ -0,0 +1,42 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.UseLock = true
+		m := New(db, &options, migrations)
+
+		c, err := m.Capabilities()
+		require.NoError(t, err)
+		assert.Equal(t, db.DriverName(), c.Driver)
+		assert.NotEmpty(t, c.ServerVersion)
+		assert.Equal(t, !m.isMySQL(), c.TransactionalDDL)
+		assert.False(t, c.Pooled)
+		assert.Equal(t, m.lockStrategy(false), c.Lock)
+
+		again, err := m.Capabilities()
+		require.NoError(t, err)
+		assert.True(t, c == again)
+	})
+}
+
+func TestLockStrategy(t *testing.T) {
+	for driver, want := range map[string][2]LockStrategy{
+		"postgres": {LockAdvisory, LockAdvisoryTransaction},
+		"mysql":    {LockNamed, LockRow},
+		"sqlite3":  {LockRow, LockRow},
+		"mssql":    {LockNamed, LockRow},
+	} {
+		m := New(sqlx.NewDb(nil, driver), DefaultOptions, migrations)
+		assert.Equal(t, want[0], m.lockStrategy(false), driver)
+		assert.Equal(t, want[1], m.lockStrategy(true), driver)
+	}
+}
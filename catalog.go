@@ -0,0 +1,146 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// HasTable returns whether a table exists. The table name can be qualified
+// with a schema, ie. "public.migrations", otherwise the table is looked up in
+// the current schema or database. The catalog of the database is queried by
+// its Dialect: pg_catalog for PostgreSQL, sqlite_master for SQLite, OBJECT_ID
+// for SQL Server and the information schema otherwise.
+func (g *Sqlxmigrate) HasTable(tableName string) (bool, error) {
+	return g.tableExists(context.Background(), tableName)
+}
+
+func (g *Sqlxmigrate) tableExists(ctx context.Context, tableName string) (bool, error) {
+	schema, table := splitTableName(tableName)
+	return g.dialect().TableExists(ctx, catalog{g}, schema, table)
+}
+
+// hasColumn returns whether a column exists in a table.
+func (g *Sqlxmigrate) hasColumn(ctx context.Context, tableName, columnName string) (bool, error) {
+	schema, table := splitTableName(tableName)
+
+	var query string
+	switch {
+	case g.isPostgres():
+		query = "SELECT count(*) FROM information_schema.columns WHERE table_schema = coalesce(?, current_schema()) AND table_name = ? AND column_name = ?"
+	case g.isMySQL():
+		query = "SELECT count(*) FROM information_schema.columns WHERE table_schema = coalesce(?, DATABASE()) AND table_name = ? AND column_name = ?"
+	case g.isSQLite():
+		return g.catalogExists(ctx, "SELECT count(*) FROM pragma_table_info(?, ?) WHERE name = ?", table, sqliteSchema(schema), columnName)
+	case g.isSQLServer():
+		return g.catalogExists(ctx, "SELECT count(*) FROM sys.columns WHERE object_id = OBJECT_ID(?, 'U') AND name = ?", tableName, columnName)
+	case schema == nil:
+		return g.catalogExists(ctx, "SELECT count(*) FROM information_schema.columns WHERE table_name = ? AND column_name = ?", table, columnName)
+	default:
+		query = "SELECT count(*) FROM information_schema.columns WHERE table_schema = ? AND table_name = ? AND column_name = ?"
+	}
+
+	return g.catalogExists(ctx, query, schema, table, columnName)
+}
+
+// tableColumns returns the columns of a table in order. Only PostgreSQL, MySQL,
+// SQLite and SQL Server are supported.
+func (g *Sqlxmigrate) tableColumns(ctx context.Context, tableName string) ([]Column, error) {
+	schema, table := splitTableName(tableName)
+
+	var query string
+	switch {
+	case g.isPostgres():
+		query = "SELECT table_name, column_name, data_type, is_nullable, column_default, ordinal_position FROM information_schema.columns WHERE table_schema = coalesce(?, current_schema()) AND table_name = ? ORDER BY ordinal_position"
+	case g.isMySQL():
+		query = "SELECT table_name, column_name, data_type, is_nullable, column_default, ordinal_position FROM information_schema.columns WHERE table_schema = coalesce(?, DATABASE()) AND table_name = ? ORDER BY ordinal_position"
+	case g.isSQLite():
+		query = "SELECT ?, " + sqliteColumnFields + " FROM pragma_table_info(?, ?) p ORDER BY p.cid"
+	case g.isSQLServer():
+		query = "SELECT table_name, column_name, data_type, is_nullable, column_default, ordinal_position FROM information_schema.columns WHERE table_schema = coalesce(?, SCHEMA_NAME()) AND table_name = ? ORDER BY ordinal_position"
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+
+	args := []interface{}{schema, table}
+	if g.isSQLite() {
+		args = []interface{}{table, table, sqliteSchema(schema)}
+	}
+
+	var columns []Column
+	err := g.inspectRows(ctx, g.rebind(query), func(rows *sql.Rows) error {
+		c, err := scanColumn(rows)
+		if err != nil {
+			return err
+		}
+		columns = append(columns, c)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+func (g *Sqlxmigrate) isPostgres() bool {
+	return isPostgresDriver(g.db.DriverName())
+}
+
+func (g *Sqlxmigrate) isMySQL() bool {
+	return isMySQLDriver(g.db.DriverName())
+}
+
+func (g *Sqlxmigrate) isSQLite() bool {
+	return isSQLiteDriver(g.db.DriverName())
+}
+
+func (g *Sqlxmigrate) isSQLServer() bool {
+	return isSQLServerDriver(g.db.DriverName())
+}
+
+// driverName returns the name of the driver of the database, empty when the
+// migrations aren't bound to one, ie. to export a rollback script.
+func (g *Sqlxmigrate) driverName() string {
+	if g.db == nil {
+		return ""
+	}
+	return g.db.DriverName()
+}
+
+func isPostgresDriver(name string) bool {
+	return strings.Contains(name, "postgres") || strings.Contains(name, "pgx")
+}
+
+func isMySQLDriver(name string) bool {
+	return strings.Contains(name, "mysql")
+}
+
+func isSQLiteDriver(name string) bool {
+	return strings.Contains(name, "sqlite")
+}
+
+// isSQLServerDriver returns whether the driver is one of SQL Server, the
+// "sqlserver" driver or the legacy "mssql" one.
+func isSQLServerDriver(name string) bool {
+	return strings.Contains(name, "sqlserver") || strings.Contains(name, "mssql")
+}
+
+// rebind converts the ? bindvars of a query to the ones of the driver.
+func (g *Sqlxmigrate) rebind(query string) string {
+	return g.dialect().Rebind(query)
+}
+
+// splitTableName returns the schema, nil when not qualified, and the name of
+// a table.
+func splitTableName(tableName string) (*string, string) {
+	if i := strings.LastIndex(tableName, "."); i >= 0 {
+		schema := tableName[:i]
+		return &schema, tableName[i+1:]
+	}
+	return nil, tableName
+}
+
+// catalogExists runs a count query against the catalog.
+func (g *Sqlxmigrate) catalogExists(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	return exists(ctx, catalog{g}, query, args...)
+}
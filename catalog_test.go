@@ -0,0 +1,94 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasTable(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+
+		has, err := m.HasTable(DefaultOptions.TableName)
+		require.NoError(t, err)
+		assert.False(t, has)
+
+		require.NoError(t, m.Migrate())
+
+		has, err = m.HasTable(DefaultOptions.TableName)
+		require.NoError(t, err)
+		assert.True(t, has)
+
+		has, err = m.hasColumn(context.Background(), DefaultOptions.TableName, DefaultOptions.IDColumnName)
+		require.NoError(t, err)
+		assert.True(t, has)
+
+		has, err = m.hasColumn(context.Background(), DefaultOptions.TableName, "missing")
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+}
+
+func TestHasTableWithSchema(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		require.NoError(t, m.Migrate())
+
+		has, err := m.HasTable("public." + DefaultOptions.TableName)
+		require.NoError(t, err)
+		assert.True(t, has)
+
+		has, err = m.HasTable("sqlxmigrate_missing." + DefaultOptions.TableName)
+		require.NoError(t, err)
+		assert.False(t, has)
+	}, "postgres")
+}
+
+func TestSplitTableName(t *testing.T) {
+	schema, table := splitTableName("migrations")
+	assert.Nil(t, schema)
+	assert.Equal(t, "migrations", table)
+
+	schema, table = splitTableName("public.migrations")
+	require.NotNil(t, schema)
+	assert.Equal(t, "public", *schema)
+	assert.Equal(t, "migrations", table)
+}
+
+func TestHasTableSQLite(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		require.NoError(t, m.Migrate())
+
+		has, err := m.HasTable("main." + DefaultOptions.TableName)
+		require.NoError(t, err)
+		assert.True(t, has)
+
+		has, err = m.HasTable("temp." + DefaultOptions.TableName)
+		require.NoError(t, err)
+		assert.False(t, has)
+
+		columns, err := m.tableColumns(context.Background(), "pets")
+		require.NoError(t, err)
+		require.Len(t, columns, 6)
+		assert.Equal(t, Column{Table: "pets", Name: "id", DataType: "serial", Position: 1}, columns[0])
+		assert.Equal(t, Column{Table: "pets", Name: "name", DataType: "text", Nullable: true, Position: 5}, columns[4])
+	}, "sqlite3")
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT count(*) FROM migrations WHERE id = ? AND checksum = ?"
+	for driver, want := range map[string]string{
+		"postgres":  "SELECT count(*) FROM migrations WHERE id = $1 AND checksum = $2",
+		"mysql":     query,
+		"sqlserver": "SELECT count(*) FROM migrations WHERE id = @p1 AND checksum = @p2",
+		"mssql":     "SELECT count(*) FROM migrations WHERE id = @p1 AND checksum = @p2",
+	} {
+		m := New(sqlx.NewDb(nil, driver), DefaultOptions, migrations)
+		assert.Equal(t, want, m.rebind(query), driver)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// bundleFile is a file of a review bundle.
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// bundleModTime is the modification time of the files of the archives, fixed
+// so the same migrations always produce the same bundle.
+var bundleModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// bundleCmd packages the pending migrations with their checksums and lock
+// analysis into a bundle to attach to a change-management ticket.
+func bundleCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("bundle", flag.ExitOnError)
+	format := fs.String("format", "markdown", "bundle format, markdown, tar or zip")
+	output := fs.String("o", "", "file to write the bundle to, stdout when empty")
+	all := fs.Bool("all", false, "bundle every migration, without connecting to the database")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var migrations []*sqlxmigrate.Migration
+	if *all {
+		var err error
+		if migrations, err = sqlxmigrate.LoadMigrations(cfg.dir); err != nil {
+			return err
+		}
+	} else {
+		db, m, err := cfg.open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if migrations, err = m.Pending(); err != nil {
+			return err
+		}
+	}
+
+	files, err := bundleFiles(cfg, migrations)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "markdown":
+		_, err = w.Write(files[0].data)
+	case "tar":
+		err = writeTarBundle(w, files)
+	case "zip":
+		err = writeZipBundle(w, files)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	return err
+}
+
+// bundleFiles returns the files of the bundle, the Markdown summary first,
+// then the SQL files and their checksums.
+func bundleFiles(cfg *config, migrations []*sqlxmigrate.Migration) ([]bundleFile, error) {
+	var summary, sums bytes.Buffer
+	var sqlFiles []bundleFile
+
+	fmt.Fprintf(&summary, "# Migration review bundle\n\n")
+	if cfg.driver != "" {
+		fmt.Fprintf(&summary, "Driver: %s\n\n", cfg.driver)
+	}
+	if len(migrations) == 0 {
+		fmt.Fprintf(&summary, "No pending migration.\n")
+		return []bundleFile{{name: "README.md", data: summary.Bytes()}}, nil
+	}
+
+	fmt.Fprintf(&summary, "## Plan\n\n")
+	for i, m := range migrations {
+		fmt.Fprintf(&summary, "%d. %s\n", i+1, m.ID)
+	}
+
+	for _, m := range migrations {
+		up, err := ioutil.ReadFile(filepath.Join(cfg.dir, m.ID+upSuffix))
+		if err != nil {
+			return nil, err
+		}
+		upName := "migrations/" + m.ID + upSuffix
+		upSum := sha256Hex(up)
+		sqlFiles = append(sqlFiles, bundleFile{name: upName, data: up})
+		fmt.Fprintf(&sums, "%s  %s\n", upSum, upName)
+
+		fmt.Fprintf(&summary, "\n## %s\n\n", m.ID)
+		fmt.Fprintf(&summary, "SHA-256 of %s: `%s`\n", m.ID+upSuffix, upSum)
+
+		if m.RollbackSQL != "" {
+			downName := "migrations/" + m.ID + downSuffix
+			downSum := sha256Hex([]byte(m.RollbackSQL))
+			sqlFiles = append(sqlFiles, bundleFile{name: downName, data: []byte(m.RollbackSQL)})
+			fmt.Fprintf(&sums, "%s  %s\n", downSum, downName)
+			fmt.Fprintf(&summary, "\nSHA-256 of %s: `%s`\n", m.ID+downSuffix, downSum)
+		}
+
+		if cfg.driver != "" {
+			fmt.Fprintf(&summary, "\n### Locks\n\n| Lock | Statement |\n| --- | --- |\n")
+			for _, l := range sqlxmigrate.AnalyzeLocks(cfg.driver, string(up)) {
+				fmt.Fprintf(&summary, "| %s | `%s` |\n", l.Lock, strings.Replace(summarize(l.Statement, 100), "|", `\|`, -1))
+			}
+		}
+
+		fmt.Fprintf(&summary, "\n### Up\n\n```sql\n%s\n```\n", strings.TrimSpace(string(up)))
+		if m.RollbackSQL != "" {
+			fmt.Fprintf(&summary, "\n### Down\n\n```sql\n%s\n```\n", strings.TrimSpace(m.RollbackSQL))
+		} else {
+			fmt.Fprintf(&summary, "\n### Down\n\nNo rollback.\n")
+		}
+	}
+
+	files := []bundleFile{{name: "README.md", data: summary.Bytes()}}
+	files = append(files, sqlFiles...)
+	return append(files, bundleFile{name: "SHA256SUMS", data: sums.Bytes()}), nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeTarBundle writes the files as a gzipped tar archive.
+func writeTarBundle(w io.Writer, files []bundleFile) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    f.name,
+			Mode:    0644,
+			Size:    int64(len(f.data)),
+			ModTime: bundleModTime,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeZipBundle writes the files as a zip archive.
+func writeZipBundle(w io.Writer, files []bundleFile) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		hdr := &zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: bundleModTime,
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
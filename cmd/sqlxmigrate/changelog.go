@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// changelogEntry describes a migration in the changelog.
+type changelogEntry struct {
+	ID          string
+	Date        string
+	Description string
+	Author      string
+	// Applied is whether the migration is applied, for each environment.
+	Applied []bool
+}
+
+// changelogEnv is an environment listed in the changelog, set with
+// -env name=dsn.
+type changelogEnv struct {
+	name string
+	dsn  string
+}
+
+// envFlag collects the -env flags.
+type envFlag []changelogEnv
+
+func (f *envFlag) String() string {
+	var names []string
+	for _, e := range *f {
+		names = append(names, e.name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *envFlag) Set(v string) error {
+	i := strings.Index(v, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid environment %q, expected name=dsn", v)
+	}
+	*f = append(*f, changelogEnv{name: v[:i], dsn: v[i+1:]})
+	return nil
+}
+
+// changelogCmd renders the migrations, newest first, with their applied
+// status in each environment.
+func changelogCmd(cfg *config, args []string) error {
+	var envs envFlag
+	fs := flag.NewFlagSet("changelog", flag.ExitOnError)
+	format := fs.String("format", "markdown", "output format, markdown or html")
+	fs.Var(&envs, "env", "environment to report the applied status of, as name=dsn, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "markdown" && *format != "html" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if len(envs) == 0 && cfg.dsn != "" {
+		envs = envFlag{{name: "applied", dsn: cfg.dsn}}
+	}
+
+	entries, err := changelogEntries(cfg.dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, env := range envs {
+		pending, err := pendingIDs(cfg, env.dsn)
+		if err != nil {
+			return fmt.Errorf("environment %s: %v", env.name, err)
+		}
+		for _, e := range entries {
+			_, ok := pending[e.ID]
+			e.Applied = append(e.Applied, !ok)
+		}
+		names = append(names, env.name)
+	}
+
+	if *format == "html" {
+		return renderChangelogHTML(os.Stdout, names, entries)
+	}
+	return renderChangelogMarkdown(os.Stdout, names, entries)
+}
+
+// pendingIDs returns the IDs of the pending migrations of a database.
+func pendingIDs(cfg *config, dsn string) (map[string]struct{}, error) {
+	envCfg := *cfg
+	envCfg.dsn = dsn
+
+	db, m, err := envCfg.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{}, len(pending))
+	for _, p := range pending {
+		ids[p.ID] = struct{}{}
+	}
+	return ids, nil
+}
+
+// changelogEntries returns the entries of the migration files, newest first.
+// The description and author are read from the leading comments of the
+// up-SQL, ie. "-- description: Create the people table", the description
+// defaulting to the name of the migration.
+func changelogEntries(dir string) ([]*changelogEntry, error) {
+	migrations, err := sqlxmigrate.LoadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*changelogEntry, 0, len(migrations))
+	for i := len(migrations) - 1; i >= 0; i-- {
+		id := migrations[i].ID
+		e := &changelogEntry{
+			ID:   id,
+			Date: migrationDate(id),
+		}
+		if j := strings.Index(id, "_"); j >= 0 {
+			e.Description = strings.Replace(id[j+1:], "_", " ", -1)
+		}
+
+		headers, err := migrationHeaders(filepath.Join(dir, id+upSuffix))
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := headers["description"]; ok {
+			e.Description = v
+		}
+		e.Author = headers["author"]
+
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// migrationDate formats the timestamp prefix of a migration ID, or returns
+// an empty string when the ID doesn't start with a timestamp.
+func migrationDate(id string) string {
+	for _, layout := range []string{"20060102150405", "200601021504"} {
+		if len(id) < len(layout) {
+			continue
+		}
+		if t, err := time.Parse(layout, id[:len(layout)]); err == nil {
+			return t.Format("2006-01-02 15:04")
+		}
+	}
+	return ""
+}
+
+// migrationHeaders returns the "-- key: value" comments at the top of a
+// migration file, with lower case keys.
+func migrationHeaders(path string) (map[string]string, error) {
+	headers := make(map[string]string)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return headers, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		kv := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "--")), ":", 2)
+		if len(kv) == 2 {
+			headers[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+		}
+	}
+	return headers, scanner.Err()
+}
+
+func renderChangelogMarkdown(w io.Writer, envs []string, entries []*changelogEntry) error {
+	cell := func(s string) string {
+		return strings.Replace(s, "|", `\|`, -1)
+	}
+
+	header := []string{"ID", "Date", "Description", "Author"}
+	for _, env := range envs {
+		header = append(header, cell(env))
+	}
+
+	var b strings.Builder
+	b.WriteString("# Changelog\n\n")
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, e := range entries {
+		row := []string{cell(e.ID), e.Date, cell(e.Description), cell(e.Author)}
+		for _, applied := range e.Applied {
+			if applied {
+				row = append(row, "yes")
+			} else {
+				row = append(row, "no")
+			}
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var changelogHTML = template.Must(template.New("changelog").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Changelog</title></head>
+<body>
+<h1>Changelog</h1>
+<table>
+<thead>
+<tr><th>ID</th><th>Date</th><th>Description</th><th>Author</th>{{range .Envs}}<th>{{.}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{range .Entries}}<tr><td>{{.ID}}</td><td>{{.Date}}</td><td>{{.Description}}</td><td>{{.Author}}</td>{{range .Applied}}<td>{{if .}}yes{{else}}no{{end}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
+
+func renderChangelogHTML(w io.Writer, envs []string, entries []*changelogEntry) error {
+	return changelogHTML.Execute(w, struct {
+		Envs    []string
+		Entries []*changelogEntry
+	}{envs, entries})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// downCmd rolls back the last migration, the last n migrations, the
+// migrations applied after a migration or all of them.
+func downCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("down", flag.ExitOnError)
+	n := fs.Int("n", 1, "number of migrations to roll back")
+	to := fs.String("to", "", "roll back the migrations applied after this migration ID")
+	toTime := fs.String("to-time", "", "roll back the migrations after this RFC 3339 time, ie. 2019-06-11T15:00:00Z")
+	all := fs.Bool("all", false, "roll back all the applied migrations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, m, err := cfg.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	switch {
+	case *all:
+		err = m.ResetContext(ctx)
+	case *to != "":
+		err = m.RollbackToContext(ctx, *to)
+	case *toTime != "":
+		t, perr := time.Parse(time.RFC3339, *toTime)
+		if perr != nil {
+			return fmt.Errorf("invalid -to-time: %v", perr)
+		}
+		err = m.RollbackToTimeContext(ctx, t)
+	case *n < 1:
+		return fmt.Errorf("the -n flag must be positive")
+	default:
+		err = m.RollbackNContext(ctx, *n)
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+// migrationID returns the migration ID of a migration file name, or an empty
+// string if the file is not a migration file.
+func migrationID(name string) string {
+	for _, suffix := range []string{upSuffix, downSuffix} {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return ""
+}
+
+// migrationFiles returns the paths of the existing files of a migration.
+func migrationFiles(dir, id string) []string {
+	var paths []string
+	for _, suffix := range []string{upSuffix, downSuffix} {
+		p := filepath.Join(dir, id+suffix)
+		if _, err := os.Stat(p); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
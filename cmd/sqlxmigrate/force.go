@@ -0,0 +1,47 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// forceCmd records the migrations up to a migration as applied and the ones
+// after it as rolled back, without running them, ie. to recover from a
+// migration applied or undone by hand.
+func forceCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("force", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: sqlxmigrate force <migration ID>")
+	}
+	id := fs.Arg(0)
+
+	migrations, err := sqlxmigrate.LoadMigrations(cfg.dir)
+	if err != nil {
+		return err
+	}
+	noop := func(*sql.Tx) error { return nil }
+	for _, m := range migrations {
+		m.MigrateSQL, m.RollbackSQL = "", ""
+		m.Migrate, m.Rollback = noop, noop
+	}
+
+	db, m, err := cfg.openMigrations(migrations)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	if err := m.MigrateToContext(ctx, id); err != nil {
+		return err
+	}
+	return m.RollbackToContext(ctx, id)
+}
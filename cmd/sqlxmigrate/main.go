@@ -0,0 +1,176 @@
+// Command sqlxmigrate manages the migrations of a database from SQL files
+// stored in a directory.
+//
+// Usage:
+//
+//	sqlxmigrate [flags] <command> [command flags]
+//
+// The database is configured with the -driver and -dsn flags, or the
+// SQLXMIGRATE_DRIVER and SQLXMIGRATE_DSN environment variables.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// config holds the global flags shared by all the commands.
+type config struct {
+	driver    string
+	dsn       string
+	dir       string
+	tableName string
+}
+
+// command is a sub command of the cli.
+type command struct {
+	usage string
+	run   func(cfg *config, args []string) error
+}
+
+var commands = map[string]command{
+	"bundle": {
+		usage: "Package the pending migrations into a bundle for review",
+		run:   bundleCmd,
+	},
+	"changelog": {
+		usage: "Render the migrations and where they are applied as Markdown or HTML",
+		run:   changelogCmd,
+	},
+	"down": {
+		usage: "Roll back the last migration, the last n ones or the ones after a migration",
+		run:   downCmd,
+	},
+	"force": {
+		usage: "Record the migrations as applied up to a migration without running them",
+		run:   forceCmd,
+	},
+	"new": {
+		usage: "Create the files of a new migration",
+		run:   newCmd,
+	},
+	"plan": {
+		usage: "Print the pending migrations, their statements and the locks they take",
+		run:   planCmd,
+	},
+	"redo": {
+		usage: "Roll back and apply again the last migration or the ones after a migration",
+		run:   redoCmd,
+	},
+	"repair": {
+		usage: "Reconcile the migration table with the migration files",
+		run:   repairCmd,
+	},
+	"renumber": {
+		usage: "Renumber pending migrations to sort after the last applied migration",
+		run:   renumberCmd,
+	},
+	"status": {
+		usage: "Print whether each migration is applied, when and how long it took",
+		run:   statusCmd,
+	},
+	"up": {
+		usage: "Apply the pending migrations, or the ones up to a migration",
+		run:   upCmd,
+	},
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("sqlxmigrate: ")
+
+	cfg := &config{}
+	flag.StringVar(&cfg.driver, "driver", os.Getenv("SQLXMIGRATE_DRIVER"), "database driver name, ie. postgres or mysql")
+	flag.StringVar(&cfg.dsn, "dsn", os.Getenv("SQLXMIGRATE_DSN"), "database connection string")
+	flag.StringVar(&cfg.dir, "dir", envOr("SQLXMIGRATE_DIR", "migrations"), "directory of the migration files")
+	flag.StringVar(&cfg.tableName, "table", sqlxmigrate.DefaultOptions.TableName, "migration table name")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		log.Printf("unknown command %q", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(cfg, flag.Args()[1:]); err != nil {
+		if hint := sqlxmigrate.Hint(err); hint != "" {
+			log.Fatalf("%v\nhint: %s", err, hint)
+		}
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: sqlxmigrate [flags] <command> [command flags]\n\nCommands:\n")
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %-10s %s\n", name, commands[name].usage)
+	}
+
+	fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// signalContext returns a context canceled on SIGINT or SIGTERM, so the
+// migration running when a CI job or a Kubernetes Job is stopped is rolled
+// back instead of being interrupted.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// open connects to the database and returns a migrator for the migration files.
+func (cfg *config) open() (*sqlx.DB, *sqlxmigrate.Sqlxmigrate, error) {
+	migrations, err := sqlxmigrate.LoadMigrations(cfg.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg.openMigrations(migrations)
+}
+
+// openMigrations connects to the database and returns a migrator for the
+// migrations.
+func (cfg *config) openMigrations(migrations []*sqlxmigrate.Migration) (*sqlx.DB, *sqlxmigrate.Sqlxmigrate, error) {
+	if cfg.driver == "" || cfg.dsn == "" {
+		return nil, nil, fmt.Errorf("the -driver and -dsn flags are required")
+	}
+
+	db, err := sqlx.Connect(cfg.driver, cfg.dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m := sqlxmigrate.New(db, &sqlxmigrate.Options{TableName: cfg.tableName}, migrations)
+	m.SetLogger(log.New(os.Stderr, "sqlxmigrate : ", log.LstdFlags))
+
+	return db, m, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// newCmd creates the files of a new migration, with an ID made of the
+// current time and the name of the migration.
+func newCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	goFile := fs.Bool("go", false, "create a Go file declaring the migration instead of SQL files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: sqlxmigrate new [-go] <name>")
+	}
+
+	format := sqlxmigrate.ScaffoldSQL
+	if *goFile {
+		format = sqlxmigrate.ScaffoldGo
+	}
+
+	paths, err := sqlxmigrate.Create(cfg.dir, strings.Join(fs.Args(), " "), format)
+	for _, p := range paths {
+		fmt.Println(p)
+	}
+	return err
+}
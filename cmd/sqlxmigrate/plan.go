@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// planCmd prints the pending migrations and, optionally, their statements or
+// the strongest lock taken by each of them.
+func planCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	locks := fs.Bool("locks", false, "print the strongest lock taken by each statement")
+	showSQL := fs.Bool("sql", false, "print the statements of each migration")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, m, err := cfg.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	steps, err := m.Plan()
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Println("No pending migration")
+		return nil
+	}
+
+	for _, s := range steps {
+		fmt.Println(s.ID)
+		for _, stmt := range s.Statements {
+			if *showSQL {
+				fmt.Printf("    %s;\n", stmt)
+			}
+			if *locks {
+				for _, l := range sqlxmigrate.AnalyzeLocks(cfg.driver, stmt) {
+					fmt.Printf("    %-24s %s\n", l.Lock, summarize(l.Statement, 72))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// summarize returns the statement on a single line, truncated to n characters.
+func summarize(stmt string, n int) string {
+	stmt = strings.Join(strings.Fields(stmt), " ")
+	if len(stmt) > n {
+		stmt = stmt[:n-3] + "..."
+	}
+	return stmt
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+)
+
+// redoCmd rolls back and applies again the last migration, or the migrations
+// from a migration.
+func redoCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("redo", flag.ExitOnError)
+	to := fs.String("to", "", "redo the migrations from this migration ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, m, err := cfg.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	if *to != "" {
+		return m.RedoToContext(ctx, *to)
+	}
+	return m.RedoContext(ctx)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// renumberCmd renames the files of the pending migrations that sort before the
+// last applied migration and updates the references to their IDs in the files
+// of the other pending migrations.
+func renumberCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("renumber", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only print the changes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, m, err := cfg.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	renumberings, err := m.RenumberPending()
+	if err != nil {
+		return err
+	}
+	if len(renumberings) == 0 {
+		fmt.Println("No pending migration needs to be renumbered")
+		return nil
+	}
+
+	// The files of the applied migrations are never edited.
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	pendingIDs := make(map[string]bool, len(pending))
+	for _, p := range pending {
+		pendingIDs[p.ID] = true
+	}
+
+	for _, r := range renumberings {
+		fmt.Printf("%s -> %s\n", r.OldID, r.NewID)
+		if *dryRun {
+			continue
+		}
+
+		for _, p := range migrationFiles(cfg.dir, r.OldID) {
+			np := filepath.Join(cfg.dir, r.NewID+strings.TrimPrefix(filepath.Base(p), r.OldID))
+			if err := os.Rename(p, np); err != nil {
+				return err
+			}
+		}
+		delete(pendingIDs, r.OldID)
+		pendingIDs[r.NewID] = true
+
+		if err := replaceReferences(cfg.dir, pendingIDs, r.OldID, r.NewID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceReferences replaces the references to oldID in the SQL files of the
+// pending migrations of dir.
+func replaceReferences(dir string, pendingIDs map[string]bool, oldID, newID string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !pendingIDs[migrationID(f.Name())] {
+			continue
+		}
+
+		p := filepath.Join(dir, f.Name())
+		dat, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		replaced := replaceID(string(dat), oldID, newID)
+		if replaced == string(dat) {
+			continue
+		}
+
+		if err := ioutil.WriteFile(p, []byte(replaced), f.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceID replaces the occurrences of oldID in s which aren't part of a
+// longer identifier, ie. 2019_a isn't replaced in 2019_a_2.
+func replaceID(s, oldID, newID string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, oldID)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(oldID)
+		b.WriteString(s[:i])
+		if (i > 0 && isIDChar(s[i-1])) || (end < len(s) && isIDChar(s[end])) {
+			b.WriteString(oldID)
+		} else {
+			b.WriteString(newID)
+		}
+		s = s[end:]
+	}
+}
+
+func isIDChar(c byte) bool {
+	return c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceReferences(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		// An applied migration, never edited.
+		"2019_a_2.up.sql": "-- after 2019_a\nCREATE TABLE b (id int);",
+		// A pending migration referencing both IDs.
+		"2019_c.up.sql": "-- after 2019_a and 2019_a_2\nINSERT INTO log VALUES ('2019_a');",
+		"notes.sql":     "2019_a",
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	pending := map[string]bool{"2019_c": true, "2019_d": true}
+	require.NoError(t, replaceReferences(dir, pending, "2019_a", "2019_d"))
+
+	for name, want := range map[string]string{
+		"2019_a_2.up.sql": files["2019_a_2.up.sql"],
+		"2019_c.up.sql":   "-- after 2019_d and 2019_a_2\nINSERT INTO log VALUES ('2019_d');",
+		"notes.sql":       files["notes.sql"],
+	} {
+		dat, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(dat), name)
+	}
+}
+
+func TestReplaceID(t *testing.T) {
+	assert.Equal(t, "2019_d", replaceID("2019_a", "2019_a", "2019_d"))
+	assert.Equal(t, "x2019_a 2019_a_2 2019_a2 (2019_d)", replaceID("x2019_a 2019_a_2 2019_a2 (2019_a)", "2019_a", "2019_d"))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// repairCmd reconciles the migration table with the migration files.
+func repairCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	removeUnknown := fs.Bool("remove-unknown", false, "delete the rows of migrations without files")
+	updateChecksums := fs.Bool("update-checksums", false, "record the checksums of the applied migrations whose files were edited")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, m, err := cfg.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	res, err := m.Repair(sqlxmigrate.RepairOptions{
+		RemoveUnknown:   *removeUnknown,
+		UpdateChecksums: *updateChecksums,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range res.RemovedIDs {
+		fmt.Printf("removed %s\n", id)
+	}
+	for _, id := range res.UpdatedIDs {
+		fmt.Printf("updated the checksum of %s\n", id)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// statusCmd prints whether each migration is applied, when and how long it
+// took.
+func statusCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	pending := fs.Bool("pending", false, "only print the pending migrations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, m, err := cfg.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tAPPLIED AT\tDURATION")
+	for _, s := range statuses {
+		if *pending && !s.Pending() {
+			continue
+		}
+		status, appliedAt, duration := "pending", "-", "-"
+		if s.Applied {
+			status = "applied"
+		} else if s.Scheduled() {
+			status = "scheduled " + s.ScheduledAt.Format(time.RFC3339)
+		}
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Duration != nil {
+			duration = s.Duration.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.ID, status, appliedAt, duration)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// upCmd applies the pending migrations.
+func upCmd(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("up", flag.ExitOnError)
+	to := fs.String("to", "", "only apply the migrations up to this migration ID")
+	toTime := fs.String("to-time", "", "only apply the migrations up to this RFC 3339 time, ie. 2019-06-11T15:00:00Z")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, m, err := cfg.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	if *toTime != "" {
+		t, err := time.Parse(time.RFC3339, *toTime)
+		if err != nil {
+			return fmt.Errorf("invalid -to-time: %v", err)
+		}
+		if *to, err = m.IDAtTime(t); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		res, err := m.MigrateToWithResultContext(ctx, *to)
+		printResult(res)
+		return err
+	}
+	res, err := m.MigrateWithResultContext(ctx)
+	printResult(res)
+	return err
+}
+
+// printResult prints the migrations applied by a run and its warnings.
+func printResult(res *sqlxmigrate.Result) {
+	if res == nil {
+		return
+	}
+	if len(res.Migrations) == 0 && len(res.Pending) == 0 {
+		fmt.Println("No pending migration")
+	}
+	for _, r := range res.Migrations {
+		fmt.Printf("applied %s\n", r.ID)
+	}
+	for _, id := range res.Pending {
+		fmt.Printf("pending %s\n", id)
+	}
+	for _, w := range res.Warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+}
@@ -0,0 +1,91 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// cockroachTxRetries is how many times the transaction of a run is retried
+// on CockroachDB when Options.TxRetries is 0.
+const cockroachTxRetries = 3
+
+// isCockroachDB returns whether the database is CockroachDB, which speaks
+// the protocol of PostgreSQL and is only told apart once the capabilities
+// were probed.
+func (g *Sqlxmigrate) isCockroachDB() bool {
+	return g.capabilities != nil && g.capabilities.CockroachDB
+}
+
+// detectCockroachDB checks whether the PostgreSQL server is CockroachDB.
+func (g *Sqlxmigrate) detectCockroachDB(ctx context.Context) (bool, error) {
+	if !g.isPostgres() {
+		return false, nil
+	}
+
+	query := "SELECT version()"
+	g.logf("detectCockroachDB %s", query)
+
+	var version string
+	if err := g.db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		err = queryError(err, query)
+		return false, err
+	}
+	return strings.Contains(version, "CockroachDB"), nil
+}
+
+// txRetries returns how many times the transaction of a run is retried after
+// a serialization failure.
+func (g *Sqlxmigrate) txRetries() int {
+	if g.options.TxRetries == 0 && g.isCockroachDB() {
+		return cockroachTxRetries
+	}
+	if g.options.TxRetries < 0 {
+		return 0
+	}
+	return g.options.TxRetries
+}
+
+// retryTx rolls back the transaction of a run that failed with a
+// serialization failure and begins a new one, moving the migrations it
+// applied back to the locked state so they are executed again. It returns
+// false when err isn't a serialization failure or once the retries are
+// exhausted.
+func (g *Sqlxmigrate) retryTx(ctx context.Context, err error, retries *int, migrations []*Migration) (bool, error) {
+	if !isSerializationFailure(err) || *retries >= g.txRetries() {
+		return false, nil
+	}
+	*retries++
+	g.logf("Run - serialization failure, retrying the transaction (%d/%d) - %v", *retries, g.txRetries(), err)
+
+	g.rollback()
+	if err := g.begin(ctx); err != nil {
+		return false, err
+	}
+	for _, m := range migrations {
+		if g.states[m.ID] == StateLocked {
+			continue
+		}
+		if err := g.transition(m, StateLocked, nil); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// isSerializationFailure returns whether err is a serialization failure,
+// SQLSTATE 40001, which CockroachDB returns when transactions conflict and
+// PostgreSQL in the serializable isolation level. The code is read from the
+// errors of pgx and lib/pq, the message is checked otherwise.
+func isSerializationFailure(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return state.SQLState() == "40001"
+	}
+	var fields interface{ Get(k byte) string }
+	if errors.As(err, &fields) {
+		return fields.Get('C') == "40001"
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "restart transaction") || strings.Contains(msg, "could not serialize access")
+}
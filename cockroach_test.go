@@ -0,0 +1,125 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sqlStateError is a database error exposing its SQLSTATE, as the errors of
+// pgx do.
+type sqlStateError string
+
+func (e sqlStateError) Error() string {
+	return "database error " + string(e)
+}
+
+func (e sqlStateError) SQLState() string {
+	return string(e)
+}
+
+// fieldsError is a database error exposing its fields, as the errors of
+// lib/pq do.
+type fieldsError map[byte]string
+
+func (e fieldsError) Error() string {
+	return "pq: " + e['M']
+}
+
+func (e fieldsError) Get(k byte) string {
+	return e[k]
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	assert.True(t, isSerializationFailure(sqlStateError("40001")))
+	assert.True(t, isSerializationFailure(fmt.Errorf("Query failed: %w", sqlStateError("40001"))))
+	assert.False(t, isSerializationFailure(sqlStateError("42P01")))
+	assert.True(t, isSerializationFailure(fieldsError{'C': "40001", 'M': "restart transaction"}))
+	assert.False(t, isSerializationFailure(fieldsError{'C': "23505", 'M': "duplicate key value"}))
+	assert.True(t, isSerializationFailure(errors.New("pq: could not serialize access due to concurrent update")))
+	assert.False(t, isSerializationFailure(errors.New("pq: relation \"pets\" does not exist")))
+}
+
+func TestTxRetries(t *testing.T) {
+	options := *DefaultOptions
+	m := New(sqlx.NewDb(nil, "postgres"), &options, migrations)
+	assert.Equal(t, 0, m.txRetries())
+
+	m.capabilities = &Capabilities{CockroachDB: true}
+	assert.Equal(t, cockroachTxRetries, m.txRetries())
+	assert.Equal(t, LockRow, m.lockStrategy(false))
+
+	options.TxRetries = -1
+	assert.Equal(t, 0, m.txRetries())
+
+	options.TxRetries = 5
+	assert.Equal(t, 5, m.txRetries())
+}
+
+func TestRetrySerializationFailure(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		attempts := 0
+		conflicting := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				attempts++
+				if attempts == 1 {
+					return sqlStateError("40001")
+				}
+				return nil
+			},
+			RollbackTx: func(tx *Tx) error {
+				return nil
+			},
+		}
+
+		options := *DefaultOptions
+		options.TxRetries = 1
+		m := New(db, &options, append(migrations, conflicting))
+
+		var relocked []string
+		m.OnTransition(func(tr Transition) {
+			if tr.To == StateLocked && tr.From != StatePlanned {
+				relocked = append(relocked, tr.MigrationID)
+			}
+		})
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, []string{"201608301400", "201608301430", "201608301500"}, relocked)
+		assert.Len(t, res.Migrations, 3)
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+		assert.True(t, m.hasTable("pets"))
+	})
+}
+
+func TestRetrySerializationFailureExhausted(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		attempts := 0
+		conflicting := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				attempts++
+				return sqlStateError("40001")
+			},
+			RollbackTx: func(tx *Tx) error {
+				return nil
+			},
+		}
+
+		options := *DefaultOptions
+		options.TxRetries = 2
+		m := New(db, &options, append(migrations, conflicting))
+
+		assert.True(t, errors.Is(m.Migrate(), sqlStateError("40001")))
+		assert.Equal(t, 3, attempts)
+		if db.DriverName() != "mysql" {
+			assert.Equal(t, 0, tableCount(t, db, "migrations"))
+		}
+	})
+}
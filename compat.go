@@ -0,0 +1,82 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// IncompatibleTableError is returned when migrations can't be recorded in
+// the migration table, ie. a newer release or an operator added a column
+// this release doesn't fill.
+type IncompatibleTableError struct {
+	Table  string
+	Reason string
+}
+
+func (e *IncompatibleTableError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migration table "%s" is incompatible: %s`, e.Table, e.Reason)
+}
+
+// Hint implements Hinter.
+func (e *IncompatibleTableError) Hint() string {
+	return "The table was changed by a newer release or by hand. Upgrade the binaries running the migrations, or make the reported column nullable."
+}
+
+// CompatibilityCheck verifies this release can record migrations in the
+// migration table, which is useful for fleets running several releases. The
+// columns added by newer releases are ignored as long as they are nullable
+// or have a default. It is run before migrations are run, and by Validate.
+func (g *Sqlxmigrate) CompatibilityCheck() error {
+	return g.compatibilityCheck(context.Background())
+}
+
+func (g *Sqlxmigrate) compatibilityCheck(ctx context.Context) error {
+	if !g.usesTable() {
+		return nil
+	}
+	if ok, err := g.tableExists(ctx, g.options.TableName); !ok || err != nil {
+		return err
+	}
+
+	columns, err := g.tableColumns(ctx, g.options.TableName)
+	if err == ErrUnsupportedDriver {
+		// Only the ID column can be checked without a catalog.
+		if ok, err := g.hasColumn(ctx, g.options.TableName, g.options.IDColumnName); err != nil {
+			return err
+		} else if !ok {
+			return &IncompatibleTableError{Table: g.options.TableName, Reason: fmt.Sprintf(`the ID column "%s" is missing`, g.options.IDColumnName)}
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	known := map[string]bool{g.options.IDColumnName: true}
+	for _, u := range tableUpgrades {
+		known[u.column] = true
+	}
+
+	var hasID bool
+	var extra []string
+	for _, c := range columns {
+		if c.Name == g.options.IDColumnName {
+			hasID = true
+		}
+		if known[c.Name] {
+			continue
+		}
+		if !c.Nullable && c.Default == nil {
+			return &IncompatibleTableError{Table: g.options.TableName, Reason: fmt.Sprintf(`the column "%s" is NOT NULL without default`, c.Name)}
+		}
+		extra = append(extra, c.Name)
+	}
+	if !hasID {
+		return &IncompatibleTableError{Table: g.options.TableName, Reason: fmt.Sprintf(`the ID column "%s" is missing`, g.options.IDColumnName)}
+	}
+
+	if len(extra) > 0 {
+		g.logf("CompatibilityCheck - ignoring columns %s unknown to this release", strings.Join(extra, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompatibilityCheck(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		assert.NoError(t, m.CompatibilityCheck())
+
+		require.NoError(t, m.MigrateTo("201608301400"))
+		assert.NoError(t, m.CompatibilityCheck())
+
+		// a nullable column added by a newer release is ignored
+		_, err := db.Exec("ALTER TABLE migrations ADD COLUMN applied_by VARCHAR(64) NULL")
+		require.NoError(t, err)
+		assert.NoError(t, m.CompatibilityCheck())
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestCompatibilityCheckNotNullColumn(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		_, err := db.Exec("ALTER TABLE migrations ADD COLUMN applied_by VARCHAR(64) NOT NULL DEFAULT 'deploy'")
+		require.NoError(t, err)
+		assert.NoError(t, m.CompatibilityCheck())
+
+		_, err = db.Exec("ALTER TABLE migrations ALTER COLUMN applied_by DROP DEFAULT")
+		require.NoError(t, err)
+
+		err = m.Migrate()
+		assert.IsType(t, &IncompatibleTableError{}, err)
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+	}, "postgres", "mysql")
+}
+
+func TestCompatibilityCheckMissingIDColumn(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		_, err := db.Exec("CREATE TABLE migrations (version VARCHAR(255) PRIMARY KEY)")
+		require.NoError(t, err)
+
+		m := New(db, DefaultOptions, migrations)
+		assert.IsType(t, &IncompatibleTableError{}, m.CompatibilityCheck())
+	})
+}
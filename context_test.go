@@ -0,0 +1,108 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contextKey string
+
+func TestMigrateContext(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		var got interface{}
+		m := New(db, DefaultOptions, []*Migration{{
+			ID: "201608301400",
+			MigrateContext: func(ctx context.Context, tx *sql.Tx) error {
+				got = ctx.Value(contextKey("deploy"))
+				return nil
+			},
+			RollbackContext: func(ctx context.Context, tx *sql.Tx) error {
+				got = ctx.Value(contextKey("rollback"))
+				return nil
+			},
+		}})
+
+		ctx := context.WithValue(context.Background(), contextKey("deploy"), "v1.2.0")
+		require.NoError(t, m.MigrateContext(ctx))
+		assert.Equal(t, "v1.2.0", got)
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+
+		ctx = context.WithValue(context.Background(), contextKey("rollback"), "incident")
+		require.NoError(t, m.RollbackLastContext(ctx))
+		assert.Equal(t, "incident", got)
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestMigrateContextCanceled(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := m.MigrateContext(ctx)
+		assert.True(t, errors.Is(err, context.Canceled), "unexpected error %v", err)
+		// The bookkeeping queries use the context too, so nothing was
+		// created.
+		assert.False(t, m.hasTable("migrations"))
+	})
+}
+
+func TestBookkeepingContext(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		require.NoError(t, m.Migrate())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// The checks of the runs and their public wrappers query with the
+		// context they're given.
+		assert.True(t, errors.Is(m.compatibilityCheck(ctx), context.Canceled))
+		assert.True(t, errors.Is(m.validate(ctx), context.Canceled))
+		_, err := m.pending(ctx)
+		assert.True(t, errors.Is(err, context.Canceled))
+		_, err = m.status(ctx)
+		assert.True(t, errors.Is(err, context.Canceled))
+		_, err = m.inspect(ctx)
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+}
+
+func TestMigrateContextCanceledDuringRun(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var ran []string
+		m := New(db, DefaultOptions, []*Migration{
+			{
+				ID: "201608301400",
+				MigrateContext: func(ctx context.Context, tx *sql.Tx) error {
+					ran = append(ran, "201608301400")
+					cancel()
+					return nil
+				},
+			},
+			{
+				ID: "201608301430",
+				MigrateContext: func(ctx context.Context, tx *sql.Tx) error {
+					ran = append(ran, "201608301430")
+					return nil
+				},
+			},
+		})
+
+		err := m.MigrateContext(ctx)
+		assert.True(t, errors.Is(err, context.Canceled), "unexpected error %v", err)
+		assert.Equal(t, []string{"201608301400"}, ran)
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+	})
+}
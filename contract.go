@@ -0,0 +1,115 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"time"
+)
+
+// contractIDSuffix is appended to the ID of an expand migration to name its
+// contract migration.
+const contractIDSuffix = "_contract"
+
+// Contract declares the cleanup an expand migration schedules, ie. dropping
+// the column it replaces once no release reads it anymore. The contract
+// migration is added right after the expand migration, with its ID suffixed
+// by "_contract", and is deferred by the runs until the expand migration was
+// applied for the safety window.
+type Contract struct {
+	// MigrateSQL is the script of the contract migration.
+	MigrateSQL string
+	// RollbackSQL is the script undoing it.
+	RollbackSQL string
+	// After is the safety window between the expand migration being applied
+	// and the contract migration becoming eligible.
+	After time.Duration
+}
+
+// withContracts returns the migrations with the contract migrations of the
+// expand migrations added after them. The migrations of another migrator,
+// ie. a staging one, already include them.
+func withContracts(migrations []*Migration) []*Migration {
+	var n int
+	for _, m := range migrations {
+		if m.expandID != "" {
+			return migrations
+		}
+		if m.Contract != nil {
+			n++
+		}
+	}
+	if n == 0 {
+		return migrations
+	}
+
+	all := make([]*Migration, 0, len(migrations)+n)
+	for _, m := range migrations {
+		all = append(all, m)
+		if m.Contract != nil {
+			all = append(all, &Migration{
+				ID:          m.ID + contractIDSuffix,
+				MigrateSQL:  m.Contract.MigrateSQL,
+				RollbackSQL: m.Contract.RollbackSQL,
+				expandID:    m.ID,
+			})
+		}
+	}
+	return all
+}
+
+// contractSchedule returns when each contract migration becomes eligible,
+// nil while its expand migration isn't applied. The time is zero when the
+// expand migration was applied before the migration table recorded when.
+func (g *Sqlxmigrate) contractSchedule(ctx context.Context) (map[string]*time.Time, error) {
+	schedule := make(map[string]*time.Time)
+	var contracts []*Migration
+	for _, m := range g.migrations {
+		if m.expandID != "" {
+			contracts = append(contracts, m)
+			schedule[m.ID] = nil
+		}
+	}
+	if len(contracts) == 0 {
+		return schedule, nil
+	}
+
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt, err := g.auditAppliedAt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := g.appliedMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range contracts {
+		if _, ok := applied[m.expandID]; !ok {
+			continue
+		}
+		at := appliedAt[m.expandID]
+		if md, ok := metadata[m.expandID]; ok && md.appliedAt != nil {
+			at = md.appliedAt
+		}
+
+		var eligible time.Time
+		if at != nil {
+			eligible = at.Add(g.contractOf(m).After)
+		}
+		schedule[m.ID] = &eligible
+	}
+	return schedule, nil
+}
+
+// contractOf returns the contract declared by the expand migration of a
+// contract migration.
+func (g *Sqlxmigrate) contractOf(m *Migration) *Contract {
+	for _, expand := range g.migrations {
+		if expand.ID == m.expandID {
+			return expand.Contract
+		}
+	}
+	return &Contract{}
+}
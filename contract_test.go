@@ -0,0 +1,87 @@
+package sqlxmigrate
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContracts(t *testing.T) {
+	expand := *migrations[1]
+	expand.Contract = &Contract{MigrateSQL: "DROP TABLE people_old", After: time.Hour}
+
+	all := withContracts([]*Migration{migrations[0], &expand, extendedMigrations[2]})
+	assert.Equal(t, []string{"201608301400", "201608301430", "201608301430_contract", "201807221927"}, migrationIDs(all))
+	assert.Equal(t, "DROP TABLE people_old", all[2].MigrateSQL)
+	assert.Equal(t, "201608301430", all[2].expandID)
+
+	// The lists without contracts, or including them already, are kept as is.
+	assert.Equal(t, migrations, withContracts(migrations))
+	assert.Equal(t, all, withContracts(all))
+}
+
+func TestContractScheduling(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		expand := *migrations[1]
+		expand.Contract = &Contract{
+			MigrateSQL:  "CREATE TABLE contracted (id INTEGER)",
+			RollbackSQL: "DROP TABLE contracted",
+			After:       time.Hour,
+		}
+		m := New(db, DefaultOptions, []*Migration{migrations[0], &expand})
+		require.NoError(t, m.Validate())
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, resultIDs(res))
+		assert.Equal(t, []string{"201608301430_contract"}, res.Deferred)
+
+		statuses, err := m.Status()
+		require.NoError(t, err)
+		require.Len(t, statuses, 3)
+		contract := statuses[2]
+		assert.Equal(t, "201608301430_contract", contract.ID)
+		assert.True(t, contract.Scheduled())
+		require.NotNil(t, statuses[1].AppliedAt)
+		assert.Equal(t, statuses[1].AppliedAt.Add(time.Hour), *contract.ScheduledAt)
+
+		// The run after the safety window applies the contract migration.
+		expand.Contract.After = 0
+		res, err = m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301430_contract"}, resultIDs(res))
+		assert.Empty(t, res.Deferred)
+
+		ok, err := m.HasTable("contracted")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+	})
+}
+
+func TestContractIDPattern(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		expand := &Migration{
+			ID:          "201608301400_people",
+			MigrateSQL:  "CREATE TABLE people (id INTEGER)",
+			RollbackSQL: "DROP TABLE people",
+			Contract:    &Contract{MigrateSQL: "DROP TABLE people_old", After: time.Hour},
+		}
+		options := *DefaultOptions
+		options.IDPattern = regexp.MustCompile(`^\d{12}_[a-z]+$`)
+
+		// The generated ID of the contract migration doesn't match the
+		// pattern.
+		m := New(db, &options, []*Migration{expand})
+		assert.NoError(t, m.Validate())
+
+		expand.ID = "201608301400-people"
+		m = New(db, &options, []*Migration{expand})
+		assert.Equal(t, &InvalidIDError{ID: "201608301400-people", Pattern: options.IDPattern}, m.Validate())
+	})
+}
@@ -0,0 +1,15 @@
+//go:build crdb
+// +build crdb
+
+package sqlxmigrate
+
+import (
+	_ "github.com/lib/pq"
+)
+
+func init() {
+	databases = append(databases, database{
+		name:    "postgres",
+		connEnv: "CRDB_CONN_STRING",
+	})
+}
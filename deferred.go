@@ -0,0 +1,42 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"time"
+)
+
+// gated returns whether a migration can be deferred by the runs, so it is
+// expected to be applied after the migrations that follow it.
+func (m *Migration) gated() bool {
+	return m.Flag != "" || !m.NotBefore.IsZero() || m.expandID != ""
+}
+
+// deferMigration returns whether a pending migration is deferred by the run:
+// its NotBefore time didn't come yet, its flag is disabled, or it is a
+// contract migration whose safety window didn't pass, as listed by the
+// schedule of contractSchedule.
+func (g *Sqlxmigrate) deferMigration(ctx context.Context, m *Migration, schedule map[string]*time.Time) (bool, error) {
+	if time.Now().Before(m.NotBefore) {
+		g.logf("Migration %s - deferred until %s", m.ID, m.NotBefore.UTC().Format(time.RFC3339))
+		return true, nil
+	}
+
+	if m.expandID != "" {
+		if eligible := schedule[m.ID]; eligible == nil {
+			g.logf("Migration %s - deferred until %s is applied", m.ID, m.expandID)
+			return true, nil
+		} else if time.Now().Before(*eligible) {
+			g.logf("Migration %s - deferred until %s", m.ID, eligible.Format(time.RFC3339))
+			return true, nil
+		}
+	}
+
+	disabled, err := g.flagDisabled(ctx, m)
+	if err != nil {
+		return false, err
+	}
+	if disabled {
+		g.logf("Migration %s - deferred, flag %s disabled", m.ID, m.Flag)
+	}
+	return disabled, nil
+}
@@ -0,0 +1,38 @@
+package sqlxmigrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotBefore(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		contract := *migrations[1]
+		contract.NotBefore = time.Now().Add(time.Hour)
+		delayed := []*Migration{migrations[0], &contract, extendedMigrations[2]}
+
+		m := New(db, DefaultOptions, delayed)
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301430"}, res.Deferred)
+		assert.Equal(t, []string{"201608301400", "201807221927"}, resultIDs(res))
+		assert.Equal(t, StateDeferred, m.states["201608301430"])
+
+		// Once the safety window passed the migration is applied, without
+		// being reported out of order.
+		contract.NotBefore = time.Now().Add(-time.Minute)
+		res, err = m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Empty(t, res.Deferred)
+		assert.Equal(t, []string{"201608301430"}, resultIDs(res))
+		for _, w := range res.Warnings {
+			assert.NotEqual(t, WarningOutOfOrder, w.Code)
+		}
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+	})
+}
@@ -0,0 +1,114 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MissingDependencyError is returned when a migration depends on a migration
+// which isn't in the list of migrations.
+type MissingDependencyError struct {
+	ID         string
+	Dependency string
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migration "%s" depends on the unknown migration "%s"`, e.ID, e.Dependency)
+}
+
+// Hint implements Hinter.
+func (e *MissingDependencyError) Hint() string {
+	return "Check the ID in DependsOn, or add the migration it depends on, ie. after merging the branch introducing it."
+}
+
+// DependencyCycleError is returned when migrations depend on each other, so
+// they can't be ordered.
+type DependencyCycleError struct {
+	IDs []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migrations "%s" depend on each other`, strings.Join(e.IDs, `", "`))
+}
+
+// Hint implements Hinter.
+func (e *DependencyCycleError) Hint() string {
+	return "Remove one of the DependsOn of the migrations forming the cycle."
+}
+
+// orderDependencies orders the migrations so each one follows the
+// migrations of its DependsOn. The first migration whose dependencies are
+// ordered is taken, so the order of the migrations without dependencies
+// between them is kept.
+func orderDependencies(migrations []*Migration) ([]*Migration, error) {
+	byID := make(map[string]bool, len(migrations))
+	hasDependencies := false
+	for _, m := range migrations {
+		byID[m.ID] = true
+		hasDependencies = hasDependencies || len(m.DependsOn) > 0
+	}
+	if !hasDependencies {
+		return migrations, nil
+	}
+	for _, m := range migrations {
+		for _, dep := range m.DependsOn {
+			if !byID[dep] {
+				return nil, &MissingDependencyError{ID: m.ID, Dependency: dep}
+			}
+		}
+	}
+
+	ordered := make([]*Migration, 0, len(migrations))
+	done := make(map[string]bool, len(migrations))
+	for len(ordered) < len(migrations) {
+		var next *Migration
+		for _, m := range migrations {
+			if !done[m.ID] && dependenciesDone(m, done) {
+				next = m
+				break
+			}
+		}
+		if next == nil {
+			var cycle []string
+			for _, m := range migrations {
+				if !done[m.ID] {
+					cycle = append(cycle, m.ID)
+				}
+			}
+			return nil, &DependencyCycleError{IDs: cycle}
+		}
+
+		ordered = append(ordered, next)
+		done[next.ID] = true
+	}
+	return ordered, nil
+}
+
+// dependenciesDone returns whether the migrations a migration depends on are
+// in the set.
+func dependenciesDone(m *Migration, done map[string]bool) bool {
+	for _, dep := range m.DependsOn {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// Check whether the dependencies of the migrations exist and can be
+// ordered. New leaves the migrations in the given order when they can't.
+func (g *Sqlxmigrate) checkDependencies() error {
+	_, err := orderDependencies(g.migrations)
+	return err
+}
+
+// pendingDependency returns the first dependency of a migration which is
+// neither applied nor planned by the run, ie. deferred.
+func pendingDependency(m *Migration, applied map[string]struct{}, planned map[string]bool) string {
+	for _, dep := range m.DependsOn {
+		if _, ok := applied[dep]; !ok && !planned[dep] {
+			return dep
+		}
+	}
+	return ""
+}
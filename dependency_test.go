@@ -0,0 +1,100 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderDependencies(t *testing.T) {
+	ordered, err := orderDependencies([]*Migration{
+		{ID: "201906121200_a", DependsOn: []string{"201906121300_c"}},
+		{ID: "201906121230_b"},
+		{ID: "201906121300_c"},
+		{ID: "201906121330_d", DependsOn: []string{"201906121200_a", "201906121230_b"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"201906121230_b", "201906121300_c", "201906121200_a", "201906121330_d"}, migrationIDs(ordered))
+
+	_, err = orderDependencies([]*Migration{{ID: "a"}, {ID: "b", DependsOn: []string{"z"}}})
+	var missing *MissingDependencyError
+	require.True(t, errors.As(err, &missing))
+	assert.Equal(t, "b", missing.ID)
+	assert.Equal(t, "z", missing.Dependency)
+	assert.NotEmpty(t, Hint(err))
+
+	_, err = orderDependencies([]*Migration{
+		{ID: "a"},
+		{ID: "b", DependsOn: []string{"c"}},
+		{ID: "c", DependsOn: []string{"b"}},
+	})
+	var cycle *DependencyCycleError
+	require.True(t, errors.As(err, &cycle))
+	assert.Equal(t, []string{"b", "c"}, cycle.IDs)
+}
+
+func TestDependsOn(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		// The pets migration is registered first but depends on the people
+		// one.
+		pets := *migrations[1]
+		pets.DependsOn = []string{migrations[0].ID}
+		m := New(db, DefaultOptions, []*Migration{&pets, migrations[0]})
+		assert.Equal(t, []string{"201608301400", "201608301430"}, migrationIDs(m.migrations))
+
+		require.NoError(t, m.Validate())
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, resultIDs(res))
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+		require.NoError(t, m.RollbackLast())
+	})
+}
+
+func TestDependsOnErrors(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		pets := *migrations[1]
+		pets.DependsOn = []string{"201608301300"}
+		m := New(db, DefaultOptions, []*Migration{migrations[0], &pets})
+
+		var missing *MissingDependencyError
+		assert.True(t, errors.As(m.Validate(), &missing))
+		assert.True(t, errors.As(m.Migrate(), &missing))
+		assert.False(t, m.hasTable("people"))
+	})
+}
+
+func TestDependsOnDeferred(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		people := *migrations[0]
+		people.Flag = "people"
+		pets := *migrations[1]
+		pets.DependsOn = []string{people.ID}
+
+		enabled := false
+		options := *DefaultOptions
+		options.Flags = FlagFunc(func(ctx context.Context, flag string) (bool, error) {
+			return enabled, nil
+		})
+		m := New(db, &options, []*Migration{&people, &pets})
+
+		// The pets migration waits for the people one to be applied.
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, res.Deferred)
+		assert.Empty(t, res.Migrations)
+
+		enabled = true
+		res, err = m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, resultIDs(res))
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+		require.NoError(t, m.RollbackLast())
+	})
+}
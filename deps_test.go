@@ -0,0 +1,24 @@
+package sqlxmigrate
+
+import (
+	"go/build"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCoreDependencies ensures the core package only depends on the standard
+// library and sqlx, the extras belong to subpackages.
+func TestCoreDependencies(t *testing.T) {
+	pkg, err := build.ImportDir(".", 0)
+	require.NoError(t, err)
+
+	for _, imp := range pkg.Imports {
+		if !strings.Contains(strings.SplitN(imp, "/", 2)[0], ".") {
+			continue
+		}
+		assert.Equal(t, "github.com/jmoiron/sqlx", imp, "core package imports %s", imp)
+	}
+}
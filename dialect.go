@@ -0,0 +1,217 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect implements the queries whose SQL differs between databases. The
+// dialect of a database is chosen by the name of its driver, the dialects of
+// other databases are added with RegisterDialect.
+type Dialect interface {
+	// Rebind converts the ? bindvars of a query to the ones of the driver.
+	Rebind(query string) string
+	// TableExists returns whether a table exists, querying the catalog of
+	// the database with db. The schema is nil when the name of the table
+	// isn't qualified, the table is then looked up in the current schema or
+	// database.
+	TableExists(ctx context.Context, db Execer, schema *string, table string) (bool, error)
+	// CreateVersionTableSQL returns the statement creating the migration
+	// table, with the ID column as primary key.
+	CreateVersionTableSQL(table, idColumn string, idSize int) string
+	// AddColumnSQL returns the statement adding a nullable column to the
+	// migration table when it is upgraded, ie. with the definition
+	// "TIMESTAMP NULL".
+	AddColumnSQL(table, column, definition string) string
+	// LockSQL returns the statements taking and releasing the lock
+	// serializing the runs when Options.UseLock is set. They are executed
+	// on a dedicated connection with the key of the lock, an int64, as
+	// argument, and the lock statement waits until the lock is held. Empty
+	// when the database has no such lock: the runs are then serialized by a
+	// lock row in the migration table, or by the named locks of MySQL and
+	// SQL Server.
+	LockSQL() (lock, unlock string)
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = make(map[string]Dialect)
+)
+
+// RegisterDialect sets the dialect of the databases opened with a driver,
+// ie. for ClickHouse or Snowflake, replacing the built-in one if any. It is
+// usually called by an init function.
+func RegisterDialect(driverName string, d Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[driverName] = d
+}
+
+// dialect returns the dialect of the database.
+func (g *Sqlxmigrate) dialect() Dialect {
+	dialectsMu.RLock()
+	d, ok := dialects[g.db.DriverName()]
+	dialectsMu.RUnlock()
+	if ok {
+		return d
+	}
+
+	switch {
+	case g.isPostgres():
+		return postgresDialect{}
+	case g.isMySQL():
+		return mysqlDialect{}
+	case g.isSQLite():
+		return sqliteDialect{}
+	case g.isSQLServer():
+		return sqlServerDialect{}
+	default:
+		return ansiDialect{bindType: sqlx.BindType(g.db.DriverName())}
+	}
+}
+
+// ansiDialect is the dialect of the databases without a dedicated one,
+// relying on the SQL standard and its information schema. The other
+// dialects embed it.
+type ansiDialect struct {
+	bindType int
+}
+
+// NewANSIDialect returns the dialect relying on the SQL standard, with the
+// bindvars of sqlx.BindType, for the registered dialects to embed.
+func NewANSIDialect(bindType int) Dialect {
+	return ansiDialect{bindType: bindType}
+}
+
+func (d ansiDialect) Rebind(query string) string {
+	return sqlx.Rebind(d.bindType, query)
+}
+
+func (ansiDialect) TableExists(ctx context.Context, db Execer, schema *string, table string) (bool, error) {
+	if schema == nil {
+		return exists(ctx, db, "SELECT count(*) FROM information_schema.tables WHERE table_name = ?", table)
+	}
+	return exists(ctx, db, "SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ?", *schema, table)
+}
+
+func (ansiDialect) CreateVersionTableSQL(table, idColumn string, idSize int) string {
+	return fmt.Sprintf("CREATE TABLE %s (%s VARCHAR(%d) PRIMARY KEY)", table, idColumn, idSize)
+}
+
+func (ansiDialect) AddColumnSQL(table, column, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+}
+
+func (ansiDialect) LockSQL() (string, string) {
+	return "", ""
+}
+
+// postgresDialect is PostgreSQL and CockroachDB. The tables are looked up in
+// pg_catalog, which unlike the information schema lists the tables the user
+// has no privilege on.
+type postgresDialect struct{}
+
+func (postgresDialect) Rebind(query string) string {
+	return sqlx.Rebind(sqlx.DOLLAR, query)
+}
+
+func (postgresDialect) TableExists(ctx context.Context, db Execer, schema *string, table string) (bool, error) {
+	return exists(ctx, db, "SELECT count(*) FROM pg_catalog.pg_tables WHERE schemaname = coalesce(?, current_schema()) AND tablename = ?", schema, table)
+}
+
+func (postgresDialect) CreateVersionTableSQL(table, idColumn string, idSize int) string {
+	return ansiDialect{}.CreateVersionTableSQL(table, idColumn, idSize)
+}
+
+func (postgresDialect) AddColumnSQL(table, column, definition string) string {
+	return ansiDialect{}.AddColumnSQL(table, column, definition)
+}
+
+func (postgresDialect) LockSQL() (string, string) {
+	return "SELECT pg_advisory_lock(?)", "SELECT pg_advisory_unlock(?)"
+}
+
+type mysqlDialect struct {
+	ansiDialect
+}
+
+func (mysqlDialect) TableExists(ctx context.Context, db Execer, schema *string, table string) (bool, error) {
+	return exists(ctx, db, "SELECT count(*) FROM information_schema.tables WHERE table_schema = coalesce(?, DATABASE()) AND table_name = ?", schema, table)
+}
+
+// sqliteDialect looks the tables up in the sqlite_master table of the
+// attached database named by the schema.
+type sqliteDialect struct {
+	ansiDialect
+}
+
+func (sqliteDialect) TableExists(ctx context.Context, db Execer, schema *string, table string) (bool, error) {
+	return exists(ctx, db, fmt.Sprintf("SELECT count(*) FROM %s WHERE type = 'table' AND name = ?", sqliteMaster(schema)), table)
+}
+
+// sqlServerDialect uses the @p1 bindvars, which sqlx only knows for the
+// "sqlserver" driver name, not the legacy "mssql" one.
+type sqlServerDialect struct{}
+
+func (sqlServerDialect) Rebind(query string) string {
+	return sqlx.Rebind(sqlx.AT, query)
+}
+
+func (sqlServerDialect) TableExists(ctx context.Context, db Execer, schema *string, table string) (bool, error) {
+	name := table
+	if schema != nil {
+		name = *schema + "." + table
+	}
+	return exists(ctx, db, "SELECT count(*) WHERE OBJECT_ID(?, 'U') IS NOT NULL", name)
+}
+
+func (sqlServerDialect) CreateVersionTableSQL(table, idColumn string, idSize int) string {
+	return ansiDialect{}.CreateVersionTableSQL(table, idColumn, idSize)
+}
+
+// AddColumnSQL leaves out the COLUMN keyword, which SQL Server rejects.
+func (sqlServerDialect) AddColumnSQL(table, column, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s %s", table, column, sqlServerColumnType(definition))
+}
+
+func (sqlServerDialect) LockSQL() (string, string) {
+	return "", ""
+}
+
+// exists runs a count query against the catalog.
+func exists(ctx context.Context, db Execer, query string, args ...interface{}) (bool, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		err = queryError(err, query)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// catalog is the Execer the dialects query the catalog with, which rebinds
+// the queries for the driver and logs them.
+type catalog struct {
+	g *Sqlxmigrate
+}
+
+func (c catalog) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query = c.g.rebind(query)
+	c.g.logf("catalog %s", query)
+	return c.g.db.ExecContext(ctx, query, args...)
+}
+
+func (c catalog) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	query = c.g.rebind(query)
+	c.g.logf("catalog %s", query)
+	return c.g.db.QueryContext(ctx, query, args...)
+}
+
+func (c catalog) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	query = c.g.rebind(query)
+	c.g.logf("catalog %s", query)
+	return c.g.db.QueryRowContext(ctx, query, args...)
+}
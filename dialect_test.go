@@ -0,0 +1,110 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialect(t *testing.T) {
+	for driver, want := range map[string]Dialect{
+		"postgres":  postgresDialect{},
+		"pgx":       postgresDialect{},
+		"mysql":     mysqlDialect{},
+		"sqlite3":   sqliteDialect{},
+		"sqlserver": sqlServerDialect{},
+		"mssql":     sqlServerDialect{},
+		"snowflake": ansiDialect{},
+	} {
+		m := New(sqlx.NewDb(nil, driver), DefaultOptions, migrations)
+		assert.Equal(t, want, m.dialect(), driver)
+	}
+}
+
+func TestDialectTableExists(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		ctx := context.Background()
+		m := New(db, DefaultOptions, migrations)
+		require.NoError(t, m.Migrate())
+
+		ok, err := m.dialect().TableExists(ctx, catalog{m}, nil, "migrations")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		// Missing tables don't fail the lookup, whatever the language of the
+		// errors of the database.
+		ok, err = m.dialect().TableExists(ctx, catalog{m}, nil, "missing")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestANSIDialectTableExists(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		ctx := context.Background()
+		m := New(db, DefaultOptions, migrations)
+		require.NoError(t, m.Migrate())
+
+		ok, err := ansiDialect{}.TableExists(ctx, catalog{m}, nil, "migrations")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = ansiDialect{}.TableExists(ctx, catalog{m}, nil, "missing")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}, "postgres", "mysql")
+}
+
+// recordingDialect is a registered dialect delegating to a built-in one,
+// which records the migration tables it creates.
+type recordingDialect struct {
+	Dialect
+	created []string
+}
+
+func (d *recordingDialect) CreateVersionTableSQL(table, idColumn string, idSize int) string {
+	sql := d.Dialect.CreateVersionTableSQL(table, idColumn, idSize)
+	d.created = append(d.created, sql)
+	return sql
+}
+
+// registerDialect registers a dialect for the duration of a test.
+func registerDialect(t *testing.T, driverName string, d Dialect) {
+	RegisterDialect(driverName, d)
+	t.Cleanup(func() {
+		dialectsMu.Lock()
+		defer dialectsMu.Unlock()
+		delete(dialects, driverName)
+	})
+}
+
+func TestRegisterDialect(t *testing.T) {
+	d := &recordingDialect{Dialect: postgresDialect{}}
+	registerDialect(t, "clickhouse", d)
+
+	m := New(sqlx.NewDb(nil, "clickhouse"), DefaultOptions, migrations)
+	assert.Equal(t, d, m.dialect())
+	assert.Equal(t, "SELECT * FROM migrations WHERE id = $1", m.rebind("SELECT * FROM migrations WHERE id = ?"))
+
+	// The session lock of the dialect is used unless behind a pooler.
+	assert.Equal(t, LockAdvisory, m.lockStrategy(false))
+	assert.Equal(t, LockRow, m.lockStrategy(true))
+
+	registerDialect(t, "clickhouse", NewANSIDialect(sqlx.QUESTION))
+	assert.Equal(t, LockRow, m.lockStrategy(false))
+	assert.Equal(t, "SELECT * FROM migrations WHERE id = ?", m.rebind("SELECT * FROM migrations WHERE id = ?"))
+}
+
+func TestRegisterDialectMigrate(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		d := &recordingDialect{Dialect: m.dialect()}
+		registerDialect(t, db.DriverName(), d)
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, []string{"CREATE TABLE migrations (id VARCHAR(255) PRIMARY KEY)"}, d.created)
+	})
+}
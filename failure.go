@@ -0,0 +1,88 @@
+package sqlxmigrate
+
+import (
+	"context"
+)
+
+// FailurePolicy defines how a run handles a failing migration.
+type FailurePolicy int
+
+const (
+	// FailureAbort rolls back the transaction of the run, undoing the failed
+	// migration and the migrations applied before it in the transaction,
+	// and fails the run.
+	FailureAbort FailurePolicy = iota
+	// FailureRollbackMigration aborts the run as FailureAbort, then runs the
+	// rollback of the failed migration in a new transaction, undoing what
+	// the database committed implicitly, ie. the DDL on MySQL.
+	FailureRollbackMigration
+	// FailureContinueOthers commits each migration in its own transaction,
+	// so a failing migration is undone alone, and applies the migrations
+	// that neither depend on it nor share its group. The run fails with the
+	// error of the first failed migration once done.
+	FailureContinueOthers
+)
+
+// rollbackFailed runs the rollback of a migration that failed, in a new
+// transaction once the transaction of the run is aborted. The failure of the
+// migration fails the run, the rollback is only logged.
+func (g *Sqlxmigrate) rollbackFailed(ctx context.Context, migration *Migration) {
+	if !migration.hasRollback() {
+		g.logf("Migration %s - Rollback skipped, the migration has no rollback", migration.ID)
+		return
+	}
+
+	if err := g.begin(ctx); err != nil {
+		g.logf("Migration %s - Rollback failed - %v", migration.ID, err)
+		return
+	}
+	if err := migration.rollback(g.newTx(ctx, migration.ID)); err != nil {
+		g.rollback()
+		g.logf("Migration %s - Rollback failed - %v", migration.ID, err)
+		return
+	}
+	if err := g.commit(); err != nil {
+		g.logf("Migration %s - Rollback failed - %v", migration.ID, err)
+		return
+	}
+	g.logf("Migration %s - rolled back", migration.ID)
+}
+
+// continueAfterFailure records the migrations undone by the failure of the
+// planned migration at `i` as failed, the ones from `committed` which share
+// its transaction, and leaves the migrations depending on them or sharing
+// their group pending. It returns the planned migrations without the ones
+// left pending.
+func (g *Sqlxmigrate) continueAfterFailure(res *Result, planned []*Migration, committed, i int, err error) ([]*Migration, error) {
+	blocked := make(map[string]bool)
+	groups := make(map[string]bool)
+	for _, m := range planned[committed : i+1] {
+		if terr := g.transition(m, StateFailed, err); terr != nil {
+			return planned, terr
+		}
+		res.Failed = append(res.Failed, m.ID)
+		blocked[m.ID] = true
+		if m.Group != "" {
+			groups[m.Group] = true
+		}
+	}
+
+	// The dependencies of a migration are planned before it.
+	left := planned[: i+1 : i+1]
+	for _, m := range planned[i+1:] {
+		skip := groups[m.Group]
+		for _, id := range m.DependsOn {
+			skip = skip || blocked[id]
+		}
+		if !skip {
+			left = append(left, m)
+			continue
+		}
+		if err := g.skipPending(res, []*Migration{m}); err != nil {
+			return planned, err
+		}
+		g.logf("Migration %s - left pending, it depends on a failed migration", m.ID)
+		blocked[m.ID] = true
+	}
+	return left, nil
+}
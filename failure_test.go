@@ -0,0 +1,104 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// carsMigration creates the table cars then fails, counting the calls of
+// its rollback.
+func carsMigration(failure error, rollbacks *int) *Migration {
+	return &Migration{
+		ID: "201608301415",
+		MigrateTx: func(tx *Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE cars (id int PRIMARY KEY)`); err != nil {
+				return err
+			}
+			return failure
+		},
+		Rollback: func(tx *sql.Tx) error {
+			*rollbacks++
+			_, err := tx.Exec(`DROP TABLE IF EXISTS cars`)
+			return err
+		},
+	}
+}
+
+func TestOnFailureAbort(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		failure := errors.New("backfill failed")
+		var rollbacks int
+		m := New(db, DefaultOptions, []*Migration{migrations[0], carsMigration(failure, &rollbacks), migrations[1]})
+
+		err := m.Migrate()
+		assert.True(t, errors.Is(err, failure))
+		// The rollback isn't run in the aborted transaction.
+		assert.Equal(t, 0, rollbacks)
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("people"))
+		assert.False(t, m.hasTable("cars"))
+	}, "sqlite3", "postgres")
+}
+
+func TestOnFailureRollbackMigration(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		failure := errors.New("backfill failed")
+		var rollbacks int
+		options := *DefaultOptions
+		options.OnFailure = FailureRollbackMigration
+		m := New(db, &options, []*Migration{migrations[0], carsMigration(failure, &rollbacks)})
+
+		assert.True(t, errors.Is(m.Migrate(), failure))
+		assert.Equal(t, 1, rollbacks)
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("cars"))
+
+		// A migration without rollback fails the run all the same.
+		noRollback := carsMigration(failure, &rollbacks)
+		noRollback.Rollback = nil
+		m = New(db, &options, []*Migration{noRollback})
+		assert.True(t, errors.Is(m.Migrate(), failure))
+		assert.Equal(t, 1, rollbacks)
+	})
+}
+
+func TestOnFailureContinueOthers(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		failure := errors.New("backfill failed")
+		var rollbacks int
+		options := *DefaultOptions
+		options.OnFailure = FailureContinueOthers
+		m := New(db, &options, []*Migration{
+			migrations[0],
+			carsMigration(failure, &rollbacks),
+			migrations[1],
+			{
+				ID:         "201608301500",
+				DependsOn:  []string{"201608301415"},
+				MigrateSQL: `CREATE TABLE trucks (id int PRIMARY KEY)`,
+			},
+		})
+
+		res, err := m.MigrateWithResult()
+		var merr *MigrationError
+		require.True(t, errors.As(err, &merr))
+		assert.Equal(t, "201608301415", merr.MigrationID)
+		assert.True(t, errors.Is(err, failure))
+		assert.Equal(t, 0, rollbacks)
+
+		assert.Equal(t, []string{"201608301400", "201608301430"}, resultIDs(res))
+		assert.Equal(t, []string{"201608301415"}, res.Failed)
+		assert.Equal(t, []string{"201608301500"}, res.Pending)
+		assert.Equal(t, "201608301430", res.Version)
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+		assert.True(t, m.hasTable("people"))
+		assert.True(t, m.hasTable("pets"))
+		assert.False(t, m.hasTable("cars"))
+		assert.False(t, m.hasTable("trucks"))
+	}, "sqlite3", "postgres")
+}
@@ -0,0 +1,37 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+)
+
+// FlagProvider tells whether the feature flags gating migrations are enabled
+// for the environment, see Migration.Flag.
+type FlagProvider interface {
+	Enabled(ctx context.Context, flag string) (bool, error)
+}
+
+// FlagFunc adapts a function to a FlagProvider.
+type FlagFunc func(ctx context.Context, flag string) (bool, error)
+
+// Enabled calls f.
+func (f FlagFunc) Enabled(ctx context.Context, flag string) (bool, error) {
+	return f(ctx, flag)
+}
+
+// flagDisabled returns whether a migration is gated on a flag that is
+// disabled, so the run defers it.
+func (g *Sqlxmigrate) flagDisabled(ctx context.Context, m *Migration) (bool, error) {
+	if m.Flag == "" {
+		return false, nil
+	}
+	if g.options.Flags == nil {
+		return false, fmt.Errorf(`sqlxmigrate: Migration "%s" is gated on the flag "%s" but Options.Flags is nil`, m.ID, m.Flag)
+	}
+
+	enabled, err := g.options.Flags.Enabled(ctx, m.Flag)
+	if err != nil {
+		return false, fmt.Errorf(`sqlxmigrate: Could not check the flag "%s" of migration "%s": %w`, m.Flag, m.ID, err)
+	}
+	return !enabled, nil
+}
@@ -0,0 +1,80 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagGatedMigration(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		gated := *migrations[1]
+		gated.Flag = "pets"
+		flagged := []*Migration{migrations[0], &gated, extendedMigrations[2]}
+
+		enabled := false
+		options := *DefaultOptions
+		options.Flags = FlagFunc(func(ctx context.Context, flag string) (bool, error) {
+			assert.Equal(t, "pets", flag)
+			return enabled, nil
+		})
+		m := New(db, &options, flagged)
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301430"}, res.Deferred)
+		assert.Equal(t, []string{"201608301400", "201807221927"}, resultIDs(res))
+		assert.Equal(t, StateDeferred, m.states["201608301430"])
+		ok, err := m.HasTable("pets")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		// Once the flag is enabled the deferred migration is applied, without
+		// being reported out of order.
+		enabled = true
+		res, err = m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Empty(t, res.Deferred)
+		assert.Equal(t, []string{"201608301430"}, resultIDs(res))
+		for _, w := range res.Warnings {
+			assert.NotEqual(t, WarningOutOfOrder, w.Code)
+		}
+		ok, err = m.HasTable("pets")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+	})
+}
+
+func TestFlagGatedMigrationErrors(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		gated := *migrations[1]
+		gated.Flag = "pets"
+		flagged := []*Migration{migrations[0], &gated}
+
+		m := New(db, DefaultOptions, flagged)
+		assert.EqualError(t, m.Migrate(), `sqlxmigrate: Migration "201608301430" is gated on the flag "pets" but Options.Flags is nil`)
+
+		options := *DefaultOptions
+		errUnavailable := errors.New("flag service unavailable")
+		options.Flags = FlagFunc(func(ctx context.Context, flag string) (bool, error) {
+			return false, errUnavailable
+		})
+		m = New(db, &options, flagged)
+		assert.True(t, errors.Is(m.Migrate(), errUnavailable))
+	})
+}
+
+// resultIDs returns the IDs of the migrations applied by a run.
+func resultIDs(res *Result) []string {
+	var ids []string
+	for _, m := range res.Migrations {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
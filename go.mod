@@ -1,14 +1,14 @@
 module github.com/geeks-accelerator/sqlxmigrate
 
 require (
+	github.com/denisenkom/go-mssqldb v0.9.0
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/joho/godotenv v1.3.0
 	github.com/lib/pq v1.0.0
-	github.com/mattn/go-sqlite3 v1.10.0 // indirect
-	github.com/pkg/errors v0.8.1
+	github.com/mattn/go-sqlite3 v1.10.0
 	github.com/stretchr/testify v1.3.0
 	google.golang.org/appengine v1.3.0 // indirect
 )
 
-go 1.13
+go 1.16
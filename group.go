@@ -0,0 +1,98 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+)
+
+// GroupError is returned when the migrations of a group can't be applied
+// as one unit.
+type GroupError struct {
+	Group       string
+	MigrationID string
+	// DisableTransaction is set when the migration disables the transaction,
+	// otherwise it doesn't follow the other migrations of the group.
+	DisableTransaction bool
+}
+
+func (e *GroupError) Error() string {
+	if e.DisableTransaction {
+		return fmt.Sprintf(`sqlxmigrate: Migration "%s" of group "%s" disables the transaction`, e.MigrationID, e.Group)
+	}
+	return fmt.Sprintf(`sqlxmigrate: Migration "%s" of group "%s" doesn't follow the other migrations of the group`, e.MigrationID, e.Group)
+}
+
+// Hint implements Hinter.
+func (e *GroupError) Hint() string {
+	if e.DisableTransaction {
+		return "The migrations of a group are applied in one transaction, move the migration out of the group."
+	}
+	return "The migrations of a group are applied as one unit, move the migration next to the other migrations of the group."
+}
+
+// checkGroups checks that the migrations of each group follow each other
+// and run in the transaction of the run.
+func (g *Sqlxmigrate) checkGroups() error {
+	seen := make(map[string]bool)
+	for i, m := range g.migrations {
+		if m.Group == "" {
+			continue
+		}
+		if m.DisableTransaction {
+			return &GroupError{Group: m.Group, MigrationID: m.ID, DisableTransaction: true}
+		}
+		if seen[m.Group] && g.migrations[i-1].Group != m.Group {
+			return &GroupError{Group: m.Group, MigrationID: m.ID}
+		}
+		seen[m.Group] = true
+	}
+	return nil
+}
+
+// rollbackGroup rolls back the applied migrations of a group, in reverse
+// order, as part of the current transaction.
+func (g *Sqlxmigrate) rollbackGroup(ctx context.Context, group string) error {
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(g.migrations) - 1; i >= 0; i-- {
+		m := g.migrations[i]
+		if m.Group != group {
+			continue
+		}
+		if _, ok := applied[m.ID]; !ok {
+			continue
+		}
+		if err := g.rollbackMigration(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// continuesGroup reports whether the migration at `i` belongs to the group
+// of the migration before it.
+func continuesGroup(migrations []*Migration, i int) bool {
+	return i > 0 && migrations[i].Group != "" && migrations[i].Group == migrations[i-1].Group
+}
+
+// groupEnd returns the ID of the last migration of the group of the
+// migration that matches `migrationID`, the ID itself when the migration
+// isn't part of a group.
+func (g *Sqlxmigrate) groupEnd(migrationID string) string {
+	for i, m := range g.migrations {
+		if m.ID != migrationID {
+			continue
+		}
+		for i+1 < len(g.migrations) && continuesGroup(g.migrations, i+1) {
+			i++
+		}
+		if end := g.migrations[i].ID; end != migrationID {
+			g.logf("Migration %s - extended to %s, the end of group %s", migrationID, end, m.Group)
+			return end
+		}
+		break
+	}
+	return migrationID
+}
@@ -0,0 +1,109 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// groupedMigrations are the extended migrations with people and pets in a
+// group.
+func groupedMigrations() []*Migration {
+	people, pets := *extendedMigrations[0], *extendedMigrations[1]
+	people.Group, pets.Group = "animals", "animals"
+	return []*Migration{&people, &pets, extendedMigrations[2]}
+}
+
+func TestGroupMigrateTo(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, groupedMigrations())
+
+		// The targeted run applies the whole group.
+		res, err := m.MigrateToWithResult("201608301400")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, resultIDs(res))
+		assert.True(t, m.hasTable("pets"))
+		assert.False(t, m.hasTable("books"))
+
+		require.NoError(t, m.Migrate())
+
+		// The rollbacks undo the whole group.
+		require.NoError(t, m.RollbackTo("201608301400"))
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("books"))
+
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("people"))
+	})
+}
+
+func TestGroupRollbackN(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, groupedMigrations())
+		require.NoError(t, m.Migrate())
+
+		// The group counts as a single step.
+		assert.Equal(t, ErrTooManyRollbackSteps, m.RollbackN(3))
+		require.NoError(t, m.RollbackN(2))
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestGroupDeferred(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		grouped := groupedMigrations()
+		grouped[1].Flag = "pets"
+		options := *DefaultOptions
+		options.Flags = FlagFunc(func(ctx context.Context, flag string) (bool, error) {
+			return false, nil
+		})
+		m := New(db, &options, grouped)
+
+		// The group is deferred with its gated migration.
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, res.Deferred)
+		assert.Equal(t, []string{"201807221927"}, resultIDs(res))
+		assert.False(t, m.hasTable("people"))
+	})
+}
+
+func TestGroupPause(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		var checks int
+		options := *DefaultOptions
+		options.PauseFunc = func() (bool, error) {
+			checks++
+			// Pause once the first migration is applied.
+			return checks > 1, nil
+		}
+		m := New(db, &options, groupedMigrations())
+
+		// The run doesn't pause in the middle of the group.
+		res, err := m.MigrateWithResult()
+		assert.Equal(t, ErrRunPaused, err)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, resultIDs(res))
+		assert.Equal(t, []string{"201807221927"}, res.Pending)
+	})
+}
+
+func TestGroupErrors(t *testing.T) {
+	grouped := groupedMigrations()
+
+	// The migrations of a group must follow each other.
+	err := New(nil, DefaultOptions, []*Migration{grouped[0], grouped[2], grouped[1]}).Validate()
+	var groupErr *GroupError
+	require.True(t, errors.As(err, &groupErr))
+	assert.Equal(t, &GroupError{Group: "animals", MigrationID: "201608301430"}, groupErr)
+	assert.NotEmpty(t, Hint(err))
+
+	noTx := *grouped[1]
+	noTx.DisableTransaction = true
+	err = New(nil, DefaultOptions, []*Migration{grouped[0], &noTx}).Migrate()
+	assert.Equal(t, &GroupError{Group: "animals", MigrationID: "201608301430", DisableTransaction: true}, err)
+}
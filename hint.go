@@ -0,0 +1,58 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Hinter is implemented by the errors which suggest how to remediate them.
+type Hinter interface {
+	Hint() string
+}
+
+// Hint returns the remediation hint of the first error of the chain of err
+// implementing Hinter, or an empty string when none does.
+func Hint(err error) string {
+	var h Hinter
+	if errors.As(err, &h) {
+		return h.Hint()
+	}
+	return ""
+}
+
+// PermissionError is returned when the database user isn't allowed to run a
+// query on the migration tables.
+type PermissionError struct {
+	Query string
+	Err   error
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Permission denied running "%s": %v`, e.Query, e.Err)
+}
+
+// Unwrap returns the database error.
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// Hint implements Hinter.
+func (e *PermissionError) Hint() string {
+	return "Grant the database user running the migrations the privileges to create, read and write the migration tables, or run the migrations with a more privileged user."
+}
+
+// queryError wraps the error of a query run on the migration tables.
+func queryError(err error, query string) error {
+	if isPermissionDenied(err) {
+		return &PermissionError{Query: query, Err: err}
+	}
+	return fmt.Errorf("Query failed %s: %w", query, err)
+}
+
+// isPermissionDenied returns whether err is a privilege error, ie. SQLSTATE
+// 42501 for PostgreSQL, 1142 or 1044 for MySQL.
+func isPermissionDenied(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "permission denied") || strings.Contains(msg, "command denied") || strings.Contains(msg, "Access denied")
+}
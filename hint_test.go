@@ -0,0 +1,31 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHint(t *testing.T) {
+	err := fmt.Errorf("deploy: %w", &DuplicatedIDError{ID: "201608301400"})
+	assert.Equal(t, "Give each migration a unique ID, ie. a new timestamp.", Hint(err))
+
+	assert.Empty(t, Hint(ErrNoMigrationDefined))
+	assert.Empty(t, Hint(nil))
+}
+
+func TestQueryError(t *testing.T) {
+	denied := errors.New("pq: permission denied for table migrations")
+	err := queryError(denied, "SELECT id FROM migrations")
+	assert.IsType(t, &PermissionError{}, err)
+	assert.True(t, errors.Is(err, denied))
+	assert.NotEmpty(t, Hint(err))
+
+	failed := errors.New("pq: syntax error")
+	err = queryError(failed, "SELEC id FROM migrations")
+	assert.EqualError(t, err, "Query failed SELEC id FROM migrations: pq: syntax error")
+	assert.True(t, errors.Is(err, failed))
+	assert.Empty(t, Hint(err))
+}
@@ -0,0 +1,182 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// HistoryStore persists which migrations are applied. The default store is
+// the migration table, named by Options.TableName. A store outside of the
+// database, ie. a separate metadata database, can't record the migrations
+// atomically with their transaction: a migration recorded by a run whose
+// commit fails remains recorded.
+type HistoryStore interface {
+	// Init prepares the store before a run, ie. creates the migration table.
+	Init(ctx context.Context) error
+	// Applied returns the IDs of the applied migrations.
+	Applied(ctx context.Context) ([]string, error)
+	// Record records a migration as applied. tx is the transaction of the
+	// run, which the stores in the migrated database record the migration
+	// in.
+	Record(ctx context.Context, tx *sql.Tx, id string) error
+	// Remove records a migration as rolled back, in the transaction of the
+	// rollback.
+	Remove(ctx context.Context, tx *sql.Tx, id string) error
+}
+
+// SetHistoryStore sets the store of the applied migrations. The features
+// relying on the migration table, ie. the checksum of the init schema or
+// CompatibilityCheck, are disabled with a store other than the default one.
+func (g *Sqlxmigrate) SetHistoryStore(store HistoryStore) {
+	g.history = store
+}
+
+// usesTable returns whether the history is stored in the migration table.
+func (g *Sqlxmigrate) usesTable() bool {
+	_, ok := g.history.(*tableHistory)
+	return ok
+}
+
+// tableHistory is the default HistoryStore, the migration table. The
+// statements recording the migrations are prepared once per transaction of
+// the run.
+type tableHistory struct {
+	g        *Sqlxmigrate
+	insert   txStmt
+	restore  txStmt
+	remove   txStmt
+	metadata txStmt
+	checksum txStmt
+}
+
+// txStmt is a statement prepared in a transaction, closed with it.
+type txStmt struct {
+	tx   *sql.Tx
+	stmt *sql.Stmt
+}
+
+func (h *tableHistory) Init(ctx context.Context) error {
+	return h.g.createMigrationTableIfNotExists(ctx)
+}
+
+func (h *tableHistory) Applied(ctx context.Context) ([]string, error) {
+	g := h.g
+	if ok, err := g.tableExists(ctx, g.options.TableName); !ok || err != nil {
+		return nil, err
+	}
+
+	// The lock row isn't a migration.
+	query := fmt.Sprintf("SELECT %[1]s FROM %[2]s WHERE %[1]s <> ?", g.options.IDColumnName, g.options.TableName)
+	if g.options.TombstoneRollbacks {
+		query += fmt.Sprintf(" AND %s IS NULL", rolledBackAtColumnName)
+	}
+	query = g.rebind(query)
+	g.logf("appliedIDs %s", query)
+
+	var ids []string
+	if err := g.db.SelectContext(ctx, &ids, query, lockMigrationID); err != nil {
+		err = queryError(err, query)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (h *tableHistory) Record(ctx context.Context, tx *sql.Tx, id string) error {
+	g := h.g
+	g.logf("Migration %s - recording", id)
+
+	// A migration applied again after its rollback reuses the row of its
+	// tombstone.
+	if g.options.TombstoneRollbacks {
+		res, err := h.exec(ctx, tx, &h.restore, g.restoreSQL(), id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+	}
+
+	_, err := h.exec(ctx, tx, &h.insert, g.recordSQL(), id)
+	return err
+}
+
+func (h *tableHistory) Remove(ctx context.Context, tx *sql.Tx, id string) error {
+	g := h.g
+	g.logf("Migration %s rollback - removing", id)
+
+	var err error
+	if g.options.TombstoneRollbacks {
+		_, err = h.exec(ctx, tx, &h.remove, g.tombstoneSQL(), time.Now().UTC(), id)
+	} else {
+		_, err = h.exec(ctx, tx, &h.remove, g.removeSQL(), id)
+	}
+	return err
+}
+
+// recordSQL returns the statement inserting the row of an applied migration,
+// with the bindvars of the driver.
+func (g *Sqlxmigrate) recordSQL() string {
+	return g.rebind(fmt.Sprintf("INSERT INTO %s (%s) VALUES (?)", g.options.TableName, g.options.IDColumnName))
+}
+
+// removeSQL returns the statement deleting the row of a rolled back
+// migration, with the bindvars of the driver. It runs in the transaction of
+// the rollback, which doesn't rebind the queries like sqlx does.
+func (g *Sqlxmigrate) removeSQL() string {
+	return g.rebind(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", g.options.TableName, g.options.IDColumnName))
+}
+
+// tombstoneSQL returns the statement marking the row of a migration as
+// rolled back when Options.TombstoneRollbacks is set.
+func (g *Sqlxmigrate) tombstoneSQL() string {
+	return g.rebind(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ? AND %[2]s IS NULL", g.options.TableName, rolledBackAtColumnName, g.options.IDColumnName))
+}
+
+// restoreSQL returns the statement clearing the tombstone of a migration
+// applied again.
+func (g *Sqlxmigrate) restoreSQL() string {
+	return g.rebind(fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = ? AND %[2]s IS NOT NULL", g.options.TableName, rolledBackAtColumnName, g.options.IDColumnName))
+}
+
+// exec executes a statement recording a migration in the transaction of the
+// run, preparing it on first use in the transaction. The query must already
+// use the bindvars of the driver. Behind a transaction pooler, which doesn't
+// keep the statements prepared by a client, the statement isn't prepared.
+func (h *tableHistory) exec(ctx context.Context, tx *sql.Tx, stmt *txStmt, query string, args ...interface{}) (sql.Result, error) {
+	g := h.g
+
+	pooled, err := g.BehindPooler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pooled {
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			err = queryError(err, query)
+			return nil, err
+		}
+		return res, nil
+	}
+
+	if stmt.tx != tx {
+		g.logf("Migration table - prepare %s", query)
+
+		prepared, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			err = queryError(err, query)
+			return nil, err
+		}
+		*stmt = txStmt{tx: tx, stmt: prepared}
+	}
+
+	res, err := stmt.stmt.ExecContext(ctx, args...)
+	if err != nil {
+		err = queryError(err, query)
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,163 @@
+package sqlxmigrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryHistory is a HistoryStore keeping the applied migrations in memory.
+type memoryHistory struct {
+	ids []string
+}
+
+func (h *memoryHistory) Init(ctx context.Context) error {
+	return nil
+}
+
+func (h *memoryHistory) Applied(ctx context.Context) ([]string, error) {
+	return append([]string(nil), h.ids...), nil
+}
+
+func (h *memoryHistory) Record(ctx context.Context, tx *sql.Tx, id string) error {
+	h.ids = append(h.ids, id)
+	return nil
+}
+
+func (h *memoryHistory) Remove(ctx context.Context, tx *sql.Tx, id string) error {
+	for i, applied := range h.ids {
+		if applied == id {
+			h.ids = append(h.ids[:i], h.ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func TestHistoryStore(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		history := &memoryHistory{}
+		m := New(db, DefaultOptions, migrations)
+		m.SetHistoryStore(history)
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, []string{"201608301400", "201608301430"}, history.ids)
+		assert.True(t, m.hasTable("pets"))
+		assert.False(t, m.hasTable("migrations"))
+
+		pending, err := m.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, []string{"201608301400"}, history.ids)
+		assert.False(t, m.hasTable("pets"))
+
+		history.ids = append(history.ids, "201501011200")
+		res, err := m.Repair(RepairOptions{RemoveUnknown: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201501011200"}, res.RemovedIDs)
+		assert.Equal(t, []string{"201608301400"}, history.ids)
+
+		// Only the migration table stores the checksums.
+		_, err = m.Repair(RepairOptions{RemoveUnknown: true, UpdateChecksums: true})
+		assert.Equal(t, ErrUnsupportedDriver, err)
+	})
+}
+
+func TestHistoryStatementsPrepared(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		var buf bytes.Buffer
+		m.SetLogger(log.New(&buf, "", 0))
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+		// The insert and the update of the metadata are prepared once.
+		assert.Equal(t, 2, strings.Count(buf.String(), "Migration table - prepare"))
+
+		buf.Reset()
+		require.NoError(t, m.Reset())
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+		assert.Equal(t, 1, strings.Count(buf.String(), "Migration table - prepare"))
+	})
+}
+
+func TestHistoryStatementsBindvars(t *testing.T) {
+	options := *DefaultOptions
+	options.TableName = "schema_migrations"
+	options.IDColumnName = "version"
+
+	for driver, want := range map[string][2]string{
+		"postgres":  {"INSERT INTO schema_migrations (version) VALUES ($1)", "DELETE FROM schema_migrations WHERE version = $1"},
+		"pgx":       {"INSERT INTO schema_migrations (version) VALUES ($1)", "DELETE FROM schema_migrations WHERE version = $1"},
+		"mysql":     {"INSERT INTO schema_migrations (version) VALUES (?)", "DELETE FROM schema_migrations WHERE version = ?"},
+		"sqlite3":   {"INSERT INTO schema_migrations (version) VALUES (?)", "DELETE FROM schema_migrations WHERE version = ?"},
+		"sqlserver": {"INSERT INTO schema_migrations (version) VALUES (@p1)", "DELETE FROM schema_migrations WHERE version = @p1"},
+		"mssql":     {"INSERT INTO schema_migrations (version) VALUES (@p1)", "DELETE FROM schema_migrations WHERE version = @p1"},
+		"oci8":      {"INSERT INTO schema_migrations (version) VALUES (:arg1)", "DELETE FROM schema_migrations WHERE version = :arg1"},
+		"custom":    {"INSERT INTO schema_migrations (version) VALUES (?)", "DELETE FROM schema_migrations WHERE version = ?"},
+	} {
+		m := New(sqlx.NewDb(nil, driver), &options, migrations)
+		assert.Equal(t, want[0], m.recordSQL(), driver)
+		assert.Equal(t, want[1], m.removeSQL(), driver)
+	}
+}
+
+func TestRollbackRemovesRow(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.IDColumnName = "version"
+		m := New(db, &options, extendedMigrations)
+		require.NoError(t, m.Migrate())
+
+		require.NoError(t, m.RollbackMigration(extendedMigrations[1]))
+		ids, err := m.appliedIDs(context.Background())
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"201608301400", "201807221927"}, ids)
+	})
+}
+
+func TestTombstoneRollbacks(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.TombstoneRollbacks = true
+		m := New(db, &options, migrations)
+		require.NoError(t, m.Migrate())
+
+		require.NoError(t, m.RollbackLast())
+		assert.False(t, m.hasTable("pets"))
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+
+		var rolledBack int
+		require.NoError(t, db.QueryRow(db.Rebind("SELECT count(*) FROM migrations WHERE id = ? AND rolled_back_at IS NOT NULL"), "201608301430").Scan(&rolledBack))
+		assert.Equal(t, 1, rolledBack)
+
+		ids, err := m.appliedIDs(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400"}, ids)
+		pending, err := m.PendingCount()
+		require.NoError(t, err)
+		assert.Equal(t, 1, pending)
+
+		// The migration applied again reuses the row of its tombstone.
+		require.NoError(t, m.Migrate())
+		assert.True(t, m.hasTable("pets"))
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+		require.NoError(t, db.QueryRow("SELECT count(*) FROM migrations WHERE rolled_back_at IS NOT NULL").Scan(&rolledBack))
+		assert.Equal(t, 0, rolledBack)
+
+		require.NoError(t, m.Reset())
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+		ids, err = m.appliedIDs(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+}
@@ -0,0 +1,49 @@
+package sqlxmigrate
+
+import (
+	"time"
+)
+
+// OnBeforeEach registers a function called before each migration is
+// applied. An error fails the run without executing the migration, ie. to
+// enforce a policy.
+func (g *Sqlxmigrate) OnBeforeEach(fn func(*Migration) error) {
+	g.beforeEach = append(g.beforeEach, fn)
+}
+
+// OnAfterEach registers a function called once each migration was executed,
+// with how long it took and the error it failed with, ie. to emit metrics or
+// notify a channel.
+func (g *Sqlxmigrate) OnAfterEach(fn func(*Migration, time.Duration, error)) {
+	g.afterEach = append(g.afterEach, fn)
+}
+
+// OnBeforeRollback registers a function called before each migration is
+// rolled back. An error fails the rollback without executing it.
+func (g *Sqlxmigrate) OnBeforeRollback(fn func(*Migration) error) {
+	g.beforeRollback = append(g.beforeRollback, fn)
+}
+
+// OnAfterRollback registers a function called once each migration was
+// rolled back, with how long it took and the error it failed with.
+func (g *Sqlxmigrate) OnAfterRollback(fn func(*Migration, time.Duration, error)) {
+	g.afterRollback = append(g.afterRollback, fn)
+}
+
+// runBeforeHooks calls the before hooks until one fails.
+func (g *Sqlxmigrate) runBeforeHooks(hooks []func(*Migration) error, m *Migration) error {
+	for _, fn := range hooks {
+		if err := fn(m); err != nil {
+			g.logf("Migration %s - hook failed - %v", m.ID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterHooks calls the after hooks.
+func runAfterHooks(hooks []func(*Migration, time.Duration, error), m *Migration, d time.Duration, err error) {
+	for _, fn := range hooks {
+		fn(m, d, err)
+	}
+}
@@ -0,0 +1,76 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+
+		var calls []string
+		m.OnBeforeEach(func(m *Migration) error {
+			calls = append(calls, "before "+m.ID)
+			return nil
+		})
+		m.OnAfterEach(func(m *Migration, d time.Duration, err error) {
+			assert.True(t, d > 0)
+			calls = append(calls, fmt.Sprintf("after %s %v", m.ID, err))
+		})
+		m.OnBeforeRollback(func(m *Migration) error {
+			calls = append(calls, "before rollback "+m.ID)
+			return nil
+		})
+		m.OnAfterRollback(func(m *Migration, d time.Duration, err error) {
+			calls = append(calls, fmt.Sprintf("after rollback %s %v", m.ID, err))
+		})
+
+		require.NoError(t, m.Migrate())
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, []string{
+			"before 201608301400",
+			"after 201608301400 <nil>",
+			"before 201608301430",
+			"after 201608301430 <nil>",
+			"before rollback 201608301430",
+			"after rollback 201608301430 <nil>",
+		}, calls)
+
+		require.NoError(t, m.RollbackLast())
+	})
+}
+
+func TestBeforeEachFailsRun(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+
+		errFrozen := errors.New("schema changes are frozen")
+		m.OnBeforeEach(func(m *Migration) error {
+			if m.ID == "201608301430" {
+				return errFrozen
+			}
+			return nil
+		})
+		var after []string
+		m.OnAfterEach(func(m *Migration, d time.Duration, err error) {
+			after = append(after, m.ID)
+		})
+
+		assert.Equal(t, errFrozen, m.Migrate())
+		assert.Equal(t, []string{"201608301400"}, after)
+
+		// The migrations of the failed run are rolled back.
+		if m.transactionalDDL() {
+			ok, err := m.HasTable("people")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// The unused indexes queries return the table and index name of the indexes
+// never scanned since the statistics were reset or the server started.
+const (
+	postgresUnusedIndexesQuery = `SELECT relname, indexrelname FROM pg_stat_user_indexes
+		WHERE schemaname = current_schema() AND idx_scan = 0
+		ORDER BY relname, indexrelname`
+	mysqlUnusedIndexesQuery = `SELECT object_name, index_name FROM sys.schema_unused_indexes
+		WHERE object_schema = DATABASE()
+		ORDER BY object_name, index_name`
+)
+
+// IndexReport returns the duplicate and unused indexes of the current schema,
+// or database for MySQL, as warnings:
+//
+//   - duplicate_index for an index whose columns are the leading columns of
+//     another index of the table, ie. an index on (person_id) once an index
+//     on (person_id, created_at) was added
+//   - unused_index for an index never scanned according to
+//     pg_stat_user_indexes on PostgreSQL and the sys schema on MySQL, whose
+//     statistics start when they were reset or the server started
+//
+// The primary keys and the unique indexes enforce constraints, so they are
+// not reported as unused nor as covered by another index. Only PostgreSQL,
+// MySQL and SQLite are supported, the unused indexes being reported on
+// PostgreSQL and MySQL only.
+func (g *Sqlxmigrate) IndexReport() ([]Warning, error) {
+	return g.indexReport(context.Background())
+}
+
+// reportIndexes adds the warnings of IndexReport to the result of the run
+// when Options.ReportIndexes is set.
+func (g *Sqlxmigrate) reportIndexes(ctx context.Context, res *Result) error {
+	if !g.options.ReportIndexes {
+		return nil
+	}
+	warnings, err := g.indexReport(ctx)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		g.warn(res, w)
+	}
+	return nil
+}
+
+func (g *Sqlxmigrate) indexReport(ctx context.Context) ([]Warning, error) {
+	schema, err := g.inspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := duplicateIndexes(schema.Indexes)
+
+	var query string
+	switch {
+	case g.isPostgres():
+		query = postgresUnusedIndexesQuery
+	case g.isMySQL():
+		query = mysqlUnusedIndexesQuery
+	default:
+		return warnings, nil
+	}
+
+	// The unused indexes are the ones of the inspected tables that don't
+	// enforce a constraint.
+	constrained := make(map[[2]string]bool)
+	for _, idx := range schema.Indexes {
+		constrained[[2]string{idx.Table, idx.Name}] = idx.Primary || idx.Unique
+	}
+
+	err = g.inspectRows(ctx, query, func(rows *sql.Rows) error {
+		var table, name string
+		if err := rows.Scan(&table, &name); err != nil {
+			return err
+		}
+		if isConstrained, ok := constrained[[2]string{table, name}]; !ok || isConstrained {
+			return nil
+		}
+		warnings = append(warnings, Warning{
+			Code:    WarningUnusedIndex,
+			Message: fmt.Sprintf("index %s on %s was never scanned", name, table),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// duplicateIndexes returns a duplicate_index warning for each index that
+// another index of its table covers: the other index has the same leading
+// columns, and enforces the same uniqueness or a stronger one. Of two
+// identical indexes, the last one by name is reported.
+func duplicateIndexes(indexes []Index) []Warning {
+	sorted := make([]Index, len(indexes))
+	copy(sorted, indexes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Table != sorted[j].Table {
+			return sorted[i].Table < sorted[j].Table
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var warnings []Warning
+	for i, idx := range sorted {
+		if idx.Primary {
+			continue
+		}
+		for j, other := range sorted {
+			if i == j || other.Table != idx.Table || !hasPrefix(other.Columns, idx.Columns) {
+				continue
+			}
+			same := len(other.Columns) == len(idx.Columns)
+			switch {
+			case idx.Unique && !same:
+				// A unique index is not covered by a longer index.
+				continue
+			case idx.Unique && !other.Unique && !other.Primary:
+				continue
+			case same && idx.Unique == (other.Unique || other.Primary) && !other.Primary && j > i:
+				// Of two identical indexes, the first one is kept.
+				continue
+			}
+
+			message := fmt.Sprintf("index %s on %s is covered by %s", idx.Name, idx.Table, other.Name)
+			if same {
+				message = fmt.Sprintf("index %s on %s duplicates %s", idx.Name, idx.Table, other.Name)
+			}
+			warnings = append(warnings, Warning{Code: WarningDuplicateIndex, Message: message})
+			break
+		}
+	}
+	return warnings
+}
+
+// hasPrefix returns whether the columns start with the prefix columns.
+func hasPrefix(columns, prefix []string) bool {
+	if len(prefix) > len(columns) {
+		return false
+	}
+	return equalColumns(columns[:len(prefix)], prefix)
+}
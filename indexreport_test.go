@@ -0,0 +1,73 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportIndexes(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.ReportIndexes = true
+
+		m := New(db, &options, append(migrations[:2:2], &Migration{
+			ID:         "201608301500",
+			MigrateSQL: "CREATE INDEX idx_pets_person_id ON pets (person_id);\nCREATE INDEX idx_pets_person_id_created_at ON pets (person_id, created_at)",
+			// The indexes are dropped with the table.
+			RollbackTx: func(tx *Tx) error {
+				return nil
+			},
+		}))
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+
+		covered := Warning{
+			Code:    WarningDuplicateIndex,
+			Message: "index idx_pets_person_id on pets is covered by idx_pets_person_id_created_at",
+		}
+		assert.Contains(t, res.Warnings, covered)
+
+		warnings, err := m.IndexReport()
+		require.NoError(t, err)
+		// The new indexes are never scanned, so they are reported as unused
+		// where the statistics are kept.
+		var duplicates []Warning
+		for _, w := range warnings {
+			if w.Code == WarningDuplicateIndex {
+				duplicates = append(duplicates, w)
+			}
+		}
+		assert.Equal(t, []Warning{covered}, duplicates)
+
+		require.NoError(t, m.RollbackLast())
+		require.NoError(t, m.RollbackLast())
+		require.NoError(t, m.RollbackLast())
+	}, "sqlite3", "mysql", "postgres")
+}
+
+func TestDuplicateIndexes(t *testing.T) {
+	indexes := []Index{
+		{Table: "people", Name: "people_pkey", Columns: []string{"id"}, Unique: true, Primary: true},
+		{Table: "people", Name: "idx_people_id", Columns: []string{"id"}},
+		{Table: "people", Name: "uq_people_email", Columns: []string{"email"}, Unique: true},
+		{Table: "people", Name: "idx_people_email_name", Columns: []string{"email", "name"}},
+		{Table: "people", Name: "idx_people_name", Columns: []string{"name"}},
+		{Table: "people", Name: "idx_people_name_2", Columns: []string{"name"}},
+		{Table: "pets", Name: "idx_pets_name", Columns: []string{"name"}},
+		{Table: "pets", Name: "uq_pets_name", Columns: []string{"name"}, Unique: true},
+	}
+
+	var messages []string
+	for _, w := range duplicateIndexes(indexes) {
+		assert.Equal(t, WarningDuplicateIndex, w.Code)
+		messages = append(messages, w.Message)
+	}
+	assert.Equal(t, []string{
+		"index idx_people_id on people duplicates people_pkey",
+		"index idx_people_name_2 on people duplicates idx_people_name",
+		"index idx_pets_name on pets duplicates uq_pets_name",
+	}, messages)
+}
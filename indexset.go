@@ -0,0 +1,266 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IndexSet declares the indexes of a table. Unlike the migrations, which
+// record the history of the data and the schema, the declared sets are
+// reconciled against the live catalog at the end of every run applying all
+// the migrations: the missing indexes are created, concurrently on
+// PostgreSQL and without locking the writes on MySQL, and the indexes whose
+// columns changed are recreated. Only PostgreSQL, MySQL and SQLite are
+// supported.
+type IndexSet struct {
+	// Table is the name of the table.
+	Table string
+	// Indexes are the indexes of the table.
+	Indexes []DeclaredIndex
+	// DropExtra drops the indexes of the table that are not declared. The
+	// primary key and the indexes of the constraints are always kept.
+	DropExtra bool
+}
+
+// DeclaredIndex is an index of an IndexSet.
+type DeclaredIndex struct {
+	// Name is the name of the index, named by DefaultNamingConvention when
+	// empty.
+	Name string
+	// Columns are the columns of the index, in order.
+	Columns []string
+	// Unique is whether the index is unique.
+	Unique bool
+}
+
+func (d DeclaredIndex) name(table string) string {
+	if d.Name != "" {
+		return d.Name
+	}
+	if d.Unique {
+		return DefaultNamingConvention.UniqueIndexName(table, d.Columns...)
+	}
+	return DefaultNamingConvention.IndexName(table, d.Columns...)
+}
+
+// IndexChange is an index created or dropped by the reconciliation of an
+// IndexSet. A recreated index is reported as dropped then created.
+type IndexChange struct {
+	Table string
+	Name  string
+	// Dropped is whether the index was dropped, created otherwise.
+	Dropped bool
+}
+
+// liveIndex is an index of the catalog.
+type liveIndex struct {
+	Columns []string
+	Unique  bool
+	// Owned is whether the index belongs to the primary key or another
+	// constraint, so it can't be dropped on its own.
+	Owned bool
+	// Valid is false for the index left by a failed CREATE INDEX
+	// CONCURRENTLY on PostgreSQL.
+	Valid bool
+}
+
+// The table indexes queries return the index name, whether it is unique,
+// owned and valid, and a column name, one row per column of the indexes of a
+// table.
+const (
+	postgresTableIndexesQuery = `SELECT i.relname, ix.indisunique,
+			ix.indisprimary OR EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid AND c.contype IN ('p', 'u', 'x')),
+			ix.indisvalid, a.attname
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = current_schema() AND t.relname = ?
+		ORDER BY i.relname, k.ord`
+	mysqlTableIndexesQuery = `SELECT index_name, non_unique = 0, index_name = 'PRIMARY', 1, column_name
+		FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = ?
+		ORDER BY index_name, seq_in_index`
+	sqliteTableIndexesQuery = `SELECT l.name, l."unique", l.origin <> 'c', 1, i.name
+		FROM pragma_index_list(?) l
+		JOIN pragma_index_info(l.name) i
+		ORDER BY l.name, i.seqno`
+)
+
+// DeclareIndexes registers index sets reconciled at the end of the runs
+// applying all the migrations, once they are committed.
+func (g *Sqlxmigrate) DeclareIndexes(sets ...*IndexSet) {
+	g.indexSets = append(g.indexSets, sets...)
+}
+
+// ReconcileIndexes reconciles the declared index sets against the live
+// catalog without running the migrations, returning the indexes it created
+// and dropped.
+func (g *Sqlxmigrate) ReconcileIndexes() ([]IndexChange, error) {
+	ctx := context.Background()
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return g.reconcileIndexes(ctx)
+}
+
+func (g *Sqlxmigrate) reconcileIndexes(ctx context.Context) ([]IndexChange, error) {
+	if len(g.indexSets) == 0 {
+		return nil, nil
+	}
+
+	var query string
+	switch {
+	case g.isPostgres():
+		query = postgresTableIndexesQuery
+	case g.isMySQL():
+		query = mysqlTableIndexesQuery
+	case g.isSQLite():
+		query = sqliteTableIndexesQuery
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+
+	var changes []IndexChange
+	for _, set := range g.indexSets {
+		for _, d := range set.Indexes {
+			if len(d.Columns) == 0 {
+				return changes, fmt.Errorf(`sqlxmigrate: Index "%s" of table "%s" declares no columns`, d.name(set.Table), set.Table)
+			}
+		}
+
+		live, err := g.liveIndexes(ctx, g.rebind(query), set.Table)
+		if err != nil {
+			return changes, err
+		}
+
+		declared := make(map[string]bool, len(set.Indexes))
+		for _, d := range set.Indexes {
+			name := d.name(set.Table)
+			declared[name] = true
+
+			if idx, ok := live[name]; ok {
+				if idx.Valid && idx.Unique == d.Unique && equalColumns(idx.Columns, d.Columns) {
+					continue
+				}
+				g.logf("Index %s - redefined on %s", name, set.Table)
+				if err := g.execIndexStatement(ctx, g.dropIndexSQL(set.Table, name)); err != nil {
+					return changes, err
+				}
+				changes = append(changes, IndexChange{Table: set.Table, Name: name, Dropped: true})
+			}
+
+			g.logf("Index %s - creating on %s", name, set.Table)
+			if err := g.execIndexStatement(ctx, g.createIndexSQL(set.Table, name, d)); err != nil {
+				return changes, err
+			}
+			changes = append(changes, IndexChange{Table: set.Table, Name: name})
+		}
+
+		names := make([]string, 0, len(live))
+		for name := range live {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if declared[name] || live[name].Owned {
+				continue
+			}
+			if !set.DropExtra {
+				g.logf("Index %s - not declared on %s, kept", name, set.Table)
+				continue
+			}
+			g.logf("Index %s - not declared on %s, dropping", name, set.Table)
+			if err := g.execIndexStatement(ctx, g.dropIndexSQL(set.Table, name)); err != nil {
+				return changes, err
+			}
+			changes = append(changes, IndexChange{Table: set.Table, Name: name, Dropped: true})
+		}
+	}
+	return changes, nil
+}
+
+// liveIndexes returns the indexes of a table by name.
+func (g *Sqlxmigrate) liveIndexes(ctx context.Context, query, table string) (map[string]*liveIndex, error) {
+	indexes := make(map[string]*liveIndex)
+	err := g.inspectRows(ctx, query, func(rows *sql.Rows) error {
+		var (
+			name   string
+			idx    liveIndex
+			column sql.NullString
+		)
+		if err := rows.Scan(&name, &idx.Unique, &idx.Owned, &idx.Valid, &column); err != nil {
+			return err
+		}
+		// The columns of the expressions are empty, so the index doesn't
+		// match a declared one.
+		if live, ok := indexes[name]; ok {
+			live.Columns = append(live.Columns, column.String)
+			return nil
+		}
+		idx.Columns = []string{column.String}
+		indexes[name] = &idx
+		return nil
+	}, table)
+	return indexes, err
+}
+
+func (g *Sqlxmigrate) createIndexSQL(table, name string, d DeclaredIndex) string {
+	unique := ""
+	if d.Unique {
+		unique = "UNIQUE "
+	}
+	columns := strings.Join(d.Columns, ", ")
+
+	switch {
+	case g.isPostgres():
+		return fmt.Sprintf("CREATE %sINDEX CONCURRENTLY %s ON %s (%s)", unique, name, table, columns)
+	case g.isMySQL():
+		return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s) ALGORITHM=INPLACE LOCK=NONE", unique, name, table, columns)
+	default:
+		return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, name, table, columns)
+	}
+}
+
+func (g *Sqlxmigrate) dropIndexSQL(table, name string) string {
+	switch {
+	case g.isPostgres():
+		return fmt.Sprintf("DROP INDEX CONCURRENTLY %s", name)
+	case g.isMySQL():
+		return fmt.Sprintf("DROP INDEX %s ON %s ALGORITHM=INPLACE LOCK=NONE", name, table)
+	default:
+		return fmt.Sprintf("DROP INDEX %s", name)
+	}
+}
+
+// execIndexStatement executes a statement outside of a transaction, which
+// CREATE INDEX CONCURRENTLY requires.
+func (g *Sqlxmigrate) execIndexStatement(ctx context.Context, query string) error {
+	g.logf("Index - %s", query)
+	if _, err := g.db.ExecContext(ctx, query); err != nil {
+		err = queryError(err, query)
+		return err
+	}
+	return nil
+}
+
+func equalColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
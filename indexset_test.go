@@ -0,0 +1,109 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeclareIndexes(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		set := &IndexSet{
+			Table: "pets",
+			Indexes: []DeclaredIndex{
+				{Columns: []string{"person_id"}},
+				{Name: "uq_pets_owner", Columns: []string{"person_id", "created_at"}, Unique: true},
+			},
+		}
+		m := New(db, DefaultOptions, migrations)
+		m.DeclareIndexes(set)
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []IndexChange{
+			{Table: "pets", Name: "idx_pets_person_id"},
+			{Table: "pets", Name: "uq_pets_owner"},
+		}, res.Indexes)
+
+		res, err = m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Empty(t, res.Indexes)
+
+		_, err = db.Exec(`CREATE INDEX idx_pets_created_at ON pets (created_at)`)
+		require.NoError(t, err)
+		set.Indexes[1].Columns = []string{"created_at", "person_id"}
+
+		changes, err := m.ReconcileIndexes()
+		require.NoError(t, err)
+		assert.Equal(t, []IndexChange{
+			{Table: "pets", Name: "uq_pets_owner", Dropped: true},
+			{Table: "pets", Name: "uq_pets_owner"},
+		}, changes)
+
+		set.DropExtra = true
+		changes, err = m.ReconcileIndexes()
+		require.NoError(t, err)
+		assert.Equal(t, []IndexChange{
+			{Table: "pets", Name: "idx_pets_created_at", Dropped: true},
+		}, changes)
+
+		schema, err := m.Inspect()
+		require.NoError(t, err)
+		var names []string
+		for _, idx := range schema.Indexes {
+			if idx.Table == "pets" && !idx.Primary {
+				names = append(names, idx.Name)
+				if idx.Name == "uq_pets_owner" {
+					assert.Equal(t, []string{"created_at", "person_id"}, idx.Columns)
+				}
+			}
+		}
+		assert.ElementsMatch(t, []string{"idx_pets_person_id", "uq_pets_owner"}, names)
+
+		require.NoError(t, m.RollbackLast())
+		require.NoError(t, m.RollbackLast())
+	}, "sqlite3", "mysql", "postgres")
+}
+
+func TestDeclareIndexesMigrateTo(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		m.DeclareIndexes(&IndexSet{Table: "pets", Indexes: []DeclaredIndex{{Columns: []string{"person_id"}}}})
+
+		// The declared indexes are only reconciled once all the migrations
+		// are applied.
+		res, err := m.MigrateToWithResult("201608301400")
+		require.NoError(t, err)
+		assert.Empty(t, res.Indexes)
+
+		require.NoError(t, m.RollbackLast())
+	}, "sqlite3", "mysql", "postgres")
+}
+
+func TestDeclareIndexesInvalid(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		m.DeclareIndexes(&IndexSet{Table: "pets", Indexes: []DeclaredIndex{{Name: "idx_pets_empty"}}})
+
+		err := m.Migrate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `Index "idx_pets_empty" of table "pets" declares no columns`)
+
+		require.NoError(t, m.RollbackLast())
+		require.NoError(t, m.RollbackLast())
+	}, "sqlite3")
+}
+
+func TestDeclareIndexesUnsupported(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		m.DeclareIndexes(&IndexSet{Table: "pets", Indexes: []DeclaredIndex{{Columns: []string{"person_id"}}}})
+		assert.True(t, errors.Is(m.Migrate(), ErrUnsupportedDriver))
+
+		require.NoError(t, m.RollbackLast())
+		require.NoError(t, m.RollbackLast())
+	}, "sqlserver")
+}
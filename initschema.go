@@ -0,0 +1,114 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const checksumColumnName = "checksum"
+
+// ChecksumMismatchError is returned when the checksum stored for a migration
+// doesn't match the checksum of its current definition.
+type ChecksumMismatchError struct {
+	ID       string
+	Stored   string
+	Computed string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Checksum mismatch for migration "%s": stored %s, computed %s`, e.ID, e.Stored, e.Computed)
+}
+
+// Hint implements Hinter.
+func (e *ChecksumMismatchError) Hint() string {
+	return "The migration changed after it was applied. Revert the change and ship it as a new migration, applied migrations must not be edited."
+}
+
+// InitSchemaSQL sets a SQL script that is run if no migration is found, as
+// InitSchema does. The script is expected to include the migrations up to the
+// one that matches `throughID`, which are recorded as applied, the following
+// ones are applied by the run. Use an empty `throughID` when the script
+// includes every migration.
+//
+// The checksum of the script is stored when the schema is initialized so
+// Validate can check that databases initialized at different times started
+// from the same baseline.
+func (g *Sqlxmigrate) InitSchemaSQL(script, throughID string) {
+	g.initChecksum = checksum(script)
+	g.initThroughID = throughID
+	g.initSchema = func(db *sqlx.DB) error {
+		for _, stmt := range splitStatements(db.DriverName(), script) {
+			if _, err := db.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// checksum returns the hex encoded SHA-256 of a script.
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// storeInitChecksum records the checksum of the init schema script, as part
+// of the current transaction.
+func (g *Sqlxmigrate) storeInitChecksum(ctx context.Context) error {
+	if g.initChecksum == "" || !g.usesTable() {
+		return nil
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", g.options.TableName, checksumColumnName, g.options.IDColumnName)
+	sql = g.rebind(sql)
+	g.logf("Migration %s - %s", initSchemaMigrationID, sql)
+
+	if _, err := g.tx.ExecContext(ctx, sql, g.initChecksum, initSchemaMigrationID); err != nil {
+		err = queryError(err, sql)
+		return err
+	}
+	return nil
+}
+
+// Check whether the init schema script changed since the schema was
+// initialized, and whether it includes migrations that exist.
+func (g *Sqlxmigrate) checkInitSchemaChecksum(ctx context.Context) error {
+	if g.initThroughID != "" {
+		if err := g.checkIDExist(g.initThroughID); err != nil {
+			return err
+		}
+	}
+	if g.initChecksum == "" || !g.usesTable() {
+		return nil
+	}
+
+	if ok, err := g.tableExists(ctx, g.options.TableName); !ok || err != nil {
+		return err
+	}
+	if ok, err := g.hasColumn(ctx, g.options.TableName, checksumColumnName); !ok || err != nil {
+		return err
+	}
+
+	var stored sql.NullString
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", checksumColumnName, g.options.TableName, g.options.IDColumnName)
+	query = g.rebind(query)
+	g.logf("checkInitSchemaChecksum %s", query)
+
+	err := g.db.QueryRowContext(ctx, query, initSchemaMigrationID).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		err = queryError(err, query)
+		return err
+	}
+
+	if stored.Valid && stored.String != g.initChecksum {
+		return &ChecksumMismatchError{ID: initSchemaMigrationID, Stored: stored.String, Computed: g.initChecksum}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const initSchemaScript = `
+	CREATE TABLE "people" ("id" serial,"created_at" timestamp with time zone,"updated_at" timestamp with time zone,"deleted_at" timestamp with time zone,"name" text , PRIMARY KEY ("id"));
+	CREATE TABLE "pets" ("id" serial,"created_at" timestamp with time zone,"updated_at" timestamp with time zone,"deleted_at" timestamp with time zone,"name" text,"person_id" integer , PRIMARY KEY ("id"));
+`
+
+func TestInitSchemaSQL(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		m.InitSchemaSQL(initSchemaScript, "201608301430")
+
+		// The script includes the first two migrations, the last one is
+		// applied by the run.
+		require.NoError(t, m.Migrate())
+		assert.True(t, m.hasTable("pets"))
+		assert.True(t, m.hasTable("books"))
+		assert.Equal(t, 4, tableCount(t, db, "migrations"))
+		assert.NoError(t, m.Validate())
+
+		var stored string
+		require.NoError(t, db.Get(&stored, "SELECT checksum FROM migrations WHERE id = 'SCHEMA_INIT'"))
+		assert.Equal(t, checksum(initSchemaScript), stored)
+
+		// A database initialized from another baseline is detected.
+		m = New(db, DefaultOptions, extendedMigrations)
+		m.InitSchemaSQL(initSchemaScript+"CREATE TABLE cars (id int);", "201608301430")
+		err := m.Validate()
+		require.IsType(t, &ChecksumMismatchError{}, err)
+		assert.Equal(t, "SCHEMA_INIT", err.(*ChecksumMismatchError).ID)
+
+		// The script must include migrations that exist.
+		m = New(db, DefaultOptions, migrations)
+		m.InitSchemaSQL(initSchemaScript, "201807221927")
+		assert.Equal(t, ErrMigrationIDDoesNotExist, m.Validate())
+	})
+}
@@ -0,0 +1,282 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Schema describes the tables of the current schema or database.
+type Schema struct {
+	Tables      []Table
+	Columns     []Column
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+}
+
+// Table describes a table.
+type Table struct {
+	Name string
+}
+
+// Column describes a column of a table.
+type Column struct {
+	Table    string
+	Name     string
+	DataType string
+	Nullable bool
+	// Default is the default expression of the column, nil when it has none.
+	Default  *string
+	Position int
+}
+
+// Index describes an index of a table, the columns being in index order.
+type Index struct {
+	Table   string
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
+}
+
+// ForeignKey describes a foreign key of a table, Columns referencing
+// RefColumns in the same order.
+type ForeignKey struct {
+	Table      string
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// inspectQueries are the catalog queries of a database, each one returning
+// its rows ordered by table.
+type inspectQueries struct {
+	// tables returns the table name.
+	tables string
+	// columns returns the table name, column name, data type, whether the
+	// column is nullable ("YES" or "NO"), its default and position.
+	columns string
+	// indexes returns the table name, index name, whether the index is
+	// unique and primary, and a column name, one row per column.
+	indexes string
+	// foreignKeys returns the table name, constraint name, a column name,
+	// the referenced table and column name, one row per column.
+	foreignKeys string
+}
+
+var postgresInspectQueries = inspectQueries{
+	tables: `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`,
+	columns: `SELECT table_name, column_name, data_type, is_nullable, column_default, ordinal_position
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		ORDER BY table_name, ordinal_position`,
+	indexes: `SELECT t.relname, i.relname, ix.indisunique, ix.indisprimary, a.attname
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = current_schema()
+		ORDER BY t.relname, i.relname, k.ord`,
+	foreignKeys: `SELECT t.relname, c.conname, a.attname, rt.relname, ra.attname
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class rt ON rt.oid = c.confrelid
+		JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refattnum
+		WHERE c.contype = 'f' AND n.nspname = current_schema()
+		ORDER BY t.relname, c.conname, k.ord`,
+}
+
+var mysqlInspectQueries = inspectQueries{
+	tables: `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`,
+	columns: `SELECT table_name, column_name, data_type, is_nullable, column_default, ordinal_position
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE()
+		ORDER BY table_name, ordinal_position`,
+	indexes: `SELECT table_name, index_name, non_unique = 0, index_name = 'PRIMARY', column_name
+		FROM information_schema.statistics
+		WHERE table_schema = DATABASE()
+		ORDER BY table_name, index_name, seq_in_index`,
+	foreignKeys: `SELECT table_name, constraint_name, column_name, referenced_table_name, referenced_column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL
+		ORDER BY table_name, constraint_name, ordinal_position`,
+}
+
+// sqliteColumnFields are the fields of a column read from pragma_table_info,
+// the primary key columns being implicitly not null.
+const sqliteColumnFields = `p.name, p.type, CASE WHEN p."notnull" = 0 AND p.pk = 0 THEN 'YES' ELSE 'NO' END, p.dflt_value, p.cid + 1`
+
+var sqliteInspectQueries = inspectQueries{
+	tables: `SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name`,
+	columns: `SELECT m.name, ` + sqliteColumnFields + `
+		FROM sqlite_master m
+		JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table'
+		ORDER BY m.name, p.cid`,
+	indexes: `SELECT m.name, l.name, l."unique", l.origin = 'pk', i.name
+		FROM sqlite_master m
+		JOIN pragma_index_list(m.name) l
+		JOIN pragma_index_info(l.name) i
+		WHERE m.type = 'table'
+		ORDER BY m.name, l.name, i.seqno`,
+	foreignKeys: `SELECT m.name, m.name || '_fkey' || f.id, f."from", f."table",
+			coalesce(f."to", (SELECT name FROM pragma_table_info(f."table") WHERE pk = f.seq + 1))
+		FROM sqlite_master m
+		JOIN pragma_foreign_key_list(m.name) f
+		WHERE m.type = 'table'
+		ORDER BY m.name, f.id, f.seq`,
+}
+
+// Inspect returns the tables of the current schema, or database for MySQL,
+// with their columns, indexes and foreign keys. The migration and audit
+// tables are left out. Only PostgreSQL, MySQL and SQLite are supported.
+func (g *Sqlxmigrate) Inspect() (*Schema, error) {
+	return g.inspect(context.Background())
+}
+
+func (g *Sqlxmigrate) inspect(ctx context.Context) (*Schema, error) {
+	var queries inspectQueries
+	switch {
+	case g.isPostgres():
+		queries = postgresInspectQueries
+	case g.isMySQL():
+		queries = mysqlInspectQueries
+	case g.isSQLite():
+		queries = sqliteInspectQueries
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+
+	schema := &Schema{}
+	tables := make(map[string]bool)
+
+	err := g.inspectRows(ctx, queries.tables, func(rows *sql.Rows) error {
+		var t Table
+		if err := rows.Scan(&t.Name); err != nil {
+			return err
+		}
+		if t.Name == g.options.TableName || t.Name == g.options.AuditTableName {
+			return nil
+		}
+		tables[t.Name] = true
+		schema.Tables = append(schema.Tables, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The column catalogs list the columns of the views too.
+	err = g.inspectRows(ctx, queries.columns, func(rows *sql.Rows) error {
+		c, err := scanColumn(rows)
+		if err != nil {
+			return err
+		}
+		if !tables[c.Table] {
+			return nil
+		}
+		schema.Columns = append(schema.Columns, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.inspectRows(ctx, queries.indexes, func(rows *sql.Rows) error {
+		var (
+			idx    Index
+			column string
+		)
+		if err := rows.Scan(&idx.Table, &idx.Name, &idx.Unique, &idx.Primary, &column); err != nil {
+			return err
+		}
+		if !tables[idx.Table] {
+			return nil
+		}
+		if n := len(schema.Indexes); n > 0 && schema.Indexes[n-1].Table == idx.Table && schema.Indexes[n-1].Name == idx.Name {
+			schema.Indexes[n-1].Columns = append(schema.Indexes[n-1].Columns, column)
+			return nil
+		}
+		idx.Columns = []string{column}
+		schema.Indexes = append(schema.Indexes, idx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.inspectRows(ctx, queries.foreignKeys, func(rows *sql.Rows) error {
+		var (
+			fk                ForeignKey
+			column, refColumn string
+		)
+		if err := rows.Scan(&fk.Table, &fk.Name, &column, &fk.RefTable, &refColumn); err != nil {
+			return err
+		}
+		if !tables[fk.Table] {
+			return nil
+		}
+		if n := len(schema.ForeignKeys); n > 0 && schema.ForeignKeys[n-1].Table == fk.Table && schema.ForeignKeys[n-1].Name == fk.Name {
+			last := &schema.ForeignKeys[n-1]
+			last.Columns = append(last.Columns, column)
+			last.RefColumns = append(last.RefColumns, refColumn)
+			return nil
+		}
+		fk.Columns = []string{column}
+		fk.RefColumns = []string{refColumn}
+		schema.ForeignKeys = append(schema.ForeignKeys, fk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// scanColumn scans a row of a columns query.
+func scanColumn(rows *sql.Rows) (Column, error) {
+	var (
+		c        Column
+		nullable string
+		def      sql.NullString
+	)
+	if err := rows.Scan(&c.Table, &c.Name, &c.DataType, &nullable, &def, &c.Position); err != nil {
+		return c, err
+	}
+	c.Nullable = nullable == "YES"
+	if def.Valid {
+		c.Default = &def.String
+	}
+	return c, nil
+}
+
+// inspectRows runs a catalog query calling fn for each row.
+func (g *Sqlxmigrate) inspectRows(ctx context.Context, query string, fn func(*sql.Rows) error, args ...interface{}) error {
+	g.logf("Inspect - %s", query)
+
+	rows, err := g.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		err = queryError(err, query)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,80 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, append(migrations, &Migration{
+			ID: "201906121200",
+			Migrate: func(tx *sql.Tx) error {
+				_, err := tx.Exec(`ALTER TABLE pets ADD CONSTRAINT pets_person_fk FOREIGN KEY (person_id) REFERENCES people (id)`)
+				return err
+			},
+		}))
+		require.NoError(t, m.Migrate())
+
+		schema, err := m.Inspect()
+		require.NoError(t, err)
+
+		assert.Equal(t, []Table{{Name: "people"}, {Name: "pets"}}, schema.Tables)
+
+		var petColumns []string
+		for _, c := range schema.Columns {
+			if c.Table == "pets" {
+				petColumns = append(petColumns, c.Name)
+			}
+		}
+		assert.Equal(t, []string{"id", "created_at", "updated_at", "deleted_at", "name", "person_id"}, petColumns)
+
+		assert.Contains(t, schema.Indexes, Index{Table: "people", Name: "people_pkey", Columns: []string{"id"}, Unique: true, Primary: true})
+		assert.Equal(t, []ForeignKey{{
+			Table:      "pets",
+			Name:       "pets_person_fk",
+			Columns:    []string{"person_id"},
+			RefTable:   "people",
+			RefColumns: []string{"id"},
+		}}, schema.ForeignKeys)
+	}, "postgres")
+}
+
+func TestInspectUnsupportedDriver(t *testing.T) {
+	db := sqlx.NewDb(nil, "sqlserver")
+	m := New(db, DefaultOptions, migrations)
+
+	_, err := m.Inspect()
+	assert.Equal(t, ErrUnsupportedDriver, err)
+}
+
+func TestInspectSQLite(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		assert.NoError(t, dropTableIfExists(db, "cars"))
+		m := New(db, DefaultOptions, append(migrations, &Migration{
+			ID:          "201906121200",
+			MigrateSQL:  `CREATE TABLE cars (id INTEGER PRIMARY KEY, plate TEXT NOT NULL UNIQUE, person_id INTEGER REFERENCES people)`,
+			RollbackSQL: `DROP TABLE cars`,
+		}))
+		require.NoError(t, m.Migrate())
+
+		schema, err := m.Inspect()
+		require.NoError(t, err)
+
+		assert.Equal(t, []Table{{Name: "cars"}, {Name: "people"}, {Name: "pets"}}, schema.Tables)
+		assert.Contains(t, schema.Columns, Column{Table: "cars", Name: "plate", DataType: "TEXT", Position: 2})
+		assert.Contains(t, schema.Indexes, Index{Table: "cars", Name: "sqlite_autoindex_cars_1", Columns: []string{"plate"}, Unique: true})
+		assert.Equal(t, []ForeignKey{{
+			Table:      "cars",
+			Name:       "cars_fkey0",
+			Columns:    []string{"person_id"},
+			RefTable:   "people",
+			RefColumns: []string{"id"},
+		}}, schema.ForeignKeys)
+		require.NoError(t, m.RollbackLast())
+	}, "sqlite3")
+}
@@ -0,0 +1,104 @@
+package sqlxmigrate
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// LogEvent is a log message emitted as a line of JSON with Options.JSONLog.
+type LogEvent struct {
+	Time time.Time `json:"time"`
+	// RunID identifies the run, as in the statement tags. Empty before the
+	// run took its lock.
+	RunID       string `json:"run_id,omitempty"`
+	MigrationID string `json:"migration_id,omitempty"`
+	// Phase is the stable part of the message, ie. "checking", "complete"
+	// or "failed" for a migration, "lock" for the lock of the run.
+	Phase string `json:"phase,omitempty"`
+	// Duration is the duration of the step reported by the message, in
+	// seconds.
+	Duration float64 `json:"duration,omitempty"`
+	// SQLHash is the SHA-256 of the statement or query of the message, so
+	// the messages about the same statement can be grouped.
+	SQLHash string `json:"sql_hash,omitempty"`
+	Message string `json:"message"`
+}
+
+// sqlKeywords are the first keywords of the statements and queries logged.
+var sqlKeywords = map[string]bool{
+	"ALTER": true, "ANALYZE": true, "BEGIN": true, "CALL": true, "COMMENT": true,
+	"COPY": true, "CREATE": true, "DELETE": true, "DO": true, "DROP": true,
+	"EXEC": true, "GRANT": true, "INSERT": true, "LOCK": true, "MERGE": true,
+	"OPTIMIZE": true, "PRAGMA": true, "REFRESH": true, "REINDEX": true,
+	"RENAME": true, "REPLACE": true, "REVOKE": true, "SELECT": true, "SET": true,
+	"SHOW": true, "TRUNCATE": true, "UPDATE": true, "VACUUM": true, "WITH": true,
+}
+
+// isSQL reports whether a logged argument is a statement or a query.
+func isSQL(s string) bool {
+	fields := strings.Fields(s)
+	return len(fields) > 1 && sqlKeywords[strings.ToUpper(fields[0])]
+}
+
+// newLogEvent returns the event of a message from its stable format and its
+// arguments: the messages about a migration start with "Migration %s", the
+// phase is the literal text of the format up to its first argument or
+// separator.
+func (g *Sqlxmigrate) newLogEvent(format string, args []interface{}, msg string) *LogEvent {
+	ev := &LogEvent{Time: time.Now().UTC(), RunID: g.runID, Message: msg}
+
+	phase := format
+	if strings.HasPrefix(format, "Migration %s") && len(args) > 0 {
+		ev.MigrationID, _ = args[0].(string)
+		phase = strings.TrimPrefix(strings.TrimPrefix(format, "Migration %s"), " - ")
+	}
+	if i := strings.Index(phase, " -"); i >= 0 {
+		phase = phase[:i]
+	}
+	if i := strings.Index(phase, "%"); i >= 0 {
+		phase = phase[:i]
+	}
+	ev.Phase = strings.TrimSpace(phase)
+
+	// The formats without literal text, ie. "Migration %s - %s", take their
+	// phase from their last argument: the state a migration moves to, the
+	// direction it's recorded with.
+	var lastArg string
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Duration:
+			if ev.Duration == 0 {
+				ev.Duration = v.Seconds()
+			}
+		case string:
+			if ev.SQLHash == "" && isSQL(v) {
+				ev.SQLHash = checksum(v)
+			} else if i > 0 {
+				lastArg = v
+			}
+		case State:
+			lastArg = v.String()
+		case error:
+			lastArg = "error"
+		}
+	}
+	if ev.Phase == "" {
+		if ev.SQLHash != "" {
+			ev.Phase = "sql"
+		} else {
+			ev.Phase = lastArg
+		}
+	}
+	return ev
+}
+
+// jsonLogLine returns the line of JSON of a message.
+func (g *Sqlxmigrate) jsonLogLine(format string, args []interface{}, msg string) string {
+	b, err := json.Marshal(g.newLogEvent(format, args, msg))
+	if err != nil {
+		// The event only holds strings and numbers.
+		return msg
+	}
+	return string(b)
+}
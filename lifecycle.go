@@ -0,0 +1,125 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+)
+
+// State is a step in the lifecycle of a migration during a run.
+type State int
+
+const (
+	// StateNew is the state of a migration before the run plans it.
+	StateNew State = iota
+	// StatePlanned is the state of a migration that did not run yet and
+	// will be applied by the run.
+	StatePlanned
+	// StateLocked is the state of a planned migration once the run holds
+	// the transaction the migrations are applied in.
+	StateLocked
+	// StateRunning is the state of a migration being executed.
+	StateRunning
+	// StateRecording is the state of an executed migration being recorded
+	// in the migration table.
+	StateRecording
+	// StateDone is the state of a migration once the run is committed.
+	StateDone
+	// StateFailed is the state of the migrations of a run that failed.
+	StateFailed
+	// StateDeferred is the state of a migration that did not run yet and is
+	// gated on a disabled flag or a NotBefore time to come.
+	StateDeferred
+)
+
+var stateNames = map[State]string{
+	StateNew:       "new",
+	StatePlanned:   "planned",
+	StateLocked:    "locked",
+	StateRunning:   "running",
+	StateRecording: "recording",
+	StateDone:      "done",
+	StateFailed:    "failed",
+	StateDeferred:  "deferred",
+}
+
+func (s State) String() string {
+	return stateNames[s]
+}
+
+// terminal returns whether no transition is allowed from the state.
+func (s State) terminal() bool {
+	return s == StateDone || s == StateFailed || s == StateDeferred
+}
+
+// transitions lists the states each state can move to. Any state that is
+// not terminal can move to StateFailed. The running and recording migrations
+// move back to StateLocked when the transaction of the run is retried.
+var transitions = map[State][]State{
+	StateNew:       {StatePlanned, StateDeferred},
+	StatePlanned:   {StateLocked},
+	StateLocked:    {StateRunning, StatePlanned},
+	StateRunning:   {StateRecording, StateLocked},
+	StateRecording: {StateDone, StateLocked},
+}
+
+// Transition is a change of state of a migration during a run.
+type Transition struct {
+	MigrationID string
+	From        State
+	To          State
+	// Err is the error that failed the run when To is StateFailed.
+	Err error
+}
+
+// InvalidTransitionError is returned when a migration is moved to a state
+// that can't be reached from its current state.
+type InvalidTransitionError struct {
+	MigrationID string
+	From        State
+	To          State
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migration "%s" can't move from state %s to %s`, e.MigrationID, e.From, e.To)
+}
+
+// OnTransition registers a function called on every change of state of a
+// migration during a run.
+func (g *Sqlxmigrate) OnTransition(fn func(Transition)) {
+	g.onTransition = append(g.onTransition, fn)
+}
+
+// transition moves a migration to a new state and notifies the observers.
+func (g *Sqlxmigrate) transition(m *Migration, to State, err error) error {
+	from := g.states[m.ID]
+
+	allowed := to == StateFailed && !from.terminal()
+	for _, s := range transitions[from] {
+		allowed = allowed || s == to
+	}
+	if !allowed {
+		return &InvalidTransitionError{MigrationID: m.ID, From: from, To: to}
+	}
+
+	g.states[m.ID] = to
+	g.logf("Migration %s - %s -> %s", m.ID, from, to)
+
+	t := Transition{MigrationID: m.ID, From: from, To: to, Err: err}
+	for _, fn := range g.onTransition {
+		fn(t)
+	}
+	return nil
+}
+
+// failMigrations moves the migrations of a run that are not in a terminal
+// state to StateFailed and returns the error that failed the run.
+func (g *Sqlxmigrate) failMigrations(migrations []*Migration, err error) error {
+	for _, m := range migrations {
+		if g.states[m.ID].terminal() {
+			continue
+		}
+		if terr := g.transition(m, StateFailed, err); terr != nil {
+			g.logf("Migration %s - %v", m.ID, terr)
+		}
+	}
+	return err
+}
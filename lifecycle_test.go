@@ -0,0 +1,78 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnTransition(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		var transitions []Transition
+		record := func(tr Transition) {
+			transitions = append(transitions, tr)
+		}
+
+		m := New(db, DefaultOptions, migrations)
+		m.OnTransition(record)
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		assert.Equal(t, []Transition{
+			{MigrationID: "201608301400", From: StateNew, To: StatePlanned},
+			{MigrationID: "201608301400", From: StatePlanned, To: StateLocked},
+			{MigrationID: "201608301400", From: StateLocked, To: StateRunning},
+			{MigrationID: "201608301400", From: StateRunning, To: StateRecording},
+			{MigrationID: "201608301400", From: StateRecording, To: StateDone},
+		}, transitions)
+
+		failure := errors.New("failure")
+		failing := &Migration{
+			ID: "201608301500",
+			Migrate: func(tx *sql.Tx) error {
+				return failure
+			},
+			Rollback: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+
+		transitions = nil
+		m = New(db, DefaultOptions, append(migrations, failing))
+		m.OnTransition(record)
+		assert.True(t, errors.Is(m.Migrate(), failure))
+
+		var states []State
+		for _, tr := range transitions {
+			if tr.To == StateFailed {
+				assert.True(t, errors.Is(tr.Err, failure))
+			}
+			states = append(states, tr.To)
+		}
+		assert.Equal(t, []State{
+			StatePlanned, StatePlanned,
+			StateLocked, StateLocked,
+			StateRunning, StateRecording,
+			StateRunning,
+			StateFailed, StateFailed,
+		}, states)
+
+		// The whole run was rolled back, including the bookkeeping, unless
+		// the schema changes were committed implicitly.
+		if db.DriverName() != "mysql" {
+			assert.Equal(t, 1, tableCount(t, db, "migrations"))
+		}
+	})
+}
+
+func TestInvalidTransition(t *testing.T) {
+	m := New(nil, DefaultOptions, migrations)
+	m.states = map[string]State{}
+
+	err := m.transition(migrations[0], StateRunning, nil)
+	assert.Equal(t, &InvalidTransitionError{MigrationID: "201608301400", From: StateNew, To: StateRunning}, err)
+	assert.EqualError(t, err, `sqlxmigrate: Migration "201608301400" can't move from state new to running`)
+}
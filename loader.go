@@ -0,0 +1,102 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+// LoadMigrations returns the migrations of a directory of SQL files named
+// like 201906121200_create_people.up.sql and
+// 201906121200_create_people.down.sql, ordered by ID. The ID of a migration
+// is the name of its files without the suffix. The up file is the
+// MigrateSQL of the migration, executed in the transaction of the run, the
+// down file is optional and stored as its RollbackSQL. The checksum of a
+// migration is the one of its up file.
+func LoadMigrations(dir string) ([]*Migration, error) {
+	return loadMigrations(os.DirFS(dir), ".", false)
+}
+
+// LoadMigrationsFS returns the migrations of the SQL files of a directory of
+// a file system, ie. an embed.FS, like LoadMigrations.
+func LoadMigrationsFS(fsys fs.FS, dir string) ([]*Migration, error) {
+	return loadMigrations(fsys, dir, false)
+}
+
+// NewFromFS returns a new Sqlxmigrate running the migrations of the SQL files
+// of a directory of a file system, so binaries can embed their migrations.
+// Unlike LoadMigrations, every migration must have a down file.
+func NewFromFS(db *sqlx.DB, options *Options, fsys fs.FS, dir string) (*Sqlxmigrate, error) {
+	migrations, err := loadMigrations(fsys, dir, true)
+	if err != nil {
+		return nil, err
+	}
+	return New(db, options, migrations), nil
+}
+
+func loadMigrations(fsys fs.FS, dir string, requireDown bool) ([]*Migration, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := make(map[string]struct{})
+	var ids []string
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		var id string
+		for _, suffix := range []string{upSuffix, downSuffix} {
+			if strings.HasSuffix(f.Name(), suffix) {
+				id = strings.TrimSuffix(f.Name(), suffix)
+			}
+		}
+		if id == "" {
+			continue
+		}
+		if _, ok := lookup[id]; !ok {
+			lookup[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	migrations := make([]*Migration, 0, len(ids))
+	for _, id := range ids {
+		up, err := fs.ReadFile(fsys, path.Join(dir, id+upSuffix))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf(`sqlxmigrate: Migration "%s" has no %s file`, id, upSuffix)
+		} else if err != nil {
+			return nil, err
+		}
+
+		down, err := fs.ReadFile(fsys, path.Join(dir, id+downSuffix))
+		if errors.Is(err, fs.ErrNotExist) {
+			if requireDown {
+				return nil, fmt.Errorf(`sqlxmigrate: Migration "%s" has no %s file`, id, downSuffix)
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, &Migration{
+			ID:          id,
+			MigrateSQL:  string(up),
+			RollbackSQL: string(down),
+			Checksum:    checksum(string(up)),
+		})
+	}
+	return migrations, nil
+}
@@ -0,0 +1,103 @@
+package sqlxmigrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMigrationFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "sqlxmigrate")
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestLoadMigrations(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		dir := writeMigrationFiles(t, map[string]string{
+			"201608301430_create_pets.up.sql":     `CREATE TABLE pets (id INTEGER PRIMARY KEY, name VARCHAR(255));`,
+			"201608301430_create_pets.down.sql":   `DROP TABLE pets;`,
+			"201608301400_create_people.up.sql":   "CREATE TABLE people (id INTEGER PRIMARY KEY, name VARCHAR(255));\nINSERT INTO people (id, name) VALUES (1, 'Jane');",
+			"201608301400_create_people.down.sql": `DROP TABLE people;`,
+			"README.md":                           `not a migration`,
+		})
+		defer os.RemoveAll(dir)
+
+		loaded, err := LoadMigrations(dir)
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		assert.Equal(t, "201608301400_create_people", loaded[0].ID)
+		assert.Equal(t, "201608301430_create_pets", loaded[1].ID)
+		assert.Equal(t, `DROP TABLE pets;`, loaded[1].RollbackSQL)
+		assert.Equal(t, checksum(`CREATE TABLE pets (id INTEGER PRIMARY KEY, name VARCHAR(255));`), loaded[1].Checksum)
+
+		m := New(db, DefaultOptions, loaded)
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Migrations[0].Stats.Statements)
+		assert.Equal(t, 1, tableCount(t, db, "people"))
+
+		require.NoError(t, m.RollbackLast())
+		assert.False(t, m.hasTable("pets"))
+	})
+}
+
+func TestLoadMigrationsMissingUp(t *testing.T) {
+	dir := writeMigrationFiles(t, map[string]string{
+		"201608301400_create_people.down.sql": `DROP TABLE people;`,
+	})
+	defer os.RemoveAll(dir)
+
+	_, err := LoadMigrations(dir)
+	assert.EqualError(t, err, `sqlxmigrate: Migration "201608301400_create_people" has no .up.sql file`)
+}
+
+func TestNewFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/201608301430_create_pets.up.sql":     {Data: []byte(`CREATE TABLE pets (id INTEGER PRIMARY KEY, name VARCHAR(255));`)},
+		"migrations/201608301430_create_pets.down.sql":   {Data: []byte(`DROP TABLE pets;`)},
+		"migrations/201608301400_create_people.up.sql":   {Data: []byte(`CREATE TABLE people (id INTEGER PRIMARY KEY, name VARCHAR(255));`)},
+		"migrations/201608301400_create_people.down.sql": {Data: []byte(`DROP TABLE people;`)},
+		"migrations/README.md":                           {Data: []byte(`not a migration`)},
+	}
+
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m, err := NewFromFS(db, DefaultOptions, fsys, "migrations")
+		require.NoError(t, err)
+		require.Len(t, m.migrations, 2)
+		assert.Equal(t, "201608301400_create_people", m.migrations[0].ID)
+		assert.Equal(t, "201608301430_create_pets", m.migrations[1].ID)
+
+		require.NoError(t, m.Migrate())
+		assert.True(t, m.hasTable("people"))
+		assert.True(t, m.hasTable("pets"))
+
+		require.NoError(t, m.RollbackLast())
+		assert.False(t, m.hasTable("pets"))
+	})
+}
+
+func TestNewFromFSMissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"201608301400_create_people.up.sql":   {Data: []byte(`CREATE TABLE people (id INTEGER PRIMARY KEY);`)},
+		"201608301400_create_people.down.sql": {Data: []byte(`DROP TABLE people;`)},
+		"201608301430_create_pets.up.sql":     {Data: []byte(`CREATE TABLE pets (id INTEGER PRIMARY KEY);`)},
+	}
+
+	_, err := NewFromFS(nil, DefaultOptions, fsys, ".")
+	assert.EqualError(t, err, `sqlxmigrate: Migration "201608301430_create_pets" has no .down.sql file`)
+
+	loaded, err := LoadMigrationsFS(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.Empty(t, loaded[1].RollbackSQL)
+}
@@ -0,0 +1,207 @@
+package sqlxmigrate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LockLevel is the strongest lock a statement takes on existing tables.
+// Levels of the same database are ordered from the weakest to the strongest.
+type LockLevel int
+
+const (
+	// LockNone is used for statements that don't lock existing tables.
+	LockNone LockLevel = iota
+	// LockUnknown is used for statements that couldn't be analyzed.
+	LockUnknown
+
+	// Postgres table level locks.
+	LockAccessShare
+	LockRowShare
+	LockRowExclusive
+	LockShareUpdateExclusive
+	LockShare
+	LockShareRowExclusive
+	LockExclusive
+	LockAccessExclusive
+
+	// MySQL metadata and row locks.
+	LockSharedMetadata
+	LockRowLevel
+	LockExclusiveMetadata
+)
+
+var lockLevelNames = map[LockLevel]string{
+	LockNone:                 "NONE",
+	LockUnknown:              "UNKNOWN",
+	LockAccessShare:          "ACCESS SHARE",
+	LockRowShare:             "ROW SHARE",
+	LockRowExclusive:         "ROW EXCLUSIVE",
+	LockShareUpdateExclusive: "SHARE UPDATE EXCLUSIVE",
+	LockShare:                "SHARE",
+	LockShareRowExclusive:    "SHARE ROW EXCLUSIVE",
+	LockExclusive:            "EXCLUSIVE",
+	LockAccessExclusive:      "ACCESS EXCLUSIVE",
+	LockSharedMetadata:       "SHARED METADATA LOCK",
+	LockRowLevel:             "ROW LOCKS",
+	LockExclusiveMetadata:    "EXCLUSIVE METADATA LOCK",
+}
+
+func (l LockLevel) String() string {
+	return lockLevelNames[l]
+}
+
+// StatementLock is the strongest lock taken by a statement.
+type StatementLock struct {
+	Statement string
+	Lock      LockLevel
+}
+
+// AnalyzeLocks splits a SQL script into statements and returns the strongest
+// lock each statement takes on existing tables for the given database driver,
+// so reviewers can see the locking impact of a migration before it runs.
+func AnalyzeLocks(driverName, script string) []StatementLock {
+	var locks []StatementLock
+	for _, stmt := range splitStatements(driverName, script) {
+		lock := postgresLock(normalizeStatement(stmt))
+		if strings.Contains(driverName, "mysql") {
+			lock = mysqlLock(normalizeStatement(stmt))
+		}
+		locks = append(locks, StatementLock{Statement: stmt, Lock: lock})
+	}
+	return locks
+}
+
+var (
+	commentsRe   = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/`)
+	whitespaceRe = regexp.MustCompile(`\s+`)
+)
+
+// normalizeStatement removes the comments of a statement and returns it upper
+// cased with single spaces.
+func normalizeStatement(stmt string) string {
+	return strings.ToUpper(compactStatement(stmt))
+}
+
+// compactStatement removes the comments of a statement and returns it with
+// single spaces.
+func compactStatement(stmt string) string {
+	stmt = commentsRe.ReplaceAllString(stmt, " ")
+	stmt = whitespaceRe.ReplaceAllString(stmt, " ")
+	return strings.TrimSpace(stmt)
+}
+
+// lockRule maps statements starting with a prefix, and containing all the
+// given keywords, to a lock level. Rules are evaluated in order.
+type lockRule struct {
+	prefix   string
+	contains []string
+	lock     LockLevel
+}
+
+var postgresLockRules = []lockRule{
+	{"CREATE INDEX", []string{" CONCURRENTLY "}, LockShareUpdateExclusive},
+	{"CREATE UNIQUE INDEX", []string{" CONCURRENTLY "}, LockShareUpdateExclusive},
+	{"CREATE INDEX", nil, LockShare},
+	{"CREATE UNIQUE INDEX", nil, LockShare},
+	{"DROP INDEX", []string{" CONCURRENTLY "}, LockShareUpdateExclusive},
+	{"DROP INDEX", nil, LockAccessExclusive},
+	{"REINDEX", []string{" CONCURRENTLY "}, LockShareUpdateExclusive},
+	{"REINDEX", nil, LockAccessExclusive},
+	{"ALTER TABLE", []string{" VALIDATE CONSTRAINT "}, LockShareUpdateExclusive},
+	{"ALTER TABLE", []string{" FOREIGN KEY "}, LockShareRowExclusive},
+	{"ALTER TABLE", []string{" REFERENCES "}, LockAccessExclusive},
+	{"ALTER TABLE", []string{" SET STATISTICS "}, LockShareUpdateExclusive},
+	{"ALTER TABLE", nil, LockAccessExclusive},
+	{"ALTER INDEX", nil, LockAccessExclusive},
+	{"CREATE TABLE", []string{" REFERENCES "}, LockShareRowExclusive},
+	{"CREATE TABLE", nil, LockNone},
+	{"CREATE TRIGGER", nil, LockShareRowExclusive},
+	{"CREATE OR REPLACE TRIGGER", nil, LockShareRowExclusive},
+	{"DROP TRIGGER", nil, LockAccessExclusive},
+	{"DROP TABLE", nil, LockAccessExclusive},
+	{"TRUNCATE", nil, LockAccessExclusive},
+	{"CLUSTER", nil, LockAccessExclusive},
+	{"VACUUM FULL", nil, LockAccessExclusive},
+	{"VACUUM", nil, LockShareUpdateExclusive},
+	{"ANALYZE", nil, LockShareUpdateExclusive},
+	{"REFRESH MATERIALIZED VIEW", []string{" CONCURRENTLY "}, LockExclusive},
+	{"REFRESH MATERIALIZED VIEW", nil, LockAccessExclusive},
+	{"LOCK TABLE", []string{" IN ACCESS SHARE MODE"}, LockAccessShare},
+	{"LOCK TABLE", []string{" IN ROW SHARE MODE"}, LockRowShare},
+	{"LOCK TABLE", []string{" IN ROW EXCLUSIVE MODE"}, LockRowExclusive},
+	{"LOCK TABLE", []string{" IN SHARE UPDATE EXCLUSIVE MODE"}, LockShareUpdateExclusive},
+	{"LOCK TABLE", []string{" IN SHARE ROW EXCLUSIVE MODE"}, LockShareRowExclusive},
+	{"LOCK TABLE", []string{" IN SHARE MODE"}, LockShare},
+	{"LOCK TABLE", []string{" IN EXCLUSIVE MODE"}, LockExclusive},
+	{"LOCK TABLE", nil, LockAccessExclusive},
+	{"INSERT", nil, LockRowExclusive},
+	{"UPDATE", nil, LockRowExclusive},
+	{"DELETE", nil, LockRowExclusive},
+	{"MERGE", nil, LockRowExclusive},
+	{"COPY", []string{" FROM "}, LockRowExclusive},
+	{"SELECT", []string{" FOR UPDATE"}, LockRowShare},
+	{"SELECT", []string{" FOR SHARE"}, LockRowShare},
+	{"SELECT", nil, LockAccessShare},
+	{"WITH", nil, LockRowExclusive},
+	{"CREATE", nil, LockNone},
+	{"COMMENT", nil, LockShareUpdateExclusive},
+	{"GRANT", nil, LockNone},
+	{"REVOKE", nil, LockNone},
+	{"SET", nil, LockNone},
+	{"DROP", nil, LockAccessExclusive},
+}
+
+var mysqlLockRules = []lockRule{
+	{"ALTER TABLE", []string{"LOCK=NONE"}, LockSharedMetadata},
+	{"ALTER TABLE", []string{"ALGORITHM=INSTANT"}, LockSharedMetadata},
+	{"ALTER", nil, LockExclusiveMetadata},
+	{"CREATE INDEX", []string{"LOCK=NONE"}, LockSharedMetadata},
+	{"CREATE UNIQUE INDEX", []string{"LOCK=NONE"}, LockSharedMetadata},
+	{"CREATE INDEX", nil, LockExclusiveMetadata},
+	{"CREATE UNIQUE INDEX", nil, LockExclusiveMetadata},
+	{"CREATE TABLE", []string{" REFERENCES "}, LockSharedMetadata},
+	{"CREATE TABLE", []string{" SELECT "}, LockSharedMetadata},
+	{"CREATE", nil, LockNone},
+	{"DROP", nil, LockExclusiveMetadata},
+	{"RENAME", nil, LockExclusiveMetadata},
+	{"TRUNCATE", nil, LockExclusiveMetadata},
+	{"OPTIMIZE", nil, LockExclusiveMetadata},
+	{"LOCK TABLE", nil, LockExclusiveMetadata},
+	{"INSERT", nil, LockRowLevel},
+	{"REPLACE", nil, LockRowLevel},
+	{"UPDATE", nil, LockRowLevel},
+	{"DELETE", nil, LockRowLevel},
+	{"SELECT", []string{" FOR UPDATE"}, LockRowLevel},
+	{"SELECT", []string{" FOR SHARE"}, LockRowLevel},
+	{"SELECT", []string{" LOCK IN SHARE MODE"}, LockRowLevel},
+	{"SELECT", nil, LockSharedMetadata},
+	{"GRANT", nil, LockNone},
+	{"REVOKE", nil, LockNone},
+	{"SET", nil, LockNone},
+}
+
+func postgresLock(stmt string) LockLevel {
+	return matchLockRules(postgresLockRules, stmt)
+}
+
+func mysqlLock(stmt string) LockLevel {
+	return matchLockRules(mysqlLockRules, stmt)
+}
+
+func matchLockRules(rules []lockRule, stmt string) LockLevel {
+	padded := " " + stmt + " "
+rules:
+	for _, r := range rules {
+		if !strings.HasPrefix(stmt, r.prefix) {
+			continue
+		}
+		for _, c := range r.contains {
+			if !strings.Contains(padded, c) {
+				continue rules
+			}
+		}
+		return r.lock
+	}
+	return LockUnknown
+}
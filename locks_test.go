@@ -0,0 +1,47 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeLocks(t *testing.T) {
+	script := `
+		CREATE TABLE toys (id serial, person_id int REFERENCES people (id));
+		-- the index is built without blocking writes
+		CREATE INDEX CONCURRENTLY idx_toys_person_id ON toys (person_id);
+		ALTER TABLE people ADD COLUMN age int;
+		ALTER TABLE pets ADD CONSTRAINT fk_pets_person FOREIGN KEY (person_id) REFERENCES people (id) NOT VALID;
+		UPDATE people SET age = 0;
+		CREATE TABLE books (id serial);
+		DO $$ BEGIN PERFORM 1; END $$;
+	`
+
+	t.Run("postgres", func(t *testing.T) {
+		var locks []LockLevel
+		for _, l := range AnalyzeLocks("postgres", script) {
+			locks = append(locks, l.Lock)
+		}
+		assert.Equal(t, []LockLevel{
+			LockShareRowExclusive,
+			LockShareUpdateExclusive,
+			LockAccessExclusive,
+			LockShareRowExclusive,
+			LockRowExclusive,
+			LockNone,
+			LockUnknown,
+		}, locks)
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		locks := AnalyzeLocks("mysql", "ALTER TABLE people ADD COLUMN age int, ALGORITHM=INSTANT; ALTER TABLE people DROP COLUMN age; DELETE FROM people")
+		assert.Equal(t, []StatementLock{
+			{Statement: "ALTER TABLE people ADD COLUMN age int, ALGORITHM=INSTANT", Lock: LockSharedMetadata},
+			{Statement: "ALTER TABLE people DROP COLUMN age", Lock: LockExclusiveMetadata},
+			{Statement: "DELETE FROM people", Lock: LockRowLevel},
+		}, locks)
+	})
+
+	assert.Equal(t, "ACCESS EXCLUSIVE", LockAccessExclusive.String())
+}
@@ -0,0 +1,137 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MigrationSet is a named list of migrations, ie. the core schema, the seeds
+// or the migrations of a module, composed with other sets by a Manifest.
+type MigrationSet struct {
+	Name       string
+	Migrations []*Migration
+	// After names the sets whose migrations run before the ones of the set.
+	After []string
+}
+
+// Manifest composes migration sets into the migrations of a single run, so
+// they are applied in one transaction and reported together. The sets are
+// ordered by their After constraints, then in the order they are given, and
+// the migrations of a set keep their order.
+type Manifest struct {
+	sets       []*MigrationSet
+	migrations []*Migration
+	setOf      map[string]string
+}
+
+// SetResult is the part of the result of a run applying a set.
+type SetResult struct {
+	Name       string
+	Migrations []MigrationResult
+}
+
+// NewManifest validates and orders migration sets: the names of the sets and
+// the IDs of their migrations must be unique, and their After constraints
+// must name other sets without cycles.
+func NewManifest(sets ...*MigrationSet) (*Manifest, error) {
+	byName := make(map[string]*MigrationSet, len(sets))
+	for _, s := range sets {
+		if s.Name == "" {
+			return nil, ErrMissingSetName
+		}
+		if _, ok := byName[s.Name]; ok {
+			return nil, fmt.Errorf(`sqlxmigrate: Duplicated migration set "%s"`, s.Name)
+		}
+		byName[s.Name] = s
+	}
+	for _, s := range sets {
+		for _, after := range s.After {
+			if _, ok := byName[after]; !ok {
+				return nil, fmt.Errorf(`sqlxmigrate: Migration set "%s" runs after the unknown set "%s"`, s.Name, after)
+			}
+		}
+	}
+
+	// The first set given whose constraints are met is taken, so the order
+	// of the sets without constraints between them is kept.
+	mf := &Manifest{setOf: make(map[string]string)}
+	done := make(map[string]bool, len(sets))
+	for len(mf.sets) < len(sets) {
+		var next *MigrationSet
+		for _, s := range sets {
+			if !done[s.Name] && setReady(s, done) {
+				next = s
+				break
+			}
+		}
+		if next == nil {
+			var cycle []string
+			for _, s := range sets {
+				if !done[s.Name] {
+					cycle = append(cycle, s.Name)
+				}
+			}
+			return nil, fmt.Errorf(`sqlxmigrate: Migration sets "%s" depend on each other`, strings.Join(cycle, `", "`))
+		}
+
+		for _, m := range next.Migrations {
+			if set, ok := mf.setOf[m.ID]; ok {
+				return nil, fmt.Errorf(`sqlxmigrate: Migration "%s" is in the sets "%s" and "%s"`, m.ID, set, next.Name)
+			}
+			mf.setOf[m.ID] = next.Name
+			mf.migrations = append(mf.migrations, m)
+		}
+		mf.sets = append(mf.sets, next)
+		done[next.Name] = true
+	}
+	return mf, nil
+}
+
+// setReady returns whether the sets a set runs after are ordered.
+func setReady(s *MigrationSet, done map[string]bool) bool {
+	for _, after := range s.After {
+		if !done[after] {
+			return false
+		}
+	}
+	return true
+}
+
+// Migrations returns the migrations of the sets in the order they run, to
+// create the migrator with New.
+func (mf *Manifest) Migrations() []*Migration {
+	return mf.migrations
+}
+
+// Sets returns the names of the sets in the order they run.
+func (mf *Manifest) Sets() []string {
+	names := make([]string, len(mf.sets))
+	for i, s := range mf.sets {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// SetOf returns the name of the set of a migration, empty when no set
+// includes it.
+func (mf *Manifest) SetOf(migrationID string) string {
+	return mf.setOf[migrationID]
+}
+
+// SetResults groups the migrations applied by a run by set, in the order
+// the sets run. The sets without applied migrations are left out.
+func (mf *Manifest) SetResults(res *Result) []SetResult {
+	var results []SetResult
+	for _, s := range mf.sets {
+		sr := SetResult{Name: s.Name}
+		for _, m := range res.Migrations {
+			if mf.setOf[m.ID] == s.Name {
+				sr.Migrations = append(sr.Migrations, m)
+			}
+		}
+		if len(sr.Migrations) > 0 {
+			results = append(results, sr)
+		}
+	}
+	return results
+}
@@ -0,0 +1,88 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noopMigration(id string) *Migration {
+	return &Migration{ID: id, MigrateSQL: "SELECT 1", RollbackSQL: "SELECT 1"}
+}
+
+func migrationIDs(migrations []*Migration) []string {
+	ids := make([]string, len(migrations))
+	for i, m := range migrations {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func TestManifestOrder(t *testing.T) {
+	seeds := &MigrationSet{Name: "seeds", Migrations: []*Migration{noopMigration("201901010000_seed")}, After: []string{"core", "billing"}}
+	core := &MigrationSet{Name: "core", Migrations: []*Migration{noopMigration("201801010000_people"), noopMigration("201701010000_pets")}}
+	billing := &MigrationSet{Name: "billing", Migrations: []*Migration{noopMigration("201601010000_invoices")}, After: []string{"core"}}
+	search := &MigrationSet{Name: "search", Migrations: []*Migration{noopMigration("201501010000_index")}}
+
+	mf, err := NewManifest(seeds, core, billing, search)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"core", "billing", "seeds", "search"}, mf.Sets())
+	assert.Equal(t, []string{
+		"201801010000_people",
+		"201701010000_pets",
+		"201601010000_invoices",
+		"201901010000_seed",
+		"201501010000_index",
+	}, migrationIDs(mf.Migrations()))
+	assert.Equal(t, "billing", mf.SetOf("201601010000_invoices"))
+	assert.Equal(t, "", mf.SetOf("201601010000_unknown"))
+}
+
+func TestManifestInvalid(t *testing.T) {
+	_, err := NewManifest(&MigrationSet{})
+	assert.Equal(t, ErrMissingSetName, err)
+
+	_, err = NewManifest(&MigrationSet{Name: "core"}, &MigrationSet{Name: "core"})
+	assert.EqualError(t, err, `sqlxmigrate: Duplicated migration set "core"`)
+
+	_, err = NewManifest(&MigrationSet{Name: "seeds", After: []string{"core"}})
+	assert.EqualError(t, err, `sqlxmigrate: Migration set "seeds" runs after the unknown set "core"`)
+
+	_, err = NewManifest(
+		&MigrationSet{Name: "core"},
+		&MigrationSet{Name: "billing", After: []string{"seeds"}},
+		&MigrationSet{Name: "seeds", After: []string{"billing"}},
+	)
+	assert.EqualError(t, err, `sqlxmigrate: Migration sets "billing", "seeds" depend on each other`)
+
+	_, err = NewManifest(
+		&MigrationSet{Name: "core", Migrations: []*Migration{noopMigration("201801010000")}},
+		&MigrationSet{Name: "billing", Migrations: []*Migration{noopMigration("201801010000")}},
+	)
+	assert.EqualError(t, err, `sqlxmigrate: Migration "201801010000" is in the sets "core" and "billing"`)
+}
+
+func TestManifestRun(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		mf, err := NewManifest(
+			&MigrationSet{Name: "seeds", Migrations: []*Migration{noopMigration("201901010000_seed")}, After: []string{"core"}},
+			&MigrationSet{Name: "core", Migrations: migrations},
+		)
+		require.NoError(t, err)
+
+		m := New(db, DefaultOptions, mf.Migrations())
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.True(t, m.hasTable("pets"))
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+
+		results := mf.SetResults(res)
+		require.Len(t, results, 2)
+		assert.Equal(t, "core", results[0].Name)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, []string{results[0].Migrations[0].ID, results[0].Migrations[1].ID})
+		assert.Equal(t, "seeds", results[1].Name)
+		assert.Len(t, results[1].Migrations, 1)
+	})
+}
@@ -0,0 +1,99 @@
+package sqlxmigrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetMessageFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	m := New(nil, DefaultOptions, migrations)
+	m.SetLogger(log.New(&buf, "", 0))
+
+	m.logf("Migration %s - checking", "201608301400")
+	assert.Equal(t, "Migration 201608301400 - checking\n", buf.String())
+
+	m.SetMessageFormatter(func(format string, args ...interface{}) string {
+		if format == "Migration %s - checking" {
+			format = "Migration %s - vérification"
+		}
+		return fmt.Sprintf(format, args...) + " (see https://runbooks.example.com/migrations)"
+	})
+
+	buf.Reset()
+	m.logf("Migration %s - checking", "201608301400")
+	assert.Equal(t, "Migration 201608301400 - vérification (see https://runbooks.example.com/migrations)\n", buf.String())
+}
+
+func TestJSONLog(t *testing.T) {
+	var buf bytes.Buffer
+	options := *DefaultOptions
+	options.JSONLog = true
+	m := New(nil, &options, migrations)
+	m.SetLogger(log.New(&buf, "", 0))
+	m.runID = "run"
+
+	decode := func() LogEvent {
+		var ev LogEvent
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &ev))
+		buf.Reset()
+		return ev
+	}
+
+	m.logf("Migration %s - complete - %d statements, %d rows affected in %s", "201608301400", 2, 4, 1500*time.Millisecond)
+	ev := decode()
+	assert.Equal(t, "run", ev.RunID)
+	assert.Equal(t, "201608301400", ev.MigrationID)
+	assert.Equal(t, "complete", ev.Phase)
+	assert.Equal(t, 1.5, ev.Duration)
+	assert.Empty(t, ev.SQLHash)
+	assert.Equal(t, "Migration 201608301400 - complete - 2 statements, 4 rows affected in 1.5s", ev.Message)
+	assert.False(t, ev.Time.IsZero())
+
+	m.logf("Migration %s - %s", "201608301400", "INSERT INTO people (name) VALUES (?)")
+	ev = decode()
+	assert.Equal(t, "sql", ev.Phase)
+	assert.Equal(t, checksum("INSERT INTO people (name) VALUES (?)"), ev.SQLHash)
+
+	m.logf("lock %s", "SELECT pg_advisory_lock(1)")
+	ev = decode()
+	assert.Empty(t, ev.MigrationID)
+	assert.Equal(t, "lock", ev.Phase)
+	assert.NotEmpty(t, ev.SQLHash)
+
+	m.logf("Migration %s rollback - removing", "201608301400")
+	assert.Equal(t, "rollback", decode().Phase)
+}
+
+func TestJSONLogRun(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		var buf bytes.Buffer
+		options := *DefaultOptions
+		options.JSONLog = true
+		m := New(db, &options, migrations)
+		m.SetLogger(log.New(&buf, "", 0))
+		require.NoError(t, m.Migrate())
+
+		var completed []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev LogEvent
+			require.NoError(t, json.Unmarshal([]byte(line), &ev), line)
+			assert.NotEmpty(t, ev.Phase, line)
+			if ev.Phase == "complete" {
+				assert.NotEmpty(t, ev.RunID)
+				assert.True(t, ev.Duration > 0)
+				completed = append(completed, ev.MigrationID)
+			}
+		}
+		assert.Equal(t, []string{"201608301400", "201608301430"}, completed)
+	})
+}
@@ -0,0 +1,15 @@
+//go:build mssql
+// +build mssql
+
+package sqlxmigrate
+
+import (
+	_ "github.com/denisenkom/go-mssqldb"
+)
+
+func init() {
+	databases = append(databases, database{
+		name:    "sqlserver",
+		connEnv: "MSSQL_CONN_STRING",
+	})
+}
@@ -0,0 +1,181 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MySQLAddColumn adds a column with a default to a MySQL table without
+// blocking the writes, whatever the version of the server.
+//
+// MySQL 8.0.12 and MariaDB 10.3.2 add the column instantly with
+// ALGORITHM=INSTANT, only changing the metadata of the table. The older
+// servers fall back to the online path:
+//
+//  1. add the column as nullable with ALGORITHM=INPLACE, LOCK=NONE
+//  2. set the default of the column, for the rows inserted from now on
+//  3. backfill the default in batches
+//  4. make the column NOT NULL with ALGORITHM=INPLACE, LOCK=NONE if NotNull
+//     is set
+//
+// The version is checked when the migration runs, so the same migration
+// works against every environment.
+type MySQLAddColumn struct {
+	// Table is the name of the table.
+	Table string
+	// Column is the name of the new column.
+	Column string
+	// Type is the type of the column, ie. "varchar(20)".
+	Type string
+	// Default is the SQL expression of the default of the column, ie.
+	// "'active'".
+	Default string
+	// NotNull is whether the column is NOT NULL.
+	NotNull bool
+	// KeyColumn is the integer primary key used to backfill in batches on the
+	// online path.
+	KeyColumn string
+	// BatchSize is the number of rows updated per statement on the online
+	// path. Defaults to 1000.
+	BatchSize int
+}
+
+// Migration returns the migration adding the column.
+func (c *MySQLAddColumn) Migration(id string) *Migration {
+	return &Migration{
+		ID: id,
+		MigrateTx: func(tx *Tx) error {
+			var version string
+			if err := tx.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+				return err
+			}
+			if mysqlInstantAddColumn(version) {
+				_, err := tx.Exec(c.instantSQL())
+				return err
+			}
+			return c.addOnline(tx)
+		},
+		RollbackTx: func(tx *Tx) error {
+			_, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", c.Table, c.Column))
+			return err
+		},
+	}
+}
+
+// definition returns the definition of the column once added.
+func (c *MySQLAddColumn) definition() string {
+	def := c.Type
+	if c.NotNull {
+		def += " NOT NULL"
+	}
+	if c.Default != "" {
+		def += " DEFAULT " + c.Default
+	}
+	return def
+}
+
+func (c *MySQLAddColumn) instantSQL() string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s, ALGORITHM=INSTANT", c.Table, c.Column, c.definition())
+}
+
+// onlineSQL returns the statements of the online path, around the backfill.
+func (c *MySQLAddColumn) onlineSQL() (before []string, after []string) {
+	before = []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s NULL, ALGORITHM=INPLACE, LOCK=NONE", c.Table, c.Column, c.Type),
+	}
+	if c.Default != "" {
+		before = append(before, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", c.Table, c.Column, c.Default))
+	}
+	if c.NotNull {
+		after = append(after, fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s, ALGORITHM=INPLACE, LOCK=NONE", c.Table, c.Column, c.definition()))
+	}
+	return before, after
+}
+
+// addOnline adds the column without ALGORITHM=INSTANT, backfilling the
+// default of the existing rows in batches ordered by key.
+func (c *MySQLAddColumn) addOnline(tx *Tx) error {
+	before, after := c.onlineSQL()
+	for _, stmt := range before {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if c.Default != "" {
+		if err := c.backfill(tx); err != nil {
+			return err
+		}
+	}
+
+	for _, stmt := range after {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *MySQLAddColumn) backfill(tx *Tx) error {
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	boundQuery := fmt.Sprintf("SELECT max(%[1]s) FROM (SELECT %[1]s FROM %[2]s WHERE %[1]s > ? ORDER BY %[1]s LIMIT %[3]d) b",
+		c.KeyColumn, c.Table, batchSize)
+	updateQuery := fmt.Sprintf("UPDATE %[1]s SET %[2]s = %[3]s WHERE %[4]s > ? AND %[4]s <= ? AND %[2]s IS NULL",
+		c.Table, c.Column, c.Default, c.KeyColumn)
+
+	var last int64
+	if err := tx.QueryRow(fmt.Sprintf("SELECT coalesce(min(%s), 0) - 1 FROM %s", c.KeyColumn, c.Table)).Scan(&last); err != nil {
+		return err
+	}
+
+	for {
+		var bound sql.NullInt64
+		if err := tx.QueryRow(boundQuery, last).Scan(&bound); err != nil {
+			return err
+		}
+		if !bound.Valid {
+			return nil
+		}
+
+		if _, err := tx.Exec(updateQuery, last, bound.Int64); err != nil {
+			return err
+		}
+		last = bound.Int64
+	}
+}
+
+// mysqlInstantAddColumn returns whether the server of the given version adds
+// columns with ALGORITHM=INSTANT: MySQL 8.0.12 and MariaDB 10.3.2.
+func mysqlInstantAddColumn(version string) bool {
+	minimum := [3]int{8, 0, 12}
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		minimum = [3]int{10, 3, 2}
+		// MariaDB 10 prefixes its version with 5.5.5- for old clients.
+		version = strings.TrimPrefix(version, "5.5.5-")
+	}
+
+	// ie. "8.0.36", "5.7.44-log" or "10.6.16-MariaDB-1:10.6.16+maria~ubu2004".
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.SplitN(version, ".", 3)
+	for i := 0; i < len(minimum); i++ {
+		var n int
+		if i < len(parts) {
+			var err error
+			if n, err = strconv.Atoi(parts[i]); err != nil {
+				return false
+			}
+		}
+		if n != minimum[i] {
+			return n > minimum[i]
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLAddColumn(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		require.NoError(t, New(db, DefaultOptions, migrations[:1]).Migrate())
+		_, err := db.Exec(`INSERT INTO people (name) VALUES ('alice'), ('bob'), ('carol')`)
+		require.NoError(t, err)
+
+		add := &MySQLAddColumn{
+			Table:     "people",
+			Column:    "status",
+			Type:      "varchar(20)",
+			Default:   "'active'",
+			NotNull:   true,
+			KeyColumn: "id",
+			BatchSize: 2,
+		}
+		m := New(db, DefaultOptions, append(migrations[:1:1], add.Migration("201608301500_add_people_status")))
+		require.NoError(t, m.Migrate())
+
+		var count int
+		require.NoError(t, db.Get(&count, `SELECT count(*) FROM people WHERE status = 'active'`))
+		assert.Equal(t, 3, count)
+
+		require.NoError(t, m.RollbackLast())
+		_, err = db.Exec(`SELECT status FROM people`)
+		assert.Error(t, err)
+	}, "mysql")
+}
+
+func TestMySQLAddColumnSQL(t *testing.T) {
+	add := &MySQLAddColumn{Table: "people", Column: "status", Type: "varchar(20)", Default: "'active'", NotNull: true, KeyColumn: "id"}
+
+	assert.Equal(t, "ALTER TABLE people ADD COLUMN status varchar(20) NOT NULL DEFAULT 'active', ALGORITHM=INSTANT", add.instantSQL())
+
+	before, after := add.onlineSQL()
+	assert.Equal(t, []string{
+		"ALTER TABLE people ADD COLUMN status varchar(20) NULL, ALGORITHM=INPLACE, LOCK=NONE",
+		"ALTER TABLE people ALTER COLUMN status SET DEFAULT 'active'",
+	}, before)
+	assert.Equal(t, []string{
+		"ALTER TABLE people MODIFY COLUMN status varchar(20) NOT NULL DEFAULT 'active', ALGORITHM=INPLACE, LOCK=NONE",
+	}, after)
+
+	add.NotNull = false
+	_, after = add.onlineSQL()
+	assert.Empty(t, after)
+}
+
+func TestMySQLInstantAddColumn(t *testing.T) {
+	for version, instant := range map[string]bool{
+		"8.0.36":           true,
+		"8.0.12":           true,
+		"8.0.11":           false,
+		"8.4.0-commercial": true,
+		"5.7.44-log":       false,
+		"5.6.51":           false,
+		"10.3.2-MariaDB":   true,
+		"10.2.44-MariaDB":  false,
+		"10.6.16-MariaDB-1:10.6.16+maria~ubu2004": true,
+		"5.5.5-10.6.16-MariaDB":                   true,
+		"unknown":                                 false,
+	} {
+		assert.Equal(t, instant, mysqlInstantAddColumn(version), version)
+	}
+}
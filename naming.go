@@ -0,0 +1,159 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+// NamingConvention holds the templates of the names of the indexes and
+// constraints, where <table> is replaced by the name of the table, <cols> by
+// the names of the columns joined by underscores, and <ref_table> by the
+// table a foreign key references.
+type NamingConvention struct {
+	Index       string
+	UniqueIndex string
+	PrimaryKey  string
+	ForeignKey  string
+	Unique      string
+	// MaxLength is the length of the longest identifier of the database,
+	// the longer names are truncated and suffixed by a hash of the full
+	// name. Disabled when 0.
+	MaxLength int
+}
+
+// DefaultNamingConvention fits the identifiers of PostgreSQL, limited to 63
+// characters.
+var DefaultNamingConvention = NamingConvention{
+	Index:       "idx_<table>_<cols>",
+	UniqueIndex: "uq_<table>_<cols>",
+	PrimaryKey:  "pk_<table>",
+	ForeignKey:  "fk_<table>_<cols>_<ref_table>",
+	Unique:      "uq_<table>_<cols>",
+	MaxLength:   63,
+}
+
+// IndexName returns the name of an index.
+func (c NamingConvention) IndexName(table string, cols ...string) string {
+	return c.name(c.Index, table, cols, "")
+}
+
+// UniqueIndexName returns the name of a unique index.
+func (c NamingConvention) UniqueIndexName(table string, cols ...string) string {
+	return c.name(c.UniqueIndex, table, cols, "")
+}
+
+// PrimaryKeyName returns the name of the primary key of a table.
+func (c NamingConvention) PrimaryKeyName(table string) string {
+	return c.name(c.PrimaryKey, table, nil, "")
+}
+
+// ForeignKeyName returns the name of a foreign key referencing refTable.
+func (c NamingConvention) ForeignKeyName(table string, cols []string, refTable string) string {
+	return c.name(c.ForeignKey, table, cols, refTable)
+}
+
+// UniqueName returns the name of a unique constraint.
+func (c NamingConvention) UniqueName(table string, cols ...string) string {
+	return c.name(c.Unique, table, cols, "")
+}
+
+func (c NamingConvention) name(template, table string, cols []string, refTable string) string {
+	name := strings.NewReplacer(
+		"<table>", unqualifiedName(table),
+		"<cols>", strings.Join(cols, "_"),
+		"<ref_table>", unqualifiedName(refTable),
+	).Replace(template)
+	if c.MaxLength <= 0 || len(name) <= c.MaxLength {
+		return name
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	suffix := fmt.Sprintf("_%08x", h.Sum32())
+	return strings.TrimRight(name[:c.MaxLength-len(suffix)], "_") + suffix
+}
+
+// unqualifiedName returns the name of an object without its schema.
+func unqualifiedName(name string) string {
+	name = unquoteName(name)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+var (
+	createIndexRe = regexp.MustCompile(`(?i)^CREATE (?:UNIQUE )?INDEX (?:CONCURRENTLY )?(?:IF NOT EXISTS )?([^\s(]+) ON (?:ONLY )?([^\s(]+)(?: USING \w+)? ?\((.*)\)`)
+	constraintRe  = regexp.MustCompile(`(?i)CONSTRAINT ([^\s(]+) (PRIMARY KEY|FOREIGN KEY|UNIQUE) ?\(([^)]*)\)(?: REFERENCES ([^\s(]+))?`)
+)
+
+// Policy returns a policy flagging the indexes created and the constraints
+// declared by CREATE TABLE and ALTER TABLE whose name deviates from the
+// convention. The kinds without a template and the indexes on expressions
+// are allowed.
+func (c NamingConvention) Policy() Policy {
+	return func(s Statement) error {
+		if m := createIndexRe.FindStringSubmatch(s.SQL); m != nil {
+			cols, ok := indexColumns(m[3])
+			template := c.Index
+			if strings.HasPrefix(strings.ToUpper(s.SQL), "CREATE UNIQUE") {
+				template = c.UniqueIndex
+			}
+			if !ok || template == "" {
+				return nil
+			}
+			return checkName("index", m[1], c.name(template, m[2], cols, ""))
+		}
+
+		if s.Kind != "CREATE TABLE" && s.Kind != "ALTER TABLE" {
+			return nil
+		}
+		for _, m := range constraintRe.FindAllStringSubmatch(s.SQL, -1) {
+			cols, ok := indexColumns(m[3])
+			if !ok {
+				continue
+			}
+			var template string
+			switch strings.ToUpper(m[2]) {
+			case "PRIMARY KEY":
+				template = c.PrimaryKey
+			case "FOREIGN KEY":
+				template = c.ForeignKey
+			case "UNIQUE":
+				template = c.Unique
+			}
+			if template == "" {
+				continue
+			}
+			if err := checkName("constraint", m[1], c.name(template, s.Object, cols, m[4])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// indexColumns returns the names of the columns of an index or constraint,
+// false when it includes expressions.
+func indexColumns(list string) ([]string, bool) {
+	var cols []string
+	for _, col := range strings.Split(list, ",") {
+		fields := strings.Fields(unquoteName(col))
+		if len(fields) == 0 || strings.ContainsAny(col, "()") {
+			return nil, false
+		}
+		cols = append(cols, fields[0])
+	}
+	return cols, true
+}
+
+// checkName returns an error when the name of an object isn't the one
+// expected.
+func checkName(kind, name, want string) error {
+	if name := unquoteName(name); !strings.EqualFold(name, want) {
+		return fmt.Errorf(`%s "%s" should be named "%s"`, kind, name, want)
+	}
+	return nil
+}
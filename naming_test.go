@@ -0,0 +1,58 @@
+package sqlxmigrate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamingConventionNames(t *testing.T) {
+	c := DefaultNamingConvention
+	assert.Equal(t, "idx_people_last_name_first_name", c.IndexName("public.people", "last_name", "first_name"))
+	assert.Equal(t, "uq_people_email", c.UniqueIndexName("people", "email"))
+	assert.Equal(t, "pk_people", c.PrimaryKeyName(`"public"."people"`))
+	assert.Equal(t, "fk_pets_person_id_people", c.ForeignKeyName("pets", []string{"person_id"}, "public.people"))
+	assert.Equal(t, "uq_people_email", c.UniqueName("people", "email"))
+
+	// The names longer than the identifiers are truncated deterministically.
+	long := c.IndexName("people", strings.Repeat("a", 40), strings.Repeat("b", 40))
+	assert.Len(t, long, 63)
+	assert.True(t, strings.HasPrefix(long, "idx_people_aaaa"))
+	assert.Equal(t, long, c.IndexName("people", strings.Repeat("a", 40), strings.Repeat("b", 40)))
+	assert.NotEqual(t, long, c.IndexName("people", strings.Repeat("a", 40), strings.Repeat("c", 40)))
+}
+
+func TestNamingConventionPolicy(t *testing.T) {
+	policy := DefaultNamingConvention.Policy()
+
+	for _, tt := range []struct {
+		sql string
+		err string
+	}{
+		{"CREATE INDEX idx_people_name ON people (name)", ""},
+		{"CREATE UNIQUE INDEX uq_people_email ON people (email)", ""},
+		{"CREATE UNIQUE INDEX idx_people_email ON people (email)", `index "idx_people_email" should be named "uq_people_email"`},
+		{"CREATE INDEX people_lower_name ON people (lower(name))", ""},
+		{`CREATE TABLE pets (id BIGINT, person_id BIGINT, CONSTRAINT pk_pets PRIMARY KEY (id), CONSTRAINT fk_pets_person_id_people FOREIGN KEY (person_id) REFERENCES people (id))`, ""},
+		{`CREATE TABLE pets (id BIGINT, CONSTRAINT pets_pkey PRIMARY KEY (id))`, `constraint "pets_pkey" should be named "pk_pets"`},
+		{"ALTER TABLE public.pets ADD CONSTRAINT pets_person_fk FOREIGN KEY (person_id) REFERENCES people (id)", `constraint "pets_person_fk" should be named "fk_pets_person_id_people"`},
+		{`ALTER TABLE people ADD CONSTRAINT "uq_people_email" UNIQUE (email)`, ""},
+		{"CREATE TABLE people (id BIGINT PRIMARY KEY)", ""},
+	} {
+		stmts := parseStatements("postgres", "201906121200", tt.sql)
+		require.Len(t, stmts, 1)
+		err := policy(stmts[0])
+		if tt.err == "" {
+			assert.NoError(t, err, tt.sql)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.sql)
+		}
+	}
+
+	// The kinds without a template are allowed.
+	policy = NamingConvention{Index: "idx_<table>_<cols>"}.Policy()
+	stmts := parseStatements("postgres", "201906121200", "CREATE TABLE pets (id BIGINT, CONSTRAINT pets_pkey PRIMARY KEY (id))")
+	assert.NoError(t, policy(stmts[0]))
+}
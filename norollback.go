@@ -0,0 +1,55 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// NoRollback marks a migration as irreversible when set as its Rollback, so
+// it passes Options.RequireRollback. Rolling it back fails with
+// ErrRollbackImpossible, as a migration without rollback.
+var NoRollback RollbackFunc = func(*sql.Tx) error {
+	return ErrRollbackImpossible
+}
+
+// MissingRollbackError is returned when a migration has no rollback and
+// Options.RequireRollback is set.
+type MissingRollbackError struct {
+	ID string
+}
+
+func (e *MissingRollbackError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migration "%s" has no rollback`, e.ID)
+}
+
+// Hint implements Hinter.
+func (e *MissingRollbackError) Hint() string {
+	return "Define the rollback of the migration, or set its Rollback to sqlxmigrate.NoRollback if it's irreversible."
+}
+
+// irreversible reports whether the migration is marked with NoRollback. Func
+// values can't be compared, their code pointers can.
+func (m *Migration) irreversible() bool {
+	return m.Rollback != nil && reflect.ValueOf(m.Rollback).Pointer() == reflect.ValueOf(NoRollback).Pointer()
+}
+
+// checkRollbacks checks that every migration has a rollback or is marked
+// irreversible when Options.RequireRollback is set. The squashed migrations
+// are never rolled back.
+func (g *Sqlxmigrate) checkRollbacks() error {
+	if !g.options.RequireRollback {
+		return nil
+	}
+
+	squashed := make(map[string]bool)
+	for _, id := range g.squashedIDs() {
+		squashed[id] = true
+	}
+	for _, m := range g.migrations {
+		if !m.hasRollback() && !m.irreversible() && !squashed[m.ID] {
+			return &MissingRollbackError{ID: m.ID}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noTxMigrations(db *sqlx.DB) []*Migration {
+	createIndex := `CREATE INDEX people_name ON people (name)`
+	dropIndex := `DROP INDEX people_name`
+	switch db.DriverName() {
+	case "postgres":
+		createIndex = `CREATE INDEX CONCURRENTLY people_name ON people (name)`
+		dropIndex = `DROP INDEX CONCURRENTLY people_name`
+	case "mysql":
+		createIndex = `CREATE INDEX people_name ON people (name(32))`
+		dropIndex = `DROP INDEX people_name ON people`
+	}
+
+	return []*Migration{
+		migrations[0],
+		{
+			ID:                 "201608301415",
+			DisableTransaction: true,
+			MigrateNoTx: func(db *sqlx.DB) error {
+				_, err := db.Exec(createIndex)
+				return err
+			},
+			RollbackNoTx: func(db *sqlx.DB) error {
+				_, err := db.Exec(dropIndex)
+				return err
+			},
+		},
+		migrations[1],
+	}
+}
+
+func TestDisableTransaction(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, noTxMigrations(db))
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+		assert.Len(t, res.Migrations, 3)
+		assert.Contains(t, res.Warnings, Warning{
+			Code:        WarningNonTransactional,
+			MigrationID: "201608301415",
+			Message:     "migration runs outside of a transaction and can't be rolled back on failure",
+		})
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+		assert.True(t, m.hasTable("people"))
+		assert.False(t, m.hasTable("pets"))
+	})
+}
+
+func TestDisableTransactionFailure(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		failure := errors.New("index build failed")
+		var rolledBack bool
+		m := New(db, DefaultOptions, []*Migration{
+			migrations[0],
+			{
+				ID:                 "201608301415",
+				DisableTransaction: true,
+				MigrateNoTx: func(db *sqlx.DB) error {
+					return failure
+				},
+				RollbackNoTx: func(db *sqlx.DB) error {
+					rolledBack = true
+					return nil
+				},
+			},
+			migrations[1],
+		})
+
+		err := m.Migrate()
+		assert.True(t, errors.Is(err, failure))
+		assert.True(t, rolledBack)
+
+		// the migration before it was committed
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+		assert.True(t, m.hasTable("people"))
+		assert.False(t, m.hasTable("pets"))
+	})
+}
+
+func TestDisableTransactionMissingMigrateNoTx(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, []*Migration{{
+			ID:                 "201608301400",
+			DisableTransaction: true,
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+		}})
+
+		assert.True(t, errors.Is(m.Migrate(), ErrMissingMigrateNoTx))
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+	})
+}
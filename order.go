@@ -0,0 +1,120 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnsortedMigrationsError is returned when Options.EnforceIDOrder is set and
+// a migration is registered after one whose ID sorts after its own.
+type UnsortedMigrationsError struct {
+	ID       string
+	Previous string
+}
+
+func (e *UnsortedMigrationsError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migration "%s" is registered after "%s" which sorts after it`, e.ID, e.Previous)
+}
+
+// Hint implements Hinter.
+func (e *UnsortedMigrationsError) Hint() string {
+	return "Sort the migrations with SortMigrations, or fix the timestamp of the migration ID if it was mistyped."
+}
+
+// idTimestampLayouts are the layouts of the timestamps prefixing the IDs.
+var idTimestampLayouts = map[int]string{
+	8:  "20060102",
+	12: "200601021504",
+	14: "20060102150405",
+}
+
+// CompareIDs compares two migration IDs, returning a negative number when a
+// sorts before b, zero when they're equal and a positive number otherwise.
+// The IDs are ordered by their timestamp prefix, so "20190612_create_people"
+// sorts before "201906121200_create_pets", or by their numeric prefix, so
+// "9_create_people" sorts before "10_create_pets", then by the IDs
+// themselves.
+func CompareIDs(a, b string) int {
+	pa, pb := timestampPrefix(a), timestampPrefix(b)
+	if pa != "" && pb != "" {
+		if c := comparePrefixes(pa, pb); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func comparePrefixes(a, b string) int {
+	la, oka := idTimestampLayouts[len(a)]
+	lb, okb := idTimestampLayouts[len(b)]
+	if oka && okb {
+		ta, erra := time.Parse(la, a)
+		tb, errb := time.Parse(lb, b)
+		if erra == nil && errb == nil {
+			switch {
+			case ta.Before(tb):
+				return -1
+			case ta.After(tb):
+				return 1
+			}
+			return 0
+		}
+	}
+
+	na, erra := strconv.ParseUint(a, 10, 64)
+	nb, errb := strconv.ParseUint(b, 10, 64)
+	if erra != nil || errb != nil || na == nb {
+		return 0
+	}
+	if na < nb {
+		return -1
+	}
+	return 1
+}
+
+// SortMigrations sorts migrations by ID with CompareIDs, keeping the order
+// of the migrations whose IDs compare equal.
+func SortMigrations(migrations []*Migration) {
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return CompareIDs(migrations[i].ID, migrations[j].ID) < 0
+	})
+}
+
+// Check whether the migrations are sorted by ID when Options.EnforceIDOrder
+// is set.
+func (g *Sqlxmigrate) checkIDOrder() error {
+	if !g.options.EnforceIDOrder {
+		return nil
+	}
+
+	for i := 1; i < len(g.migrations); i++ {
+		if g.compareIDs(g.migrations[i-1].ID, g.migrations[i].ID) > 0 {
+			return &UnsortedMigrationsError{ID: g.migrations[i].ID, Previous: g.migrations[i-1].ID}
+		}
+	}
+	return nil
+}
+
+// compareIDs compares two migration IDs with Options.CompareIDs, or
+// CompareIDs when not set.
+func (g *Sqlxmigrate) compareIDs(a, b string) int {
+	if g.options.CompareIDs != nil {
+		return g.options.CompareIDs(a, b)
+	}
+	return CompareIDs(a, b)
+}
+
+// lastAppliedID returns the ID sorting last among the applied IDs, compared
+// with compareIDs, leaving out SCHEMA_INIT. Empty when none is applied.
+func (g *Sqlxmigrate) lastAppliedID(appliedIDs []string) string {
+	var last string
+	for _, id := range appliedIDs {
+		if id != initSchemaMigrationID && (last == "" || g.compareIDs(id, last) > 0) {
+			last = id
+		}
+	}
+	return last
+}
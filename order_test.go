@@ -0,0 +1,102 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareIDs(t *testing.T) {
+	for _, ids := range [][2]string{
+		{"201608301400", "201608301430"},
+		{"20190612_create_people", "201906121200_create_pets"},
+		{"201906121200_create_people", "20190613_create_pets"},
+		{"20190612120000_create_people", "201906121201_create_pets"},
+		{"9_create_people", "10_create_pets"},
+		{"201906121200_create_people", "201906121200_create_people_contract"},
+		{"create_people", "create_pets"},
+	} {
+		assert.True(t, CompareIDs(ids[0], ids[1]) < 0, "%s < %s", ids[0], ids[1])
+		assert.True(t, CompareIDs(ids[1], ids[0]) > 0, "%s > %s", ids[1], ids[0])
+	}
+	assert.Equal(t, 0, CompareIDs("201608301400", "201608301400"))
+}
+
+func TestSortMigrations(t *testing.T) {
+	ms := []*Migration{{ID: "10_c"}, {ID: "201608301430"}, {ID: "9_b"}, {ID: "201608301400"}}
+	SortMigrations(ms)
+	assert.Equal(t, []string{"9_b", "10_c", "201608301400", "201608301430"}, migrationIDs(ms))
+}
+
+func TestEnforceIDOrder(t *testing.T) {
+	options := *DefaultOptions
+	unsorted := []*Migration{migrations[1], migrations[0]}
+
+	m := New(sqlx.NewDb(nil, "sqlite3"), &options, unsorted)
+	assert.NoError(t, m.checkIDOrder())
+
+	options.EnforceIDOrder = true
+	m = New(sqlx.NewDb(nil, "sqlite3"), &options, unsorted)
+	err := m.checkIDOrder()
+	var unsortedErr *UnsortedMigrationsError
+	require.True(t, errors.As(err, &unsortedErr))
+	assert.Equal(t, "201608301400", unsortedErr.ID)
+	assert.Equal(t, "201608301430", unsortedErr.Previous)
+	assert.NotEmpty(t, Hint(err))
+
+	m = New(sqlx.NewDb(nil, "sqlite3"), &options, migrations)
+	assert.NoError(t, m.checkIDOrder())
+
+	options.CompareIDs = func(a, b string) int {
+		return -strings.Compare(a, b)
+	}
+	m = New(sqlx.NewDb(nil, "sqlite3"), &options, unsorted)
+	assert.NoError(t, m.checkIDOrder())
+}
+
+func TestEnforceIDOrderMigrate(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.EnforceIDOrder = true
+
+		m := New(db, &options, []*Migration{migrations[1], migrations[0]})
+		var unsortedErr *UnsortedMigrationsError
+		assert.True(t, errors.As(m.Migrate(), &unsortedErr))
+		assert.True(t, errors.As(m.Validate(), &unsortedErr))
+		assert.False(t, m.hasTable("people"))
+
+		m = New(db, &options, migrations)
+		require.NoError(t, m.Migrate())
+		require.NoError(t, m.Validate())
+	})
+}
+
+func TestOutOfOrderNumericIDs(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		first := &Migration{ID: "9_create_people", MigrateSQL: "CREATE TABLE people (id INTEGER)", RollbackSQL: "DROP TABLE people"}
+		second := &Migration{ID: "10_create_pets", MigrateSQL: "CREATE TABLE pets (id INTEGER)", RollbackSQL: "DROP TABLE pets"}
+
+		options := *DefaultOptions
+		options.OutOfOrder = SeverityError
+		m := New(db, &options, []*Migration{first})
+		require.NoError(t, m.Migrate())
+
+		// 10_create_pets sorts after 9_create_people, not before it as a
+		// string.
+		m = New(db, &options, []*Migration{first, second})
+		require.NoError(t, m.Validate())
+		options.OutOfOrder = SeverityWarn
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		for _, w := range res.Warnings {
+			assert.NotEqual(t, WarningOutOfOrder, w.Code)
+		}
+
+		require.NoError(t, m.RollbackTo("9_create_people"))
+		require.NoError(t, m.RollbackLast())
+	})
+}
@@ -0,0 +1,16 @@
+module github.com/geeks-accelerator/sqlxmigrate/otel
+
+go 1.16
+
+require (
+	github.com/geeks-accelerator/sqlxmigrate v0.0.0-20261015155135-846658865517
+	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/sdk v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+)
+
+// The tracer is developed along the module it traces. The replace directive
+// only applies when building in this repository, the modules importing the
+// tracer resolve the version required above, to bump along the root module.
+replace github.com/geeks-accelerator/sqlxmigrate => ../
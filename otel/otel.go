@@ -0,0 +1,70 @@
+// Package otel traces the runs of sqlxmigrate with OpenTelemetry.
+//
+// Each run is a span, sqlxmigrate.migrate or sqlxmigrate.rollback, parent of
+// the spans of its migrations, ie. sqlxmigrate.migrate 201608301400:
+//
+//	options := *sqlxmigrate.DefaultOptions
+//	options.Tracer = otel.NewTracer(tracerProvider)
+package otel
+
+import (
+	"context"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+	global "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/geeks-accelerator/sqlxmigrate"
+
+// The attributes of the spans.
+const (
+	MigrationIDKey  = attribute.Key("sqlxmigrate.migration.id")
+	DirectionKey    = attribute.Key("sqlxmigrate.direction")
+	StatementsKey   = attribute.Key("sqlxmigrate.statements")
+	RowsAffectedKey = attribute.Key("sqlxmigrate.rows_affected")
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns the sqlxmigrate.Tracer starting the spans with the given
+// provider, the global one when nil.
+func NewTracer(provider trace.TracerProvider) sqlxmigrate.Tracer {
+	if provider == nil {
+		provider = global.GetTracerProvider()
+	}
+	return &tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// StartRun implements sqlxmigrate.Tracer.
+func (t *tracer) StartRun(ctx context.Context, direction sqlxmigrate.Direction) (context.Context, func(error)) {
+	ctx, span := t.tracer.Start(ctx, "sqlxmigrate."+string(direction),
+		trace.WithAttributes(DirectionKey.String(string(direction))))
+	return ctx, func(err error) {
+		recordError(span, err)
+		span.End()
+	}
+}
+
+// StartMigration implements sqlxmigrate.Tracer.
+func (t *tracer) StartMigration(ctx context.Context, migrationID string, direction sqlxmigrate.Direction) (context.Context, func(sqlxmigrate.Stats, error)) {
+	ctx, span := t.tracer.Start(ctx, "sqlxmigrate."+string(direction)+" "+migrationID,
+		trace.WithAttributes(MigrationIDKey.String(migrationID), DirectionKey.String(string(direction))))
+	return ctx, func(stats sqlxmigrate.Stats, err error) {
+		span.SetAttributes(StatementsKey.Int(stats.Statements), RowsAffectedKey.Int64(stats.RowsAffected))
+		recordError(span, err)
+		span.End()
+	}
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,48 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	errFailed := errors.New("failed")
+	ctx, endRun := tracer.StartRun(context.Background(), sqlxmigrate.DirectionMigrate)
+	_, endMigration := tracer.StartMigration(ctx, "201608301400", sqlxmigrate.DirectionMigrate)
+	endMigration(sqlxmigrate.Stats{Statements: 2, RowsAffected: 10}, nil)
+	_, endMigration = tracer.StartMigration(ctx, "201608301430", sqlxmigrate.DirectionMigrate)
+	endMigration(sqlxmigrate.Stats{}, errFailed)
+	endRun(errFailed)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	run := spans[2]
+	assert.Equal(t, "sqlxmigrate.migrate", run.Name())
+	assert.Equal(t, codes.Error, run.Status().Code)
+
+	assert.Equal(t, "sqlxmigrate.migrate 201608301400", spans[0].Name())
+	assert.Equal(t, run.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, []attribute.KeyValue{
+		MigrationIDKey.String("201608301400"),
+		DirectionKey.String("migrate"),
+		StatementsKey.Int(2),
+		RowsAffectedKey.Int64(10),
+	}, spans[0].Attributes())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	assert.Equal(t, "sqlxmigrate.migrate 201608301430", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "failed", spans[1].Status().Description)
+}
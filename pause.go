@@ -0,0 +1,69 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+)
+
+// Pause requests the current run, or the next one, to stop cleanly before
+// starting its next migration. The migrations already applied are committed
+// and the run returns ErrRunPaused. Pause is safe to call from any goroutine.
+func (g *Sqlxmigrate) Pause() {
+	atomic.StoreInt32(&g.pauseRequested, 1)
+}
+
+// PauseOnSignal pauses the runs when one of the signals is received, until
+// the returned function is called.
+func (g *Sqlxmigrate) PauseOnSignal(sig ...os.Signal) (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sig...)
+
+	go func() {
+		for {
+			select {
+			case <-c:
+				g.logf("Pause requested by signal")
+				g.Pause()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+// Resume continues the paused run up to the migration it targeted.
+func (g *Sqlxmigrate) Resume() (*Result, error) {
+	return g.ResumeContext(context.Background())
+}
+
+// ResumeContext is Resume with a context aborting the run when done.
+func (g *Sqlxmigrate) ResumeContext(ctx context.Context) (*Result, error) {
+	if g.pausedTarget == nil {
+		return nil, ErrNotPaused
+	}
+	migrationID := *g.pausedTarget
+
+	atomic.StoreInt32(&g.pauseRequested, 0)
+	g.pausedTarget = nil
+
+	return g.migrate(ctx, migrationID)
+}
+
+// shouldPause returns whether a pause was requested.
+func (g *Sqlxmigrate) shouldPause() (bool, error) {
+	if atomic.LoadInt32(&g.pauseRequested) == 1 {
+		return true, nil
+	}
+	if g.options.PauseFunc != nil {
+		return g.options.PauseFunc()
+	}
+	return false, nil
+}
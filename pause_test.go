@@ -0,0 +1,62 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseResume(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		var m *Sqlxmigrate
+
+		hotfix := &Migration{
+			ID: "201608301300",
+			Migrate: func(tx *sql.Tx) error {
+				// An operator requests a pause while the first migration runs.
+				m.Pause()
+				return nil
+			},
+			Rollback: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+
+		m = New(db, DefaultOptions, append([]*Migration{hotfix}, migrations...))
+
+		_, err := m.Resume()
+		assert.Equal(t, ErrNotPaused, err)
+
+		res, err := m.MigrateWithResult()
+		assert.Equal(t, ErrRunPaused, err)
+		require.Len(t, res.Migrations, 1)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, res.Pending)
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("people"))
+
+		res, err = m.Resume()
+		require.NoError(t, err)
+		require.Len(t, res.Migrations, 2)
+		assert.Empty(t, res.Pending)
+		assert.True(t, m.hasTable("pets"))
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestPauseFunc(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, &Options{
+			PauseFunc: func() (bool, error) {
+				return true, nil
+			},
+		}, migrations)
+
+		res, err := m.MigrateWithResult()
+		assert.Equal(t, ErrRunPaused, err)
+		assert.Empty(t, res.Migrations)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, res.Pending)
+	})
+}
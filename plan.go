@@ -0,0 +1,178 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PlannedMigration is a migration a run would apply.
+type PlannedMigration struct {
+	ID string
+	// Statements are the statements of the MigrateSQL of the migration. Nil
+	// for the migrations defined by a function, whose SQL isn't known before
+	// they run.
+	Statements []string
+	// DisableTransaction is set for the migrations run outside of the
+	// transaction of the run.
+	DisableTransaction bool
+}
+
+// Plan lists and logs, in order, the migrations a run would apply with the
+// statements they would execute, without changing anything, so DBAs can
+// review the changes before they are applied.
+func (g *Sqlxmigrate) Plan() ([]PlannedMigration, error) {
+	return g.plan(context.Background())
+}
+
+func (g *Sqlxmigrate) plan(ctx context.Context) ([]PlannedMigration, error) {
+	if !g.hasMigrations() {
+		return nil, ErrNoMigrationDefined
+	}
+
+	pending, err := g.pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]PlannedMigration, 0, len(pending))
+	for _, m := range pending {
+		step := PlannedMigration{ID: m.ID, DisableTransaction: m.DisableTransaction}
+		if m.MigrateTx == nil && m.MigrateContext == nil && m.Migrate == nil {
+			step.Statements = splitStatements(g.db.DriverName(), m.MigrateSQL)
+		}
+
+		if step.Statements == nil {
+			g.logf("Plan %s - function", m.ID)
+		}
+		for _, stmt := range step.Statements {
+			g.logf("Plan %s - %s", m.ID, stmt)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// RollbackStep is a migration that would be undone by a rollback.
+type RollbackStep struct {
+	ID string
+	// Reversible is false when the migration has no rollback, which makes
+	// the rollback fail with ErrRollbackImpossible when reaching it.
+	Reversible bool
+	// RollbackSQL is the stored down-SQL of the migration, if any.
+	RollbackSQL string
+}
+
+// RollbackPlan lists, in execution order, the migrations RollbackTo would undo
+// to get back to the migration that matches `migrationID`, without changing
+// anything, so operators can assess a rollback before running it.
+func (g *Sqlxmigrate) RollbackPlan(migrationID string) ([]RollbackStep, error) {
+	return g.rollbackPlan(context.Background(), migrationID)
+}
+
+func (g *Sqlxmigrate) rollbackPlan(ctx context.Context, migrationID string) ([]RollbackStep, error) {
+	if len(g.migrations) == 0 {
+		return nil, ErrNoMigrationDefined
+	}
+
+	if err := g.checkIDExist(migrationID); err != nil {
+		return nil, err
+	}
+
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migrationID = g.groupEnd(migrationID)
+	var steps []RollbackStep
+	for i := len(g.migrations) - 1; i >= 0; i-- {
+		m := g.migrations[i]
+		if m.ID == migrationID {
+			break
+		}
+		if _, ok := applied[m.ID]; !ok {
+			continue
+		}
+		steps = append(steps, RollbackStep{
+			ID:          m.ID,
+			Reversible:  m.hasRollback(),
+			RollbackSQL: m.RollbackSQL,
+		})
+	}
+	return steps, nil
+}
+
+// ExportRollbackScript writes a SQL script rolling back the migrations from
+// the one that matches `fromID` down to the one that matches `toID`, which is
+// not rolled back, as RollbackTo would. The script concatenates the stored
+// down-SQL of the migrations, with the delimiters they use, and the
+// statements removing them from the migration table, or marking them as
+// rolled back when Options.TombstoneRollbacks is set, for DBAs to review and
+// run when the Go binary can't be run against the database. Migrations
+// without down-SQL are flagged with a comment and kept in the migration
+// table.
+func (g *Sqlxmigrate) ExportRollbackScript(fromID, toID string, w io.Writer) error {
+	if err := g.checkIDExist(fromID); err != nil {
+		return err
+	}
+	if err := g.checkIDExist(toID); err != nil {
+		return err
+	}
+
+	var from, to int
+	for i, m := range g.migrations {
+		switch m.ID {
+		case fromID:
+			from = i
+		case toID:
+			to = i
+		}
+	}
+	if from <= to {
+		return ErrInvalidRollbackRange
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "-- Rollback from %s to %s generated by sqlxmigrate\n", fromID, toID)
+	fmt.Fprintf(b, "BEGIN;\n")
+
+	for i := from; i > to; i-- {
+		m := g.migrations[i]
+		fmt.Fprintf(b, "\n-- %s\n", m.ID)
+
+		if m.RollbackSQL == "" {
+			fmt.Fprintf(b, "-- WARNING: no down-SQL is stored for %s, it must be rolled back manually\n", m.ID)
+			continue
+		}
+
+		delimiter := ";"
+		for _, stmt := range splitScript(g.driverName(), m.RollbackSQL) {
+			if stmt.delimiter != delimiter {
+				delimiter = stmt.delimiter
+				fmt.Fprintf(b, "DELIMITER %s\n", delimiter)
+			}
+			fmt.Fprintf(b, "%s%s\n", stmt.text, delimiter)
+		}
+		if delimiter != ";" {
+			fmt.Fprintf(b, "DELIMITER ;\n")
+		}
+
+		id := strings.Replace(m.ID, "'", "''", -1)
+		switch {
+		case !g.usesTable():
+			fmt.Fprintf(b, "-- record the rollback of %s in the history store\n", m.ID)
+		case g.options.TombstoneRollbacks:
+			fmt.Fprintf(b, "UPDATE %s SET %s = CURRENT_TIMESTAMP WHERE %s = '%s' AND %[2]s IS NULL;\n",
+				g.options.TableName, rolledBackAtColumnName, g.options.IDColumnName, id)
+		default:
+			fmt.Fprintf(b, "DELETE FROM %s WHERE %s = '%s';\n", g.options.TableName, g.options.IDColumnName, id)
+		}
+	}
+
+	fmt.Fprintf(b, "\nCOMMIT;\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,171 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackPlan(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		irreversible := &Migration{
+			ID: "201608301500",
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+		downSQL := &Migration{
+			ID: "201608301600",
+			Migrate: func(tx *sql.Tx) error {
+				_, err := tx.Exec(`CREATE TABLE "cars" ("id" serial, PRIMARY KEY ("id"))`)
+				return err
+			},
+			RollbackSQL: `DROP TABLE "cars"`,
+		}
+		pending := &Migration{
+			ID: "201608301700",
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+
+		m := New(db, DefaultOptions, append(migrations, irreversible, downSQL, pending))
+		require.NoError(t, m.MigrateTo("201608301600"))
+
+		steps, err := m.RollbackPlan("201608301400")
+		require.NoError(t, err)
+		assert.Equal(t, []RollbackStep{
+			{ID: "201608301600", Reversible: true, RollbackSQL: `DROP TABLE "cars"`},
+			{ID: "201608301500", Reversible: false},
+			{ID: "201608301430", Reversible: true},
+		}, steps)
+
+		_, err = m.RollbackPlan("1234")
+		assert.Equal(t, ErrMigrationIDDoesNotExist, err)
+
+		// The stored down-SQL is executed on rollback.
+		require.NoError(t, m.RollbackLast())
+		assert.False(t, m.hasTable("cars"))
+	})
+}
+
+func TestExportRollbackScript(t *testing.T) {
+	m := New(nil, DefaultOptions, []*Migration{
+		{ID: "201608301400", RollbackSQL: `DROP TABLE "people"`},
+		{ID: "201608301430", RollbackSQL: "DROP TABLE \"pets\";\nDROP TABLE \"toys\";"},
+		{ID: "201608301500"},
+		{ID: "201608301600", RollbackSQL: `DROP TABLE "cars"`},
+	})
+
+	b := &strings.Builder{}
+	require.NoError(t, m.ExportRollbackScript("201608301600", "201608301400", b))
+	assert.Equal(t, `-- Rollback from 201608301600 to 201608301400 generated by sqlxmigrate
+BEGIN;
+
+-- 201608301600
+DROP TABLE "cars";
+DELETE FROM migrations WHERE id = '201608301600';
+
+-- 201608301500
+-- WARNING: no down-SQL is stored for 201608301500, it must be rolled back manually
+
+-- 201608301430
+DROP TABLE "pets";
+DROP TABLE "toys";
+DELETE FROM migrations WHERE id = '201608301430';
+
+COMMIT;
+`, b.String())
+
+	assert.Equal(t, ErrInvalidRollbackRange, m.ExportRollbackScript("201608301400", "201608301600", b))
+	assert.Equal(t, ErrMigrationIDDoesNotExist, m.ExportRollbackScript("1234", "201608301400", b))
+}
+
+func TestExportRollbackScriptTombstones(t *testing.T) {
+	options := *DefaultOptions
+	options.TombstoneRollbacks = true
+	m := New(nil, &options, []*Migration{
+		{ID: "201608301400", RollbackSQL: `DROP TABLE "people"`},
+		{ID: "201608301430", RollbackSQL: `DROP TABLE "pets"`},
+	})
+
+	b := &strings.Builder{}
+	require.NoError(t, m.ExportRollbackScript("201608301430", "201608301400", b))
+	assert.Equal(t, `-- Rollback from 201608301430 to 201608301400 generated by sqlxmigrate
+BEGIN;
+
+-- 201608301430
+DROP TABLE "pets";
+UPDATE migrations SET rolled_back_at = CURRENT_TIMESTAMP WHERE id = '201608301430' AND rolled_back_at IS NULL;
+
+COMMIT;
+`, b.String())
+}
+
+func TestExportRollbackScriptDelimiters(t *testing.T) {
+	m := New(nil, DefaultOptions, []*Migration{
+		{ID: "201608301400", RollbackSQL: `DROP TABLE people`},
+		{ID: "201608301430", RollbackSQL: `DROP TRIGGER IF EXISTS pets_count;
+DELIMITER //
+CREATE TRIGGER pets_count AFTER INSERT ON pets FOR EACH ROW
+BEGIN
+	UPDATE people SET pets = pets + 1 WHERE id = NEW.owner_id;
+END//
+DELIMITER ;
+DROP TABLE toys;`},
+	})
+
+	b := &strings.Builder{}
+	require.NoError(t, m.ExportRollbackScript("201608301430", "201608301400", b))
+	assert.Equal(t, `-- Rollback from 201608301430 to 201608301400 generated by sqlxmigrate
+BEGIN;
+
+-- 201608301430
+DROP TRIGGER IF EXISTS pets_count;
+DELIMITER //
+CREATE TRIGGER pets_count AFTER INSERT ON pets FOR EACH ROW
+BEGIN
+	UPDATE people SET pets = pets + 1 WHERE id = NEW.owner_id;
+END//
+DELIMITER ;
+DROP TABLE toys;
+DELETE FROM migrations WHERE id = '201608301430';
+
+COMMIT;
+`, b.String())
+}
+
+func TestPlan(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		scripted := &Migration{
+			ID:          "201608301500",
+			MigrateSQL:  "CREATE TABLE cars (id INTEGER PRIMARY KEY);\nINSERT INTO cars (id) VALUES (1);",
+			RollbackSQL: "DROP TABLE cars",
+		}
+		m := New(db, DefaultOptions, append(append([]*Migration{}, migrations...), scripted))
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		steps, err := m.Plan()
+		require.NoError(t, err)
+		assert.Equal(t, []PlannedMigration{
+			{ID: "201608301430"},
+			{ID: "201608301500", Statements: []string{
+				"CREATE TABLE cars (id INTEGER PRIMARY KEY)",
+				"INSERT INTO cars (id) VALUES (1)",
+			}},
+		}, steps)
+		assert.False(t, m.hasTable("pets"))
+		assert.False(t, m.hasTable("cars"))
+
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 1, tableCount(t, db, "cars"))
+
+		steps, err = m.Plan()
+		require.NoError(t, err)
+		assert.Empty(t, steps)
+	})
+}
@@ -0,0 +1,170 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Statement is a statement of the SQL script of a migration, as checked by
+// the policies of Options.Policies.
+type Statement struct {
+	MigrationID string
+	// SQL is the statement without its comments, with single spaces.
+	SQL string
+	// Kind is the upper cased leading keywords of a statement creating,
+	// altering or dropping an object, ie. "CREATE TABLE" or "DROP INDEX",
+	// else its first keyword.
+	Kind string
+	// Object is the name of the object created, altered or dropped, without
+	// quotes, ie. "billing.invoices". Empty when not found.
+	Object string
+}
+
+// Policy checks a statement of the SQL script of a migration, ie. for the
+// governance rules of an organization. It returns an error describing the
+// violation, nil when the statement is allowed.
+type Policy func(Statement) error
+
+// PolicyViolationError is returned by Validate when a statement violates a
+// policy of Options.Policies.
+type PolicyViolationError struct {
+	MigrationID string
+	Statement   string
+	Err         error
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migration "%s" violates a policy: %v, in "%s"`, e.MigrationID, e.Err, e.Statement)
+}
+
+func (e *PolicyViolationError) Unwrap() error {
+	return e.Err
+}
+
+var objectRe = regexp.MustCompile(`(?i)^(CREATE|ALTER|DROP)(?: OR REPLACE)?(?: UNIQUE| TEMP| TEMPORARY| UNLOGGED| MATERIALIZED| EVENT)* (TABLE|VIEW|INDEX|SEQUENCE|SCHEMA|FUNCTION|PROCEDURE|TRIGGER|TYPE|DOMAIN|EXTENSION|ROLE|USER|DATABASE)(?: CONCURRENTLY)?(?: IF (?:NOT )?EXISTS)?(?: ([^\s(;]+))?`)
+
+// parseStatements returns the statements of the SQL script of a migration
+// for the given database driver.
+func parseStatements(driverName, migrationID, script string) []Statement {
+	var stmts []Statement
+	for _, sql := range splitStatements(driverName, script) {
+		s := Statement{MigrationID: migrationID, SQL: compactStatement(sql)}
+		if m := objectRe.FindStringSubmatch(s.SQL); m != nil {
+			s.Kind = strings.ToUpper(m[1] + " " + m[2])
+			s.Object = unquoteName(m[3])
+		} else if fields := strings.Fields(s.SQL); len(fields) > 0 {
+			s.Kind = strings.ToUpper(fields[0])
+		}
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// unquoteName removes the quotes of the parts of an object name.
+func unquoteName(name string) string {
+	return strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "").Replace(name)
+}
+
+// checkPolicies checks the SQL scripts of the migrations against
+// Options.Policies.
+func (g *Sqlxmigrate) checkPolicies() error {
+	if len(g.options.Policies) == 0 {
+		return nil
+	}
+	for _, m := range g.migrations {
+		for _, script := range []string{m.MigrateSQL, m.RollbackSQL} {
+			for _, stmt := range parseStatements(g.driverName(), m.ID, script) {
+				for _, policy := range g.options.Policies {
+					if err := policy(stmt); err != nil {
+						return &PolicyViolationError{MigrationID: m.ID, Statement: stmt.SQL, Err: err}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AllowSchemas returns a policy denying the creation of objects qualified
+// with a schema outside of the given ones. The unqualified objects are
+// created in the current schema and allowed.
+func AllowSchemas(schemas ...string) Policy {
+	allowed := make(map[string]struct{}, len(schemas))
+	for _, s := range schemas {
+		allowed[strings.ToLower(s)] = struct{}{}
+	}
+	return func(s Statement) error {
+		if !strings.HasPrefix(s.Kind, "CREATE ") || s.Kind == "CREATE SCHEMA" {
+			return nil
+		}
+		i := strings.LastIndex(s.Object, ".")
+		if i < 0 {
+			return nil
+		}
+		schema := s.Object[:i]
+		if _, ok := allowed[strings.ToLower(schema)]; !ok {
+			return fmt.Errorf(`schema "%s" isn't allowed`, schema)
+		}
+		return nil
+	}
+}
+
+// superuserRule matches the statements starting with a prefix and containing
+// all the given keywords.
+type superuserRule struct {
+	prefix   string
+	contains []string
+}
+
+// superuserRules are the statements requiring a superuser, or a privileged
+// account, on PostgreSQL and MySQL.
+var superuserRules = []superuserRule{
+	{"ALTER SYSTEM", nil},
+	{"CREATE ROLE", []string{" SUPERUSER"}},
+	{"ALTER ROLE", []string{" SUPERUSER"}},
+	{"CREATE USER", []string{" SUPERUSER"}},
+	{"ALTER USER", []string{" SUPERUSER"}},
+	{"CREATE EVENT TRIGGER", nil},
+	{"CREATE LANGUAGE", nil},
+	{"CREATE OR REPLACE LANGUAGE", nil},
+	{"COPY", []string{" PROGRAM "}},
+	{"LOAD", nil},
+	{"SET GLOBAL", nil},
+	{"SET PERSIST", nil},
+	{"INSTALL PLUGIN", nil},
+	{"GRANT ALL", []string{" *.* "}},
+	{"GRANT SUPER", nil},
+}
+
+// DenySuperuser returns a policy denying the statements requiring a
+// superuser, ie. ALTER SYSTEM or COPY ... FROM PROGRAM, which the migrations
+// shouldn't rely on.
+func DenySuperuser() Policy {
+	return func(s Statement) error {
+		stmt := strings.ToUpper(s.SQL)
+		padded := " " + stmt + " "
+	rules:
+		for _, r := range superuserRules {
+			if !strings.HasPrefix(stmt, r.prefix) {
+				continue
+			}
+			for _, c := range r.contains {
+				if !strings.Contains(padded, c) {
+					continue rules
+				}
+			}
+			return fmt.Errorf("%s requires a superuser", r.prefix)
+		}
+		return nil
+	}
+}
+
+// IndexNaming returns a policy requiring the names of the indexes created to
+// follow a template, where <table> is replaced by the name of the table and
+// <cols> by the names of the columns joined by underscores, ie.
+// "idx_<table>_<cols>". The indexes on expressions are allowed. See
+// NamingConvention for the constraints.
+func IndexNaming(template string) Policy {
+	return NamingConvention{Index: template, UniqueIndex: template}.Policy()
+}
@@ -0,0 +1,87 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatements(t *testing.T) {
+	stmts := parseStatements("postgres", "201906121200", `
+		-- the invoices
+		CREATE TABLE IF NOT EXISTS "billing"."invoices" (id BIGINT PRIMARY KEY);
+		CREATE UNIQUE INDEX CONCURRENTLY idx_invoices_id ON billing.invoices (id);
+		ALTER SYSTEM SET work_mem = '64MB';
+		INSERT INTO billing.invoices VALUES (1)`)
+
+	assert.Equal(t, []Statement{
+		{MigrationID: "201906121200", SQL: `CREATE TABLE IF NOT EXISTS "billing"."invoices" (id BIGINT PRIMARY KEY)`, Kind: "CREATE TABLE", Object: "billing.invoices"},
+		{MigrationID: "201906121200", SQL: "CREATE UNIQUE INDEX CONCURRENTLY idx_invoices_id ON billing.invoices (id)", Kind: "CREATE INDEX", Object: "idx_invoices_id"},
+		{MigrationID: "201906121200", SQL: "ALTER SYSTEM SET work_mem = '64MB'", Kind: "ALTER"},
+		{MigrationID: "201906121200", SQL: "INSERT INTO billing.invoices VALUES (1)", Kind: "INSERT"},
+	}, stmts)
+}
+
+func TestPolicies(t *testing.T) {
+	schemas := AllowSchemas("public", "billing")
+	superuser := DenySuperuser()
+	naming := IndexNaming("idx_<table>_<cols>")
+
+	for _, tt := range []struct {
+		policy Policy
+		sql    string
+		err    string
+	}{
+		{schemas, "CREATE TABLE billing.invoices (id BIGINT)", ""},
+		{schemas, "CREATE TABLE invoices (id BIGINT)", ""},
+		{schemas, `CREATE TABLE "Public".invoices (id BIGINT)`, ""},
+		{schemas, "CREATE TABLE audit.invoices (id BIGINT)", `schema "audit" isn't allowed`},
+		{schemas, "CREATE VIEW audit.recent AS SELECT 1", `schema "audit" isn't allowed`},
+		{schemas, "DROP TABLE audit.invoices", ""},
+		{superuser, "ALTER SYSTEM SET work_mem = '64MB'", "ALTER SYSTEM requires a superuser"},
+		{superuser, "CREATE ROLE admin WITH SUPERUSER LOGIN", "CREATE ROLE requires a superuser"},
+		{superuser, "CREATE ROLE reader LOGIN", ""},
+		{superuser, "COPY people FROM PROGRAM 'curl example.com'", "COPY requires a superuser"},
+		{superuser, "COPY people FROM STDIN", ""},
+		{superuser, "SET GLOBAL max_connections = 500", "SET GLOBAL requires a superuser"},
+		{naming, "CREATE INDEX idx_people_name ON people (name)", ""},
+		{naming, `CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS "idx_people_last_name_first_name" ON public.people USING btree ("last_name", first_name DESC)`, ""},
+		{naming, "CREATE INDEX people_name ON people (name)", `index "people_name" should be named "idx_people_name"`},
+		{naming, "CREATE INDEX people_lower_name ON people (lower(name))", ""},
+		{naming, "CREATE TABLE people (id BIGINT)", ""},
+	} {
+		stmts := parseStatements("postgres", "201906121200", tt.sql)
+		require.Len(t, stmts, 1)
+		err := tt.policy(stmts[0])
+		if tt.err == "" {
+			assert.NoError(t, err, tt.sql)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.sql)
+		}
+	}
+}
+
+func TestValidatePolicies(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		noop := func(tx *sql.Tx) error {
+			return nil
+		}
+
+		options := *DefaultOptions
+		options.Policies = []Policy{AllowSchemas("public"), IndexNaming("idx_<table>_<cols>")}
+		m := New(db, &options, []*Migration{
+			{ID: "201906121200", MigrateSQL: "CREATE TABLE public.people (name TEXT)", RollbackSQL: "DROP TABLE public.people"},
+			{ID: "201906121215", Migrate: noop},
+			{ID: "201906121230", MigrateSQL: "CREATE INDEX people_name ON people (name)"},
+		})
+
+		err := m.Validate()
+		require.IsType(t, &PolicyViolationError{}, err)
+		assert.EqualError(t, err, `sqlxmigrate: Migration "201906121230" violates a policy: index "people_name" should be named "idx_people_name", in "CREATE INDEX people_name ON people (name)"`)
+		assert.NotNil(t, errors.Unwrap(err))
+	})
+}
@@ -0,0 +1,104 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"sync"
+)
+
+// PoolerMode defines whether the database is reached through a transaction
+// pooler, ie. PgBouncer in transaction pooling mode, RDS Proxy or the Cloud
+// SQL connector, which can run each transaction on a different server
+// connection.
+type PoolerMode int
+
+const (
+	// PoolerDetect detects the pooler when a feature relies on the session,
+	// by checking whether consecutive statements of a connection reach the
+	// same server connection. The detection is best effort: a pooler that
+	// happens to reuse the server connection isn't detected.
+	PoolerDetect PoolerMode = iota
+	// PoolerNone uses session level features.
+	PoolerNone
+	// PoolerTransaction avoids session level features, ie. session advisory
+	// locks, only relying on what is scoped to a transaction.
+	PoolerTransaction
+)
+
+// poolerProbes is the number of statements compared by the detection.
+const poolerProbes = 3
+
+// pooler caches the detection of the pooler. A failed detection isn't
+// cached, ie. when the context of the caller was canceled, the next call
+// detects the pooler again.
+type pooler struct {
+	mu       sync.Mutex
+	detected bool
+	pooled   bool
+}
+
+// BehindPooler reports whether the migrations run through a transaction
+// pooler, in which case the features relying on the session are replaced by
+// their transaction scoped alternative. It is set by Options.Pooler, or
+// detected on PostgreSQL and MySQL until a detection succeeds.
+func (g *Sqlxmigrate) BehindPooler(ctx context.Context) (bool, error) {
+	switch g.options.Pooler {
+	case PoolerNone:
+		return false, nil
+	case PoolerTransaction:
+		return true, nil
+	}
+
+	g.pooler.mu.Lock()
+	defer g.pooler.mu.Unlock()
+	if g.pooler.detected {
+		return g.pooler.pooled, nil
+	}
+
+	pooled, err := g.detectPooler(ctx)
+	if err != nil {
+		return false, err
+	}
+	if pooled {
+		g.logf("BehindPooler - transaction pooler detected, session level features are disabled")
+	}
+	g.pooler.detected, g.pooler.pooled = true, pooled
+	return pooled, nil
+}
+
+// detectPooler checks whether consecutive statements of a connection are
+// served by different server connections.
+func (g *Sqlxmigrate) detectPooler(ctx context.Context) (bool, error) {
+	var query string
+	switch {
+	case g.isPostgres():
+		query = "SELECT pg_backend_pid()"
+	case g.isMySQL():
+		query = "SELECT CONNECTION_ID()"
+	case g.isSQLServer():
+		query = "SELECT @@SPID"
+	default:
+		return false, nil
+	}
+	g.logf("detectPooler %s", query)
+
+	conn, err := g.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var first int64
+	for i := 0; i < poolerProbes; i++ {
+		var id int64
+		if err := conn.QueryRowContext(ctx, query).Scan(&id); err != nil {
+			err = queryError(err, query)
+			return false, err
+		}
+		if i == 0 {
+			first = id
+		} else if id != first {
+			return true, nil
+		}
+	}
+	return false, nil
+}
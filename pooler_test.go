@@ -0,0 +1,49 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBehindPoolerOption(t *testing.T) {
+	for mode, want := range map[PoolerMode]bool{PoolerNone: false, PoolerTransaction: true} {
+		options := *DefaultOptions
+		options.Pooler = mode
+		m := New(sqlx.NewDb(nil, "postgres"), &options, migrations)
+
+		pooled, err := m.BehindPooler(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, want, pooled)
+	}
+}
+
+func TestBehindPoolerDetect(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+
+		pooled, err := m.BehindPooler(context.Background())
+		require.NoError(t, err)
+		assert.False(t, pooled)
+	})
+}
+
+func TestBehindPoolerDetectRetried(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+
+		// The failure of the detection isn't cached.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := m.BehindPooler(ctx)
+		assert.True(t, errors.Is(err, context.Canceled))
+
+		pooled, err := m.BehindPooler(context.Background())
+		require.NoError(t, err)
+		assert.False(t, pooled)
+	}, "postgres", "mysql")
+}
@@ -0,0 +1,196 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PruneResult lists what PruneHistory deletes from the bookkeeping tables.
+type PruneResult struct {
+	// Tombstones are the IDs of the rolled back migrations whose row is
+	// deleted from the migration table, see Options.TombstoneRollbacks.
+	Tombstones []string
+	// AuditRows is the number of rows deleted from the audit table.
+	AuditRows int64
+}
+
+// PruneHistory bounds the size of the bookkeeping tables: it deletes the
+// tombstones of the migrations rolled back before `olderThan` from the
+// migration table, and the rows of the audit table executed before it. The
+// `keepLast` most recent tombstones and audit rows are kept whatever their
+// age. The rows of the applied migrations are never deleted.
+func (g *Sqlxmigrate) PruneHistory(olderThan time.Time, keepLast int) (*PruneResult, error) {
+	ctx := context.Background()
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	res, cutoff, err := g.prunable(ctx, olderThan, keepLast)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Tombstones) == 0 && res.AuditRows == 0 {
+		return res, nil
+	}
+
+	if err := g.begin(ctx); err != nil {
+		return nil, err
+	}
+	defer g.rollback()
+
+	for _, id := range res.Tombstones {
+		g.logf("PruneHistory %s - removing its tombstone", id)
+
+		sql := g.removeSQL()
+		if _, err := g.tx.ExecContext(ctx, sql, id); err != nil {
+			err = queryError(err, sql)
+			return nil, err
+		}
+	}
+
+	if res.AuditRows > 0 {
+		sql := fmt.Sprintf("DELETE FROM %s WHERE executed_at < ?", g.options.AuditTableName)
+		sql = g.rebind(sql)
+		g.logf("PruneHistory %s", sql)
+
+		if _, err := g.tx.ExecContext(ctx, sql, cutoff); err != nil {
+			err = queryError(err, sql)
+			return nil, err
+		}
+	}
+
+	if err := g.commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PrunePlan returns what PruneHistory would delete, without changing
+// anything.
+func (g *Sqlxmigrate) PrunePlan(olderThan time.Time, keepLast int) (*PruneResult, error) {
+	res, _, err := g.prunable(context.Background(), olderThan, keepLast)
+	return res, err
+}
+
+// prunable returns what PruneHistory deletes and the time before which the
+// rows of the audit table are deleted.
+func (g *Sqlxmigrate) prunable(ctx context.Context, olderThan time.Time, keepLast int) (*PruneResult, time.Time, error) {
+	res := &PruneResult{}
+
+	tombstones, err := g.prunableTombstones(ctx, olderThan, keepLast)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	res.Tombstones = tombstones
+
+	cutoff, err := g.auditCutoff(ctx, olderThan, keepLast)
+	if err != nil || cutoff.IsZero() {
+		return res, cutoff, err
+	}
+
+	query := fmt.Sprintf("SELECT count(*) FROM %s WHERE executed_at < ?", g.options.AuditTableName)
+	query = g.rebind(query)
+	g.logf("PruneHistory %s", query)
+
+	if err := g.db.QueryRowContext(ctx, query, cutoff).Scan(&res.AuditRows); err != nil {
+		err = queryError(err, query)
+		return nil, time.Time{}, err
+	}
+	return res, cutoff, nil
+}
+
+// prunableTombstones returns the IDs of the tombstones rolled back before
+// `olderThan`, except the `keepLast` most recent ones.
+func (g *Sqlxmigrate) prunableTombstones(ctx context.Context, olderThan time.Time, keepLast int) ([]string, error) {
+	if !g.usesTable() {
+		return nil, nil
+	}
+	if version, err := g.tableVersion(ctx); version < 4 || err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %[1]s, %[2]s FROM %[3]s WHERE %[2]s IS NOT NULL ORDER BY %[2]s DESC, %[1]s DESC",
+		g.options.IDColumnName, rolledBackAtColumnName, g.options.TableName)
+
+	var ids []string
+	kept := 0
+	err := g.inspectRows(ctx, query, func(rows *sql.Rows) error {
+		var id string
+		var rolledBackAt interface{}
+		if err := rows.Scan(&id, &rolledBackAt); err != nil {
+			return err
+		}
+		t, err := parseTimestamp(rolledBackAt)
+		if err != nil {
+			return err
+		}
+
+		if kept < keepLast || !t.Before(olderThan) {
+			kept++
+			return nil
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// auditCutoff returns the time before which the rows of the audit table are
+// deleted: `olderThan`, or the time of the oldest of the `keepLast` most
+// recent rows when it is before. Zero when the audit is disabled or nothing
+// is deleted.
+func (g *Sqlxmigrate) auditCutoff(ctx context.Context, olderThan time.Time, keepLast int) (time.Time, error) {
+	if g.options.AuditTableName == "" {
+		return time.Time{}, nil
+	}
+	if ok, err := g.tableExists(ctx, g.options.AuditTableName); !ok || err != nil {
+		return time.Time{}, err
+	}
+
+	cutoff := olderThan.UTC()
+	if keepLast <= 0 {
+		return cutoff, nil
+	}
+
+	query := fmt.Sprintf("SELECT executed_at FROM %s WHERE executed_at IS NOT NULL ORDER BY executed_at DESC", g.options.AuditTableName)
+	g.logf("PruneHistory %s", query)
+
+	rows, err := g.db.QueryContext(ctx, query)
+	if err != nil {
+		err = queryError(err, query)
+		return time.Time{}, err
+	}
+	defer rows.Close()
+
+	// Only the most recent rows kept are read.
+	for n := 0; n < keepLast; n++ {
+		if !rows.Next() {
+			// Fewer rows than kept, nothing is deleted.
+			return time.Time{}, rows.Err()
+		}
+		if n < keepLast-1 {
+			continue
+		}
+
+		var executedAt interface{}
+		if err := rows.Scan(&executedAt); err != nil {
+			return time.Time{}, err
+		}
+		t, err := parseTimestamp(executedAt)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if t.Before(cutoff) {
+			cutoff = *t
+		}
+	}
+	return cutoff, rows.Err()
+}
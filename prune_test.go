@@ -0,0 +1,60 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneHistory(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		require.NoError(t, dropTableIfExists(db, "migration_audit"))
+		options := *DefaultOptions
+		options.TombstoneRollbacks = true
+		options.AuditTableName = "migration_audit"
+		m := New(db, &options, extendedMigrations)
+
+		require.NoError(t, m.Migrate())
+		require.NoError(t, m.RollbackTo("201608301400"))
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+		assert.Equal(t, 5, tableCount(t, db, "migration_audit"))
+
+		// Nothing is older than an hour ago.
+		res, err := m.PrunePlan(time.Now().Add(-time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, &PruneResult{}, res)
+
+		future := time.Now().Add(time.Hour)
+		res, err = m.PrunePlan(future, 1)
+		require.NoError(t, err)
+		assert.Len(t, res.Tombstones, 1)
+		assert.True(t, res.AuditRows < 5)
+
+		res, err = m.PrunePlan(future, 0)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"201608301430", "201807221927"}, res.Tombstones)
+		assert.Equal(t, int64(5), res.AuditRows)
+
+		// The plan doesn't change anything.
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+		assert.Equal(t, 5, tableCount(t, db, "migration_audit"))
+
+		res, err = m.PruneHistory(future, 0)
+		require.NoError(t, err)
+		assert.Len(t, res.Tombstones, 2)
+		assert.Equal(t, int64(5), res.AuditRows)
+
+		// The applied migration is kept.
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+		assert.Equal(t, 0, tableCount(t, db, "migration_audit"))
+		ids, err := m.appliedIDs(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400"}, ids)
+
+		require.NoError(t, dropTableIfExists(db, "migration_audit"))
+	})
+}
@@ -0,0 +1,114 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// readiness is the state of the status path of the readiness checks,
+// prepared once.
+type readiness struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	query string
+	stmt  *sql.Stmt
+}
+
+// UpToDate reports whether every migration is applied. It is meant for
+// readiness probes: it runs a single statement, prepared once, without
+// logging.
+func (g *Sqlxmigrate) UpToDate() (bool, error) {
+	n, err := g.PendingCount()
+	return n == 0, err
+}
+
+// PendingCount returns the number of migrations that did not run yet, with
+// the cost of UpToDate.
+func (g *Sqlxmigrate) PendingCount() (int, error) {
+	return g.pendingCountPrepared(context.Background())
+}
+
+func (g *Sqlxmigrate) pendingCountPrepared(ctx context.Context) (int, error) {
+	if g.options.CacheAppliedIDs || !g.usesTable() {
+		return g.pendingCount(ctx)
+	}
+
+	r := &g.readiness
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ids == nil {
+		r.ids = make(map[string]struct{}, len(g.migrations))
+		for _, m := range g.migrations {
+			r.ids[m.ID] = struct{}{}
+		}
+		r.query = fmt.Sprintf("SELECT %s FROM %s", g.options.IDColumnName, g.options.TableName)
+		if g.options.TombstoneRollbacks {
+			r.query += fmt.Sprintf(" WHERE %s IS NULL", rolledBackAtColumnName)
+		}
+	}
+
+	rows, err := g.readinessRows(ctx)
+	if err != nil {
+		// Nothing ran yet when the migration table doesn't exist.
+		if ok, herr := g.tableExists(ctx, g.options.TableName); herr == nil && !ok {
+			return len(r.ids), nil
+		}
+		err = queryError(err, r.query)
+		return 0, err
+	}
+	defer rows.Close()
+
+	// The IDs are scanned in a reused buffer and looked up without
+	// conversion, so counting doesn't allocate for each row.
+	var id sql.RawBytes
+	applied := 0
+	for rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		if _, ok := r.ids[string(id)]; ok {
+			applied++
+		}
+	}
+	return len(r.ids) - applied, rows.Err()
+}
+
+// readinessRows queries the applied IDs with the prepared statement, or
+// without it behind a transaction pooler, which doesn't keep the statements
+// prepared by a client.
+func (g *Sqlxmigrate) readinessRows(ctx context.Context) (*sql.Rows, error) {
+	r := &g.readiness
+
+	pooled, err := g.BehindPooler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pooled {
+		return g.db.QueryContext(ctx, r.query)
+	}
+
+	if r.stmt == nil {
+		stmt, err := g.db.PrepareContext(ctx, r.query)
+		if err != nil {
+			return nil, err
+		}
+		r.stmt = stmt
+	}
+
+	rows, err := r.stmt.QueryContext(ctx)
+	if err != nil {
+		// Prepared again by the next call, ie. once the table is created.
+		r.stmt.Close()
+		r.stmt = nil
+	}
+	return rows, err
+}
+
+// pendingCount counts the pending migrations from the history.
+func (g *Sqlxmigrate) pendingCount(ctx context.Context) (int, error) {
+	pending, err := g.pending(ctx)
+	return len(pending), err
+}
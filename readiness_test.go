@@ -0,0 +1,68 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingCount(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+
+		n, err := m.PendingCount()
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		require.NoError(t, m.MigrateTo("201608301430"))
+		n, err = m.PendingCount()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		// IDs that aren't migrations aren't counted.
+		_, err = db.Exec("INSERT INTO migrations (id) VALUES ('201501011200')")
+		require.NoError(t, err)
+		n, err = m.PendingCount()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		require.NoError(t, m.Migrate())
+		ok, err := m.UpToDate()
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
+
+func TestPendingCountPooled(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.Pooler = PoolerTransaction
+		m := New(db, &options, migrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		n, err := m.PendingCount()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Nil(t, m.readiness.stmt)
+	})
+}
+
+func TestUpToDateCached(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.CacheAppliedIDs = true
+		m := New(db, &options, migrations)
+		defer m.InvalidateCache()
+
+		ok, err := m.UpToDate()
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		require.NoError(t, m.Migrate())
+		ok, err = m.UpToDate()
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,254 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RebuildVerificationError is returned when the rebuilt table doesn't match
+// the original table once backfilled.
+type RebuildVerificationError struct {
+	Table  string
+	Reason string
+}
+
+func (e *RebuildVerificationError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Rebuild of table "%s" failed verification: %s`, e.Table, e.Reason)
+}
+
+// Hint implements Hinter.
+func (e *RebuildVerificationError) Hint() string {
+	return "The original table is untouched. Check for writes bypassing the sync triggers, then run the backfill again before swapping the tables."
+}
+
+// TableRebuild rebuilds a table with a new definition while it keeps being
+// written to, ie. to change the type of a primary key on a huge table. It is
+// only supported by PostgreSQL.
+//
+// The rebuild is split in phases, each one a migration so it is tracked in
+// the migration table:
+//
+//  1. create the new table named <Table>_new
+//  2. install triggers copying the writes of the table to the new table
+//  3. backfill the new table in batches
+//  4. verify the row counts and checksums of both tables match
+//  5. swap the tables, the original one is kept as <Table>_old
+//
+// The backfill disables the transaction and commits each batch on its own:
+// the new table and its triggers are committed before it starts, and the
+// tables are only verified and swapped once it is done, in a transaction of
+// their own.
+//
+// Only the table is rebuilt: the defaults, sequences and indexes of the new
+// table must be part of its Definition.
+type TableRebuild struct {
+	// Table is the name of the table to rebuild.
+	Table string
+	// Definition is the definition of the new table, ie.
+	// "(id bigint PRIMARY KEY, name text)".
+	Definition string
+	// Columns are the columns copied to the new table.
+	Columns []string
+	// KeyColumn is the integer primary key used to backfill in batches.
+	KeyColumn string
+	// BatchSize is the number of rows copied per statement. Defaults to 1000.
+	BatchSize int
+}
+
+// Migrations returns the migrations of the phases of the rebuild. Their IDs
+// are the given ID suffixed with the phase, with consecutive timestamps
+// starting at the one of the given ID, ie. 201906121200_rebuild_people_create
+// then 201906121201_rebuild_people_sync.
+func (r *TableRebuild) Migrations(id string) ([]*Migration, error) {
+	ids, err := phaseIDs(id, "create", "sync", "backfill", "verify", "swap")
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Migration{
+		{
+			ID: ids[0],
+			MigrateTx: func(tx *Tx) error {
+				_, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s %s", r.newTable(), r.Definition))
+				return err
+			},
+			RollbackTx: func(tx *Tx) error {
+				_, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", r.newTable()))
+				return err
+			},
+		},
+		{
+			ID: ids[1],
+			MigrateTx: func(tx *Tx) error {
+				return tx.execScript(r.syncSQL())
+			},
+			RollbackTx: func(tx *Tx) error {
+				return tx.execScript(r.dropSyncSQL())
+			},
+		},
+		{
+			ID:                 ids[2],
+			DisableTransaction: true,
+			MigrateNoTx:        r.backfill,
+			RollbackTx: func(tx *Tx) error {
+				_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s", r.newTable()))
+				return err
+			},
+		},
+		{
+			ID:        ids[3],
+			MigrateTx: r.verify,
+			RollbackTx: func(tx *Tx) error {
+				return nil
+			},
+		},
+		{
+			ID: ids[4],
+			MigrateTx: func(tx *Tx) error {
+				return tx.execScript(r.swapSQL())
+			},
+			RollbackTx: func(tx *Tx) error {
+				return tx.execScript(r.unswapSQL())
+			},
+		},
+	}, nil
+}
+
+// phaseIDs returns the IDs of the phases of an operation spanning several
+// migrations, using consecutive timestamps so they don't conflict.
+func phaseIDs(id string, phases ...string) ([]string, error) {
+	prefix := timestampPrefix(id)
+	if prefix == "" {
+		return nil, ErrMissingTimestamp
+	}
+
+	ids := make([]string, 0, len(phases))
+	for _, phase := range phases {
+		ids = append(ids, prefix+id[len(prefix):]+"_"+phase)
+
+		var err error
+		if prefix, err = nextTimestamp(prefix); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func (r *TableRebuild) newTable() string {
+	return r.Table + "_new"
+}
+
+func (r *TableRebuild) oldTable() string {
+	return r.Table + "_old"
+}
+
+func (r *TableRebuild) syncName() string {
+	return r.Table + "_rebuild_sync"
+}
+
+// syncSQL creates the trigger copying the writes of the table to the new one.
+func (r *TableRebuild) syncSQL() string {
+	cols := strings.Join(r.Columns, ", ")
+
+	var values, updates []string
+	for _, c := range r.Columns {
+		values = append(values, "NEW."+c)
+		if c != r.KeyColumn {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+		}
+	}
+	conflict := "DO NOTHING"
+	if len(updates) > 0 {
+		conflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	return fmt.Sprintf(`CREATE FUNCTION %[1]s() RETURNS trigger AS $sync$
+BEGIN
+	IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND NEW.%[4]s <> OLD.%[4]s) THEN
+		DELETE FROM %[2]s WHERE %[4]s = OLD.%[4]s;
+	END IF;
+	IF TG_OP = 'DELETE' THEN
+		RETURN OLD;
+	END IF;
+	INSERT INTO %[2]s (%[5]s) VALUES (%[6]s) ON CONFLICT (%[4]s) %[7]s;
+	RETURN NEW;
+END
+$sync$ LANGUAGE plpgsql;
+CREATE TRIGGER %[1]s AFTER INSERT OR UPDATE OR DELETE ON %[3]s FOR EACH ROW EXECUTE PROCEDURE %[1]s();`,
+		r.syncName(), r.newTable(), r.Table, r.KeyColumn, cols, strings.Join(values, ", "), conflict)
+}
+
+func (r *TableRebuild) dropSyncSQL() string {
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS %[1]s ON %[2]s; DROP FUNCTION IF EXISTS %[1]s();", r.syncName(), r.Table)
+}
+
+// swapSQL replaces the table with the new one, keeping the original table.
+func (r *TableRebuild) swapSQL() string {
+	return fmt.Sprintf("LOCK TABLE %[1]s IN ACCESS EXCLUSIVE MODE; %[4]s ALTER TABLE %[1]s RENAME TO %[2]s; ALTER TABLE %[3]s RENAME TO %[1]s;",
+		r.Table, r.oldTable(), r.newTable(), r.dropSyncSQL())
+}
+
+func (r *TableRebuild) unswapSQL() string {
+	return fmt.Sprintf("LOCK TABLE %[1]s IN ACCESS EXCLUSIVE MODE; ALTER TABLE %[1]s RENAME TO %[3]s; ALTER TABLE %[2]s RENAME TO %[1]s; %[4]s",
+		r.Table, r.oldTable(), r.newTable(), r.syncSQL())
+}
+
+// backfill copies the rows of the table to the new table in batches ordered
+// by key, skipping the ones already copied by the triggers. Each batch is
+// committed on its own.
+func (r *TableRebuild) backfill(db *sqlx.DB) error {
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	cols := strings.Join(r.Columns, ", ")
+
+	boundQuery := fmt.Sprintf("SELECT max(%[1]s) FROM (SELECT %[1]s FROM %[2]s WHERE %[1]s > $1 ORDER BY %[1]s LIMIT %[3]d) b",
+		r.KeyColumn, r.Table, batchSize)
+	copyQuery := fmt.Sprintf("INSERT INTO %[1]s (%[2]s) SELECT %[2]s FROM %[3]s WHERE %[4]s > $1 AND %[4]s <= $2 ON CONFLICT (%[4]s) DO NOTHING",
+		r.newTable(), cols, r.Table, r.KeyColumn)
+
+	var last int64
+	if err := db.QueryRow(fmt.Sprintf("SELECT coalesce(min(%s), 0) - 1 FROM %s", r.KeyColumn, r.Table)).Scan(&last); err != nil {
+		return err
+	}
+
+	for {
+		var bound sql.NullInt64
+		if err := db.QueryRow(boundQuery, last).Scan(&bound); err != nil {
+			return err
+		}
+		if !bound.Valid {
+			return nil
+		}
+
+		if _, err := db.Exec(copyQuery, last, bound.Int64); err != nil {
+			return err
+		}
+		last = bound.Int64
+	}
+}
+
+// verify checks the new table has the same rows as the table.
+func (r *TableRebuild) verify(tx *Tx) error {
+	var counts [2]int64
+	var checksums [2]sql.NullString
+	for i, table := range []string{r.Table, r.newTable()} {
+		query := fmt.Sprintf("SELECT count(*), md5(string_agg(md5(ROW(%s)::text), '' ORDER BY %s)) FROM %s",
+			strings.Join(r.Columns, ", "), r.KeyColumn, table)
+		if err := tx.QueryRow(query).Scan(&counts[i], &checksums[i]); err != nil {
+			return err
+		}
+	}
+
+	if counts[0] != counts[1] {
+		return &RebuildVerificationError{Table: r.Table, Reason: fmt.Sprintf("%d rows in the table, %d in the new table", counts[0], counts[1])}
+	}
+	if checksums[0] != checksums[1] {
+		return &RebuildVerificationError{Table: r.Table, Reason: "checksums don't match"}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableRebuild(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		assert.NoError(t, dropTableIfExists(db, "people_new", "people_old"))
+
+		require.NoError(t, New(db, DefaultOptions, migrations[:1]).Migrate())
+		_, err := db.Exec(`INSERT INTO people (name) VALUES ('alice'), ('bob'), ('carol')`)
+		require.NoError(t, err)
+
+		rebuild := &TableRebuild{
+			Table:      "people",
+			Definition: "(id bigint PRIMARY KEY, name text)",
+			Columns:    []string{"id", "name"},
+			KeyColumn:  "id",
+			BatchSize:  2,
+		}
+		phases, err := rebuild.Migrations("201608301500_rebuild_people")
+		require.NoError(t, err)
+		require.Len(t, phases, 5)
+		assert.Equal(t, "201608301500_rebuild_people_create", phases[0].ID)
+		assert.Equal(t, "201608301504_rebuild_people_swap", phases[4].ID)
+
+		m := New(db, DefaultOptions, append(migrations[:1:1], phases[:2]...))
+		require.NoError(t, m.Validate())
+		require.NoError(t, m.Migrate())
+
+		// Writes made during the rebuild are copied by the triggers.
+		_, err = db.Exec(`INSERT INTO people (name) VALUES ('dave')`)
+		require.NoError(t, err)
+		_, err = db.Exec(`UPDATE people SET name = 'robert' WHERE name = 'bob'`)
+		require.NoError(t, err)
+		_, err = db.Exec(`DELETE FROM people WHERE name = 'alice'`)
+		require.NoError(t, err)
+
+		m = New(db, DefaultOptions, append(migrations[:1:1], phases...))
+		plan, err := m.Plan()
+		require.NoError(t, err)
+		require.Len(t, plan, 3)
+		assert.True(t, plan[0].DisableTransaction)
+
+		// A row missing from the table fails the verification: only the
+		// verification and the swap are rolled back, the backfill was
+		// committed before them.
+		_, err = db.Exec(`INSERT INTO people_new (id, name) VALUES (1000, 'stray')`)
+		require.NoError(t, err)
+		var verr *RebuildVerificationError
+		require.True(t, errors.As(m.Migrate(), &verr))
+		assert.Equal(t, 4, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("people_old"))
+		var copied int
+		require.NoError(t, db.Get(&copied, "SELECT count(*) FROM people_new"))
+		assert.Equal(t, 4, copied)
+
+		_, err = db.Exec(`DELETE FROM people_new WHERE id = 1000`)
+		require.NoError(t, err)
+		require.NoError(t, m.Migrate())
+
+		assert.True(t, m.hasTable("people_old"))
+		var names []string
+		require.NoError(t, db.Select(&names, "SELECT name FROM people ORDER BY id"))
+		assert.Equal(t, []string{"robert", "carol", "dave"}, names)
+
+		var dataType string
+		require.NoError(t, db.Get(&dataType, "SELECT data_type FROM information_schema.columns WHERE table_name = 'people' AND column_name = 'id'"))
+		assert.Equal(t, "bigint", dataType)
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+		assert.False(t, m.hasTable("people_new"))
+		assert.False(t, m.hasTable("people_old"))
+
+		assert.NoError(t, dropTableIfExists(db, "people_new", "people_old"))
+	}, "postgres")
+}
+
+func TestPhaseIDs(t *testing.T) {
+	ids, err := phaseIDs("201608301559_rebuild", "create", "swap")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"201608301559_rebuild_create", "201608301600_rebuild_swap"}, ids)
+
+	_, err = phaseIDs("rebuild", "create")
+	assert.Equal(t, ErrMissingTimestamp, err)
+}
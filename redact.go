@@ -0,0 +1,42 @@
+package sqlxmigrate
+
+import "regexp"
+
+// redacted replaces the statements matching Options.RedactStatements.
+const redacted = "[redacted]"
+
+// literalPattern matches the string literals, with their doubled quotes, and
+// the number literals of a statement.
+var literalPattern = regexp.MustCompile(`'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b`)
+
+// Redact returns a statement as it's logged: replaced when it matches one of
+// Options.RedactStatements, its literals masked with Options.RedactLiterals.
+// Use it to log the statements of a WrapTx middleware.
+func (g *Sqlxmigrate) Redact(statement string) string {
+	for _, re := range g.options.RedactStatements {
+		if re.MatchString(statement) {
+			return redacted
+		}
+	}
+	if g.options.RedactLiterals {
+		return literalPattern.ReplaceAllString(statement, "?")
+	}
+	return statement
+}
+
+// redactArgs returns the arguments of a log message with its statements
+// redacted.
+func (g *Sqlxmigrate) redactArgs(args []interface{}) []interface{} {
+	if !g.options.RedactLiterals && len(g.options.RedactStatements) == 0 {
+		return args
+	}
+
+	redactedArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(string); ok && isSQL(s) {
+			arg = g.Redact(s)
+		}
+		redactedArgs[i] = arg
+	}
+	return redactedArgs
+}
@@ -0,0 +1,60 @@
+package sqlxmigrate
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"regexp"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	options := *DefaultOptions
+	m := New(nil, &options, migrations)
+	stmt := `UPDATE people SET email = 'alice@example.com', age = 42 WHERE id = 7`
+	assert.Equal(t, stmt, m.Redact(stmt))
+
+	options.RedactLiterals = true
+	assert.Equal(t, `UPDATE people SET email = ?, age = ? WHERE id = ?`, m.Redact(stmt))
+	assert.Equal(t, `INSERT INTO people2 (name) VALUES (?)`, m.Redact(`INSERT INTO people2 (name) VALUES ('O''Brien')`))
+
+	options.RedactStatements = []*regexp.Regexp{regexp.MustCompile(`(?i)\bemail\b`)}
+	assert.Equal(t, "[redacted]", m.Redact(stmt))
+}
+
+func TestRedactLog(t *testing.T) {
+	var buf bytes.Buffer
+	options := *DefaultOptions
+	options.RedactLiterals = true
+	m := New(nil, &options, migrations)
+	m.SetLogger(log.New(&buf, "", 0))
+
+	m.logf("Plan %s - %s", "201608301400", "INSERT INTO people (name) VALUES ('alice')")
+	assert.Equal(t, "Plan 201608301400 - INSERT INTO people (name) VALUES (?)\n", buf.String())
+
+	// The arguments that aren't statements are kept.
+	buf.Reset()
+	m.logf("Migration %s - %s", "201608301400", "mark_applied")
+	assert.Equal(t, "Migration 201608301400 - mark_applied\n", buf.String())
+}
+
+func TestRedactMigrationError(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.RedactLiterals = true
+		m := New(db, &options, []*Migration{migrations[0], {
+			ID:          "201608301500",
+			MigrateSQL:  `INSERT INTO missing (email) VALUES ('alice@example.com')`,
+			RollbackSQL: `DELETE FROM missing`,
+		}})
+
+		var migrationErr *MigrationError
+		require.True(t, errors.As(m.Migrate(), &migrationErr))
+		assert.Equal(t, "INSERT INTO missing (email) VALUES (?)", migrationErr.Query)
+		assert.NotContains(t, migrationErr.Error(), "alice@example.com")
+	})
+}
@@ -0,0 +1,114 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+)
+
+// Redo rolls back the last applied migration and applies it again, in a
+// single transaction, which is the workflow when iterating on a migration
+// during development.
+func (g *Sqlxmigrate) Redo() error {
+	return g.RedoContext(context.Background())
+}
+
+// RedoContext is Redo with a context aborting it when done.
+func (g *Sqlxmigrate) RedoContext(ctx context.Context) error {
+	return g.redo(ctx, "")
+}
+
+// RedoTo rolls back the migrations applied after the one that matches
+// `migrationID` and applies them again, in a single transaction.
+func (g *Sqlxmigrate) RedoTo(migrationID string) error {
+	return g.RedoToContext(context.Background(), migrationID)
+}
+
+// RedoToContext is RedoTo with a context aborting it when done.
+func (g *Sqlxmigrate) RedoToContext(ctx context.Context, migrationID string) error {
+	if err := g.checkIDExist(migrationID); err != nil {
+		return err
+	}
+	return g.redo(ctx, migrationID)
+}
+
+// redo rolls back and applies again the migrations applied after the one
+// that matches `migrationID`, the last applied migration when empty.
+func (g *Sqlxmigrate) redo(ctx context.Context, migrationID string) error {
+	if len(g.migrations) == 0 {
+		return ErrNoMigrationDefined
+	}
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The migrations to redo, in the order they are applied.
+	var redo []*Migration
+	for i := len(g.migrations) - 1; i >= 0; i-- {
+		m := g.migrations[i]
+		if m.ID == migrationID {
+			break
+		}
+		if _, ok := applied[m.ID]; !ok {
+			continue
+		}
+		redo = append([]*Migration{m}, redo...)
+		if migrationID == "" {
+			break
+		}
+	}
+	if len(redo) == 0 {
+		if migrationID == "" {
+			return ErrNoRunMigration
+		}
+		return nil
+	}
+	for _, m := range redo {
+		if m.DisableTransaction {
+			return fmt.Errorf(`sqlxmigrate: Migration "%s" disables the transaction and can't be redone`, m.ID)
+		}
+	}
+
+	if err := g.createAuditTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	if err := g.begin(ctx); err != nil {
+		return err
+	}
+	defer g.rollback()
+
+	for i := len(redo) - 1; i >= 0; i-- {
+		if err := g.rollbackMigration(ctx, redo[i]); err != nil {
+			return err
+		}
+	}
+
+	g.states = make(map[string]State, len(redo))
+	for _, m := range redo {
+		if err := g.transition(m, StatePlanned, nil); err != nil {
+			return err
+		}
+		if err := g.transition(m, StateLocked, nil); err != nil {
+			return g.failMigrations(redo, err)
+		}
+	}
+
+	res := &Result{}
+	for _, m := range redo {
+		if err := g.runMigration(ctx, res, m); err != nil {
+			return g.failMigrations(redo, err)
+		}
+	}
+	if err := g.commitMigrations(redo); err != nil {
+		return g.failMigrations(redo, err)
+	}
+	return nil
+}
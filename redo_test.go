@@ -0,0 +1,51 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedo(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		var runs, rollbacks int
+		counted := append(append([]*Migration{}, migrations...), &Migration{
+			ID: "201608301500",
+			Migrate: func(tx *sql.Tx) error {
+				runs++
+				_, err := tx.Exec("INSERT INTO people (id, name) VALUES (1, 'Jane')")
+				return err
+			},
+			Rollback: func(tx *sql.Tx) error {
+				rollbacks++
+				_, err := tx.Exec("DELETE FROM people")
+				return err
+			},
+		})
+		m := New(db, DefaultOptions, counted)
+		require.NoError(t, m.Migrate())
+
+		require.NoError(t, m.Redo())
+		assert.Equal(t, 2, runs)
+		assert.Equal(t, 1, rollbacks)
+		assert.Equal(t, 1, tableCount(t, db, "people"))
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+
+		require.NoError(t, m.RedoTo("201608301400"))
+		assert.Equal(t, 3, runs)
+		assert.Equal(t, 2, rollbacks)
+		assert.True(t, m.hasTable("pets"))
+		assert.Equal(t, 1, tableCount(t, db, "people"))
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestRedoNothingApplied(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		assert.Equal(t, ErrNoRunMigration, m.Redo())
+	})
+}
@@ -0,0 +1,110 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Renumbering describes the new ID assigned to a pending migration.
+type Renumbering struct {
+	OldID string
+	NewID string
+}
+
+// RenumberPending computes new IDs for the pending migrations whose ID sorts
+// before the last applied migration, which usually happens after merging a
+// branch that added migrations in parallel. The new IDs keep the suffix of
+// the original ID and sort after every known migration, preserving the order
+// of the list. Nothing is changed, it's up to the caller to apply the result.
+func (g *Sqlxmigrate) RenumberPending() ([]Renumbering, error) {
+	return g.renumberPending(context.Background())
+}
+
+func (g *Sqlxmigrate) renumberPending(ctx context.Context) ([]Renumbering, error) {
+	if err := g.validate(ctx); err != nil {
+		if _, ok := err.(*TimestampConflictError); !ok {
+			return nil, err
+		}
+	}
+
+	appliedIDs, err := g.appliedIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// SCHEMA_INIT sorts after the timestamps, it isn't a migration.
+	applied := make(map[string]struct{}, len(appliedIDs))
+	var lastApplied string
+	for _, id := range appliedIDs {
+		applied[id] = struct{}{}
+		if id != initSchemaMigrationID && id > lastApplied {
+			lastApplied = id
+		}
+	}
+	if lastApplied == "" {
+		return nil, nil
+	}
+
+	// New IDs start after the greatest known ID, applied or not.
+	last := lastApplied
+	for _, m := range g.migrations {
+		if m.ID > last {
+			last = m.ID
+		}
+	}
+
+	var res []Renumbering
+	for _, m := range g.migrations {
+		if _, ok := applied[m.ID]; ok || m.ID > lastApplied {
+			continue
+		}
+
+		prefix := timestampPrefix(m.ID)
+		next, err := nextTimestamp(timestampPrefix(last))
+		if err != nil {
+			return nil, err
+		}
+
+		newID := next + m.ID[len(prefix):]
+		if prefix == "" {
+			newID = next + "_" + m.ID
+		}
+		res = append(res, Renumbering{OldID: m.ID, NewID: newID})
+		last = newID
+	}
+
+	return res, nil
+}
+
+// nextTimestamp returns the prefix that directly follows the given one. Prefixes
+// formatted as 200601021504 or 20060102150405 are incremented by a minute and
+// a second respectively, other prefixes are incremented as numbers.
+func nextTimestamp(prefix string) (string, error) {
+	for _, layout := range []struct {
+		format string
+		step   time.Duration
+	}{
+		{"200601021504", time.Minute},
+		{"20060102150405", time.Second},
+	} {
+		if len(prefix) != len(layout.format) {
+			continue
+		}
+		if t, err := time.Parse(layout.format, prefix); err == nil {
+			return t.Add(layout.step).Format(layout.format), nil
+		}
+	}
+
+	if prefix == "" {
+		return "", ErrMissingTimestamp
+	}
+	n, err := strconv.ParseUint(prefix, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	next := strconv.FormatUint(n+1, 10)
+	for len(next) < len(prefix) {
+		next = "0" + next
+	}
+	return next, nil
+}
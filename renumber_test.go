@@ -0,0 +1,69 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenumberPending(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		require.NoError(t, m.Migrate())
+
+		merged := &Migration{
+			ID: "201608301415_create_toys",
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+		m = New(db, DefaultOptions, []*Migration{migrations[0], merged, migrations[1], extendedMigrations[2]})
+
+		renumberings, err := m.RenumberPending()
+		require.NoError(t, err)
+		assert.Equal(t, []Renumbering{
+			{OldID: "201608301415_create_toys", NewID: "201807221928_create_toys"},
+		}, renumberings)
+	})
+}
+
+func TestRenumberPendingAfterInitSchema(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		m.InitSchemaSQL(initSchemaScript, "201608301430")
+		require.NoError(t, m.Migrate())
+
+		merged := &Migration{
+			ID: "201608301415_create_toys",
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+		m = New(db, DefaultOptions, []*Migration{migrations[0], merged, migrations[1], extendedMigrations[2]})
+
+		renumberings, err := m.RenumberPending()
+		require.NoError(t, err)
+		assert.Equal(t, []Renumbering{
+			{OldID: "201608301415_create_toys", NewID: "201807221928_create_toys"},
+		}, renumberings)
+	})
+}
+
+func TestNextTimestamp(t *testing.T) {
+	for prefix, expected := range map[string]string{
+		"201608301459":   "201608301500",
+		"20160830145959": "20160830150000",
+		"0009":           "0010",
+		"41":             "42",
+	} {
+		next, err := nextTimestamp(prefix)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, next)
+	}
+
+	_, err := nextTimestamp("")
+	assert.Equal(t, ErrMissingTimestamp, err)
+}
@@ -0,0 +1,255 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RepairOptions define what Repair is allowed to change in the migration table.
+type RepairOptions struct {
+	// RemoveUnknown deletes the rows of the migrations that are not in the
+	// list of migrations anymore.
+	RemoveUnknown bool
+	// UpdateChecksums records the current checksum of the applied
+	// migrations whose stored checksum is missing or differs, once their
+	// edits are approved, so Validate stops failing with a
+	// ChecksumMismatchError. The checksums are stored by the migration
+	// table, Repair fails with ErrUnsupportedDriver with another
+	// HistoryStore.
+	UpdateChecksums bool
+}
+
+// RepairResult lists the changes made by Repair.
+type RepairResult struct {
+	// RemovedIDs are the IDs of the deleted rows.
+	RemovedIDs []string
+	// UpdatedIDs are the IDs of the migrations whose checksum was updated.
+	UpdatedIDs []string
+}
+
+// Repair reconciles the migration table with the list of migrations, for
+// teams recovering from manual changes of the database. Nothing is changed
+// unless enabled by the options. The migration table has no dirty flag to
+// clear: a failed migration is never recorded, its transaction is rolled
+// back.
+func (g *Sqlxmigrate) Repair(opts RepairOptions) (*RepairResult, error) {
+	return g.repair(context.Background(), opts)
+}
+
+func (g *Sqlxmigrate) repair(ctx context.Context, opts RepairOptions) (*RepairResult, error) {
+	if opts.UpdateChecksums && !g.usesTable() {
+		return nil, ErrUnsupportedDriver
+	}
+	res := &RepairResult{}
+
+	var unknown []string
+	if opts.RemoveUnknown {
+		var err error
+		if unknown, err = g.unknownAppliedIDs(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var changed []*Migration
+	if opts.UpdateChecksums {
+		var err error
+		if changed, err = g.changedChecksums(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(unknown) == 0 && len(changed) == 0 {
+		return res, nil
+	}
+
+	if err := g.begin(ctx); err != nil {
+		return nil, err
+	}
+	defer g.rollback()
+
+	for _, id := range unknown {
+		g.logf("Repair %s - removing", id)
+
+		if err := g.history.Remove(ctx, g.tx, id); err != nil {
+			return nil, err
+		}
+		res.RemovedIDs = append(res.RemovedIDs, id)
+	}
+
+	for _, m := range changed {
+		g.logf("Repair %s - updating its checksum", m.ID)
+
+		if err := g.updateChecksum(ctx, m); err != nil {
+			return nil, err
+		}
+		res.UpdatedIDs = append(res.UpdatedIDs, m.ID)
+	}
+
+	if err := g.commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// changedChecksums returns the applied migrations with a checksum that
+// doesn't match the stored one, or has none stored.
+func (g *Sqlxmigrate) changedChecksums(ctx context.Context) ([]*Migration, error) {
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := g.appliedMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []*Migration
+	for _, m := range g.migrations {
+		md, ok := metadata[m.ID]
+		if _, isApplied := applied[m.ID]; !ok || !isApplied {
+			continue
+		}
+		if sum := m.scriptChecksum(); sum != "" && sum != md.checksum {
+			changed = append(changed, m)
+		}
+	}
+	return changed, nil
+}
+
+// MarkApplied records a migration as applied without running it, ie. once a
+// hotfix applied its changes by hand in production. Nothing is done when the
+// migration is already applied. The audit table records it with the
+// mark_applied direction.
+func (g *Sqlxmigrate) MarkApplied(migrationID string) error {
+	return g.mark(context.Background(), migrationID, auditMarkApplied)
+}
+
+// MarkRolledBack records a migration as rolled back without running its
+// rollback, ie. once its changes were undone by hand. Nothing is done when
+// the migration isn't applied. The audit table records it with the
+// mark_rolled_back direction.
+func (g *Sqlxmigrate) MarkRolledBack(migrationID string) error {
+	return g.mark(context.Background(), migrationID, auditMarkRolledBack)
+}
+
+func (g *Sqlxmigrate) mark(ctx context.Context, migrationID, direction string) error {
+	if err := g.checkIDExist(migrationID); err != nil {
+		return err
+	}
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := g.history.Init(ctx); err != nil {
+		return err
+	}
+	if err := g.createAuditTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return err
+	}
+	_, isApplied := applied[migrationID]
+	switch {
+	case direction == auditMarkApplied && isApplied:
+		g.logf("Migration %s - already applied", migrationID)
+		return nil
+	case direction == auditMarkRolledBack && !isApplied:
+		g.logf("Migration %s - not applied", migrationID)
+		return nil
+	}
+
+	if err := g.begin(ctx); err != nil {
+		return err
+	}
+	defer g.rollback()
+
+	g.logf("Migration %s - %s", migrationID, direction)
+	if direction == auditMarkApplied {
+		err = g.insertMigration(ctx, migrationID)
+	} else {
+		err = g.history.Remove(ctx, g.tx, migrationID)
+	}
+	if err != nil {
+		return err
+	}
+	if err := g.insertAudit(ctx, migrationID, direction, Stats{}, nil); err != nil {
+		return err
+	}
+	return g.commit()
+}
+
+// BaselineError is returned by Baseline when migrations are already
+// recorded.
+type BaselineError struct {
+	IDs []string
+}
+
+func (e *BaselineError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Can't baseline, migrations are already recorded: "%s"`, strings.Join(e.IDs, `", "`))
+}
+
+// Hint implements Hinter.
+func (e *BaselineError) Hint() string {
+	return "Baseline adopts a database without recorded migrations, MarkApplied records a single migration of a database already migrated."
+}
+
+// Baseline records the migrations up to the one that matches `migrationID`
+// as applied without running them, so an existing database adopts
+// sqlxmigrate without replaying its history. The following migrations are
+// applied by the next run. It fails with a *BaselineError when migrations are
+// already recorded. The audit table records them with the baseline
+// direction.
+func (g *Sqlxmigrate) Baseline(migrationID string) error {
+	return g.baseline(context.Background(), migrationID)
+}
+
+func (g *Sqlxmigrate) baseline(ctx context.Context, migrationID string) error {
+	if err := g.checkIDExist(migrationID); err != nil {
+		return err
+	}
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := g.history.Init(ctx); err != nil {
+		return err
+	}
+	if err := g.createAuditTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	applied, err := g.appliedIDs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		return &BaselineError{IDs: applied}
+	}
+
+	if err := g.begin(ctx); err != nil {
+		return err
+	}
+	defer g.rollback()
+
+	for _, m := range g.migrations {
+		g.logf("Migration %s - %s", m.ID, auditBaseline)
+		if err := g.insertMigration(ctx, m.ID); err != nil {
+			return err
+		}
+		if err := g.insertAudit(ctx, m.ID, auditBaseline, Stats{}, nil); err != nil {
+			return err
+		}
+		if m.ID == migrationID {
+			break
+		}
+	}
+	return g.commit()
+}
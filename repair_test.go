@@ -0,0 +1,113 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepair(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.Migrate())
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+
+		// The last migration was deleted from the code.
+		m = New(db, DefaultOptions, migrations)
+
+		res, err := m.Repair(RepairOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, res.RemovedIDs)
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+
+		res, err = m.Repair(RepairOptions{RemoveUnknown: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201807221927"}, res.RemovedIDs)
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestRepairChecksums(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		script := []*Migration{
+			migrations[0],
+			{
+				ID:         "201608301500",
+				MigrateSQL: "CREATE TABLE cars (id int PRIMARY KEY)",
+			},
+		}
+		m := New(db, DefaultOptions, script)
+		require.NoError(t, m.Migrate())
+
+		// The script was edited after it was applied, ie. a comment.
+		script[1].MigrateSQL = "-- the cars of the people\nCREATE TABLE cars (id int PRIMARY KEY)"
+		var mismatch *ChecksumMismatchError
+		require.True(t, errors.As(m.Validate(), &mismatch))
+
+		res, err := m.Repair(RepairOptions{UpdateChecksums: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301500"}, res.UpdatedIDs)
+		require.NoError(t, m.Validate())
+
+		res, err = m.Repair(RepairOptions{UpdateChecksums: true})
+		require.NoError(t, err)
+		assert.Empty(t, res.UpdatedIDs)
+	})
+}
+
+func TestMarkApplied(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		require.NoError(t, dropTableIfExists(db, "migration_audit"))
+		options := *DefaultOptions
+		options.AuditTableName = "migration_audit"
+
+		m := New(db, &options, migrations)
+
+		// The hotfix created the people table by hand.
+		require.NoError(t, m.MarkApplied("201608301400"))
+		require.NoError(t, m.MarkApplied("201608301400"))
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("people"))
+
+		require.NoError(t, m.Migrate())
+		assert.False(t, m.hasTable("people"))
+		assert.True(t, m.hasTable("pets"))
+
+		// The pets table was dropped by hand.
+		require.NoError(t, m.MarkRolledBack("201608301430"))
+		require.NoError(t, m.MarkRolledBack("201608301430"))
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+		assert.True(t, m.hasTable("pets"))
+
+		var directions []string
+		require.NoError(t, db.Select(&directions, "SELECT direction FROM migration_audit ORDER BY executed_at"))
+		assert.Equal(t, []string{"mark_applied", "migrate", "mark_rolled_back"}, directions)
+
+		assert.Equal(t, ErrMigrationIDDoesNotExist, m.MarkApplied("201608301500"))
+	})
+}
+
+func TestBaseline(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		// The legacy database has the tables of the first two migrations.
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.Baseline("201608301430"))
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("people"))
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201807221927"}, resultIDs(res))
+
+		err = m.Baseline("201608301430")
+		var baselineErr *BaselineError
+		require.True(t, errors.As(err, &baselineErr))
+		assert.Len(t, baselineErr.IDs, 3)
+		assert.NotEmpty(t, Hint(err))
+
+		assert.Equal(t, ErrMigrationIDDoesNotExist, New(db, DefaultOptions, migrations).Baseline("201807221927"))
+	})
+}
@@ -0,0 +1,127 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ReplicaLagFunc returns the replication lag of the replicas, the longest
+// one when there are several.
+type ReplicaLagFunc func(ctx context.Context) (time.Duration, error)
+
+// postgresReplicaLagQuery returns the longest replay lag of the standbys
+// connected to the primary, in seconds. The lag of a standby that caught up
+// and stayed idle is NULL.
+const postgresReplicaLagQuery = "SELECT coalesce(max(extract(epoch FROM replay_lag)), 0) FROM pg_stat_replication"
+
+// postgresReplicaLag returns the function reading the replication lag from
+// pg_stat_replication on the primary.
+func postgresReplicaLag(e Execer) ReplicaLagFunc {
+	return func(ctx context.Context) (time.Duration, error) {
+		var seconds float64
+		if err := e.QueryRowContext(ctx, postgresReplicaLagQuery).Scan(&seconds); err != nil {
+			err = queryError(err, postgresReplicaLagQuery)
+			return 0, err
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+}
+
+// MySQLReplicaLag returns the function reading the replication lag from
+// Seconds_Behind_Source of SHOW REPLICA STATUS on the replicas, or
+// Seconds_Behind_Master of SHOW SLAVE STATUS before MySQL 8.0.22 and on
+// MariaDB. The primary doesn't know the lag of its replicas, so each replica
+// is connected to. A replica whose replication is stopped fails.
+func MySQLReplicaLag(replicas ...*sqlx.DB) ReplicaLagFunc {
+	return func(ctx context.Context) (time.Duration, error) {
+		var lag time.Duration
+		for _, db := range replicas {
+			l, err := mysqlReplicaLag(ctx, db)
+			if err != nil {
+				return 0, err
+			}
+			if l > lag {
+				lag = l
+			}
+		}
+		return lag, nil
+	}
+}
+
+func mysqlReplicaLag(ctx context.Context, db *sqlx.DB) (time.Duration, error) {
+	query := "SHOW REPLICA STATUS"
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		query = "SHOW SLAVE STATUS"
+		if rows, err = db.QueryContext(ctx, query); err != nil {
+			err = queryError(err, query)
+			return 0, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("sqlxmigrate: %s returned no row, the server isn't a replica", query)
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+	for i, c := range columns {
+		if c != "Seconds_Behind_Source" && c != "Seconds_Behind_Master" {
+			continue
+		}
+		// NULL while the replication is stopped.
+		if values[i] == nil {
+			return 0, fmt.Errorf("sqlxmigrate: Replication is stopped, %s is NULL", c)
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, fmt.Errorf("sqlxmigrate: %s has no Seconds_Behind_Source column", query)
+}
+
+// waitReplicaLag waits until the replication lag is at most the maximum of
+// the options, polling it.
+func waitReplicaLag(ctx context.Context, lagFunc ReplicaLagFunc, opts *BatchOptions, logf func(string, ...interface{}), batch int) error {
+	interval := opts.ReplicaLagPollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for {
+		lag, err := lagFunc(ctx)
+		if err != nil {
+			return err
+		}
+		if lag <= opts.MaxReplicaLag {
+			return nil
+		}
+		logf("batch %d - replica lag of %s over %s, waiting", batch, lag, opts.MaxReplicaLag)
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+)
+
+// WarningCode identifies the kind of a Warning.
+type WarningCode string
+
+const (
+	// WarningOrphanedMigration is reported for an ID of the migration table
+	// that doesn't match any migration.
+	WarningOrphanedMigration WarningCode = "orphaned_migration"
+	// WarningMissingRollback is reported for a migration to apply that has
+	// no Rollback function and isn't marked with NoRollback.
+	WarningMissingRollback WarningCode = "missing_rollback"
+	// WarningNonTransactional is reported when the database engine commits
+	// schema changes implicitly, so a failed migration can't be undone.
+	WarningNonTransactional WarningCode = "non_transactional"
+	// WarningOutOfOrder is reported for a migration to apply whose ID sorts
+	// before the last applied migration.
+	WarningOutOfOrder WarningCode = "out_of_order"
+	// WarningRunBudgetExceeded is reported when the run stopped before
+	// applying every migration because Options.RunBudget was exceeded.
+	WarningRunBudgetExceeded WarningCode = "run_budget_exceeded"
+	// WarningUnsupportedAlter is reported for a statement of a migration to
+	// apply that the limited ALTER TABLE of SQLite can't run.
+	WarningUnsupportedAlter WarningCode = "unsupported_alter"
+	// WarningDuplicateIndex is reported by Options.ReportIndexes for an
+	// index covered by another index of its table.
+	WarningDuplicateIndex WarningCode = "duplicate_index"
+	// WarningUnusedIndex is reported by Options.ReportIndexes for an index
+	// never scanned.
+	WarningUnusedIndex WarningCode = "unused_index"
+	// WarningTableBloat is reported by Options.BloatThreshold for a table of
+	// Migration.HeavyDMLTables left bloated by the migration.
+	WarningTableBloat WarningCode = "table_bloat"
+)
+
+// Warning is a problem found during a run that didn't fail it.
+type Warning struct {
+	Code WarningCode
+	// MigrationID is the ID of the migration concerned, if any.
+	MigrationID string
+	Message     string
+}
+
+func (w Warning) String() string {
+	if w.MigrationID == "" {
+		return fmt.Sprintf("%s: %s", w.Code, w.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", w.Code, w.MigrationID, w.Message)
+}
+
+// Result reports the outcome of a migration run.
+type Result struct {
+	// Migrations are the migrations applied by the run, in order.
+	Migrations []MigrationResult
+	// Pending are the IDs of the migrations left to apply when the run
+	// stopped early.
+	Pending []string
+	// Deferred are the IDs of the migrations gated on a disabled flag or
+	// whose NotBefore time didn't come yet, which a later run applies.
+	Deferred []string
+	// Skipped are the IDs of the migrations the run skipped because they
+	// were already applied.
+	Skipped []string
+	// Failed are the IDs of the migrations that failed, with the migrations
+	// of their group, when Options.OnFailure is FailureContinueOthers.
+	Failed []string
+	// Version is the version of the schema once the run ended, the ID of
+	// the last applied migration. Empty when none is applied.
+	Version string
+	// Warnings are the problems found during the run that didn't fail it.
+	Warnings []Warning
+	// Indexes are the indexes created and dropped by the reconciliation of
+	// the declared index sets.
+	Indexes []IndexChange
+	// WALBytes are the bytes of WAL, or binary log on MySQL, generated by
+	// the database during the run, including the writes of the other
+	// sessions. 0 when the server doesn't expose it.
+	WALBytes int64
+	// Attestation is the signed statement of the migrations applied once
+	// the run completed, when Options.AttestationSigner is set.
+	Attestation *Attestation
+}
+
+// MigrationResult reports the outcome of a migration.
+type MigrationResult struct {
+	ID    string
+	Stats Stats
+	// Usage is the resources the migration used, Usage.WallTime being its
+	// duration.
+	Usage Usage
+}
+
+// setVersion sets the version of the schema on the result of the run.
+func (g *Sqlxmigrate) setVersion(ctx context.Context, res *Result) error {
+	version, err := g.currentVersion(ctx)
+	res.Version = version
+	return err
+}
+
+// warn records a warning on the result of the run.
+func (g *Sqlxmigrate) warn(res *Result, w Warning) {
+	g.logf("Warning - %s", w)
+	res.Warnings = append(res.Warnings, w)
+}
+
+// collectWarnings looks for the soft problems of a run up to the migration
+// that matches `migrationID`.
+func (g *Sqlxmigrate) collectWarnings(ctx context.Context, res *Result, migrationID string) error {
+	appliedIDs, err := g.appliedIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[string]struct{}, len(appliedIDs))
+	for _, id := range appliedIDs {
+		applied[id] = struct{}{}
+	}
+	lastApplied := g.lastAppliedID(appliedIDs)
+
+	if g.options.OrphanedMigrations != SeverityIgnore {
+		unknown, err := g.unknownAppliedIDs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, id := range unknown {
+			g.warn(res, Warning{Code: WarningOrphanedMigration, MigrationID: id, Message: "applied migration is missing from the list of migrations"})
+		}
+	}
+
+	if !g.transactionalDDL() {
+		g.warn(res, Warning{Code: WarningNonTransactional, Message: "schema changes are committed implicitly and can't be rolled back on failure"})
+	}
+
+	for _, m := range g.migrations {
+		if _, ok := applied[m.ID]; !ok {
+			if !m.hasRollback() && !m.irreversible() {
+				g.warn(res, Warning{Code: WarningMissingRollback, MigrationID: m.ID, Message: "migration has no rollback function"})
+			}
+			if m.DisableTransaction {
+				g.warn(res, Warning{Code: WarningNonTransactional, MigrationID: m.ID, Message: "migration runs outside of a transaction and can't be rolled back on failure"})
+			}
+			// The migrations the runs defer are expected to be applied
+			// after the ones that follow them.
+			if lastApplied != "" && g.compareIDs(m.ID, lastApplied) < 0 && !m.gated() && g.options.OutOfOrder != SeverityIgnore {
+				g.warn(res, Warning{Code: WarningOutOfOrder, MigrationID: m.ID, Message: fmt.Sprintf("migration sorts before the last applied migration %s", lastApplied)})
+			}
+			if g.isSQLite() {
+				for _, stmt := range unsupportedSQLiteAlters(g.db.DriverName(), m.MigrateSQL) {
+					g.warn(res, Warning{Code: WarningUnsupportedAlter, MigrationID: m.ID, Message: fmt.Sprintf("SQLite can't run %q, rebuild the table instead: create the new table, copy the rows, drop the table and rename the new one", stmt)})
+				}
+			}
+		}
+		if migrationID != "" && m.ID == migrationID {
+			break
+		}
+	}
+
+	return nil
+}
+
+// skipPending reports the migrations left when a run stops early as pending
+// and moves them back to the planned state.
+func (g *Sqlxmigrate) skipPending(res *Result, migrations []*Migration) error {
+	for _, m := range migrations {
+		if err := g.transition(m, StatePlanned, nil); err != nil {
+			return err
+		}
+		res.Pending = append(res.Pending, m.ID)
+	}
+	return nil
+}
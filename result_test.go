@@ -0,0 +1,138 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateWithResultWarnings(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		_, err := m.MigrateWithResult()
+		require.NoError(t, err)
+
+		merged := &Migration{
+			ID: "201608301415",
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+		m = New(db, DefaultOptions, []*Migration{migrations[0], merged, migrations[1]})
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+
+		var codes []WarningCode
+		for _, w := range res.Warnings {
+			if w.Code != WarningNonTransactional {
+				codes = append(codes, w.Code)
+			}
+		}
+		assert.Equal(t, []WarningCode{WarningOrphanedMigration, WarningMissingRollback, WarningOutOfOrder}, codes)
+		assert.Equal(t, Warning{Code: WarningOutOfOrder, MigrationID: "201608301415", Message: "migration sorts before the last applied migration 201807221927"}, res.Warnings[len(res.Warnings)-1])
+	})
+}
+
+func TestMigrateWithResult(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301430", "201807221927"}, resultIDs(res))
+		assert.Equal(t, []string{"201608301400"}, res.Skipped)
+		assert.Equal(t, "201807221927", res.Version)
+		for _, r := range res.Migrations {
+			assert.True(t, r.Usage.WallTime > 0)
+		}
+
+		// Nothing left to apply.
+		res, err = m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Empty(t, res.Migrations)
+		assert.Equal(t, []string{"201608301400", "201608301430", "201807221927"}, res.Skipped)
+		assert.Equal(t, "201807221927", res.Version)
+	})
+}
+
+func TestRunBudget(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		slow := &Migration{
+			ID: "201608301300",
+			Migrate: func(tx *sql.Tx) error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			},
+			Rollback: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+
+		m := New(db, &Options{RunBudget: 10 * time.Millisecond}, append([]*Migration{slow}, migrations...))
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		require.Len(t, res.Migrations, 1)
+		assert.Equal(t, "201608301300", res.Migrations[0].ID)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, res.Pending)
+		assert.False(t, m.hasTable("people"))
+
+		m = New(db, DefaultOptions, append([]*Migration{slow}, migrations...))
+
+		res, err = m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Empty(t, res.Pending)
+		assert.True(t, m.hasTable("people"))
+	})
+}
+
+func TestUnsupportedAlterWarning(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, append(migrations, &Migration{
+			ID:          "201906121200",
+			MigrateSQL:  `ALTER TABLE pets ADD COLUMN age INTEGER NULL`,
+			RollbackSQL: `ALTER TABLE pets DROP COLUMN age`,
+		}))
+		require.NoError(t, m.MigrateTo("201608301430"))
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		for _, w := range res.Warnings {
+			assert.NotEqual(t, WarningUnsupportedAlter, w.Code)
+		}
+
+		m = New(db, DefaultOptions, append(migrations, &Migration{
+			ID:         "201906121300",
+			MigrateSQL: `ALTER TABLE pets ALTER COLUMN name SET NOT NULL`,
+		}))
+		_, err = m.MigrateWithResult()
+		require.Error(t, err)
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+	}, "sqlite3")
+}
+
+func TestUnsupportedSQLiteAlters(t *testing.T) {
+	script := `ALTER TABLE pets RENAME TO animals;
+ALTER TABLE pets RENAME COLUMN name TO label;
+ALTER TABLE pets ADD COLUMN price DECIMAL(10,2);
+ALTER TABLE pets ADD age INTEGER;
+ALTER TABLE pets DROP COLUMN age;
+ALTER TABLE pets ALTER COLUMN name SET NOT NULL;
+ALTER TABLE pets ADD CONSTRAINT pets_name_key UNIQUE (name);
+ALTER TABLE pets DROP CONSTRAINT pets_name_key;
+ALTER TABLE pets ADD COLUMN a INTEGER, ADD COLUMN b INTEGER;
+CREATE INDEX pets_name ON pets (name);`
+
+	assert.Equal(t, []string{
+		"ALTER TABLE pets ALTER COLUMN name SET NOT NULL",
+		"ALTER TABLE pets ADD CONSTRAINT pets_name_key UNIQUE (name)",
+		"ALTER TABLE pets DROP CONSTRAINT pets_name_key",
+		"ALTER TABLE pets ADD COLUMN a INTEGER, ADD COLUMN b INTEGER",
+	}, unsupportedSQLiteAlters("sqlite3", script))
+}
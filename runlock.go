@@ -0,0 +1,262 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+// lockPollInterval is how often the lock row is tried again while another
+// instance holds it.
+const lockPollInterval = 250 * time.Millisecond
+
+// lockKey returns the key of the advisory lock of the migration table, so
+// runs against different migration tables don't wait for each other.
+func (g *Sqlxmigrate) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("sqlxmigrate:" + g.options.TableName))
+	return int64(h.Sum64())
+}
+
+// lock serializes the runs when Options.UseLock is set, returning the
+// function releasing the lock. The lock is:
+//   - on PostgreSQL, except CockroachDB, a session advisory lock held by a dedicated connection,
+//     or behind a transaction pooler, which doesn't keep the session of a
+//     client, a transaction advisory lock held by a dedicated transaction
+//   - on MySQL, a named lock held by a dedicated connection
+//   - on SQL Server, an application lock held by a dedicated connection
+//   - otherwise, a lock row inserted in the migration table
+func (g *Sqlxmigrate) lock(ctx context.Context) (func(), error) {
+	// Every run and rollback starts by taking the lock, which identifies it.
+	runID, err := newRunID()
+	if err != nil {
+		return nil, err
+	}
+	g.runID = runID
+
+	if !g.options.UseLock {
+		return func() {}, nil
+	}
+
+	// The strategy depends on the server, ie. CockroachDB doesn't implement
+	// the advisory locks of PostgreSQL.
+	c, err := g.probeCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var unlock func()
+	lockCtx, cancel := g.lockContext(ctx)
+	defer cancel()
+
+	switch g.lockStrategy(c.Pooled) {
+	case LockAdvisoryTransaction:
+		unlock, err = g.lockPostgresTransaction(ctx, lockCtx)
+	case LockAdvisory:
+		unlock, err = g.lockSession(ctx, lockCtx)
+	case LockNamed:
+		if g.isSQLServer() {
+			unlock, err = g.lockSQLServer(ctx)
+		} else {
+			unlock, err = g.lockMySQL(ctx)
+		}
+	default:
+		unlock, err = g.lockRow(ctx, lockCtx)
+	}
+
+	// The lock timeout is reported unless the run itself was canceled.
+	if err != nil && lockCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return nil, ErrLockTimeout
+	}
+	return unlock, err
+}
+
+func (g *Sqlxmigrate) lockStrategy(pooled bool) LockStrategy {
+	switch {
+	case g.isCockroachDB():
+		return LockRow
+	case g.isPostgres() && pooled:
+		return LockAdvisoryTransaction
+	case g.isPostgres():
+		return LockAdvisory
+	case (g.isMySQL() || g.isSQLServer()) && !pooled:
+		return LockNamed
+	}
+	if lock, _ := g.dialect().LockSQL(); lock != "" && !pooled {
+		return LockAdvisory
+	}
+	return LockRow
+}
+
+// lockName returns the name of the named lock of the migration table,
+// limited to 64 characters by MySQL.
+func (g *Sqlxmigrate) lockName() string {
+	return fmt.Sprintf("sqlxmigrate_%x", uint64(g.lockKey()))
+}
+
+// lockContext returns the context the lock is waited for with, done once
+// Options.LockTimeout elapsed.
+func (g *Sqlxmigrate) lockContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.options.LockTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, g.options.LockTimeout)
+}
+
+// lockSession takes the lock of the LockSQL of the dialect, held by the
+// session of a dedicated connection.
+func (g *Sqlxmigrate) lockSession(ctx, lockCtx context.Context) (func(), error) {
+	conn, err := g.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lockSQL, unlockSQL := g.dialect().LockSQL()
+	sql := g.rebind(lockSQL)
+	g.logf("lock %s", sql)
+
+	if _, err := conn.ExecContext(lockCtx, sql, g.lockKey()); err != nil {
+		conn.Close()
+		err = queryError(err, sql)
+		return nil, err
+	}
+
+	return func() {
+		sql := g.rebind(unlockSQL)
+		g.logf("unlock %s", sql)
+
+		// Closing the connection returns it to the pool, the lock must be
+		// released first.
+		if _, err := conn.ExecContext(context.Background(), sql, g.lockKey()); err != nil {
+			g.logf("unlock failed - %v", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+func (g *Sqlxmigrate) lockPostgresTransaction(ctx, lockCtx context.Context) (func(), error) {
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sql := g.rebind("SELECT pg_advisory_xact_lock(?)")
+	g.logf("lock %s", sql)
+
+	if _, err := tx.ExecContext(lockCtx, sql, g.lockKey()); err != nil {
+		tx.Rollback()
+		err = queryError(err, sql)
+		return nil, err
+	}
+
+	return func() {
+		g.logf("unlock - ending the lock transaction")
+
+		if err := tx.Rollback(); err != nil {
+			g.logf("unlock failed - %v", err)
+		}
+	}, nil
+}
+
+// lockMySQL waits for the named lock with the timeout of GET_LOCK, which
+// returns 0 once it elapsed.
+func (g *Sqlxmigrate) lockMySQL(ctx context.Context) (func(), error) {
+	conn, err := g.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := g.lockName()
+	timeout := -1
+	if g.options.LockTimeout > 0 {
+		timeout = int(math.Ceil(g.options.LockTimeout.Seconds()))
+	}
+
+	query := "SELECT GET_LOCK(?, ?)"
+	g.logf("lock %s", query)
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, query, name, timeout).Scan(&locked); err != nil {
+		conn.Close()
+		err = queryError(err, query)
+		return nil, err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		conn.Close()
+		return nil, ErrLockTimeout
+	}
+
+	return func() {
+		query := "SELECT RELEASE_LOCK(?)"
+		g.logf("unlock %s", query)
+
+		if _, err := conn.ExecContext(context.Background(), query, name); err != nil {
+			g.logf("unlock failed - %v", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+// lockRow inserts the lock row in the migration table, waiting while
+// another instance holds it. The row of an instance that crashed while
+// holding the lock must be deleted manually.
+func (g *Sqlxmigrate) lockRow(ctx, lockCtx context.Context) (func(), error) {
+	if !g.usesTable() {
+		return nil, ErrUnsupportedDriver
+	}
+	if err := g.createMigrationTableIfNotExists(ctx); err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?)", g.options.TableName, g.options.IDColumnName)
+	sql = g.rebind(sql)
+	g.logf("lock %s", sql)
+
+	for {
+		_, err := g.db.ExecContext(lockCtx, sql, lockMigrationID)
+		if err == nil {
+			break
+		}
+
+		// The insert fails on the primary key while the row exists.
+		held, herr := g.lockRowHeld(lockCtx)
+		if herr != nil {
+			return nil, herr
+		} else if !held {
+			err = queryError(err, sql)
+			return nil, err
+		}
+
+		select {
+		case <-lockCtx.Done():
+			return nil, lockCtx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	return func() {
+		sql := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", g.options.TableName, g.options.IDColumnName)
+		sql = g.rebind(sql)
+		g.logf("unlock %s", sql)
+
+		// The lock is released even when the run was canceled.
+		if _, err := g.db.ExecContext(context.Background(), sql, lockMigrationID); err != nil {
+			g.logf("unlock failed - %v", err)
+		}
+	}, nil
+}
+
+func (g *Sqlxmigrate) lockRowHeld(ctx context.Context) (bool, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", g.options.TableName, g.options.IDColumnName)
+	query = g.rebind(query)
+
+	var count int
+	if err := g.db.QueryRowContext(ctx, query, lockMigrationID).Scan(&count); err != nil {
+		err = queryError(err, query)
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,70 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseLock(t *testing.T) {
+	for _, mode := range []PoolerMode{PoolerNone, PoolerTransaction} {
+		forEachDatabase(t, func(db *sqlx.DB) {
+			options := *DefaultOptions
+			options.UseLock = true
+			options.LockTimeout = 200 * time.Millisecond
+			options.Pooler = mode
+
+			holder := New(db, &options, migrations)
+			unlock, err := holder.lock(context.Background())
+			require.NoError(t, err)
+
+			m := New(db, &options, migrations)
+			assert.Equal(t, ErrLockTimeout, m.Migrate())
+			assert.False(t, m.hasTable("people"))
+			assert.Equal(t, ErrLockTimeout, m.RollbackLast())
+
+			unlock()
+			require.NoError(t, m.Migrate())
+			assert.True(t, m.hasTable("pets"))
+
+			// The lock row isn't a migration.
+			pending, err := m.Pending()
+			require.NoError(t, err)
+			assert.Empty(t, pending)
+
+			require.NoError(t, m.RollbackLast())
+		})
+	}
+}
+
+func TestUseLockCanceled(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.UseLock = true
+
+		holder := New(db, &options, migrations)
+		unlock, err := holder.lock(context.Background())
+		require.NoError(t, err)
+		defer unlock()
+
+		// Without timeout the run waits for the lock until its context is
+		// done.
+		m := New(db, &options, migrations)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		err = m.MigrateContext(ctx)
+		assert.Error(t, err)
+		assert.NotEqual(t, ErrLockTimeout, err)
+	})
+}
+
+func TestLockReservedID(t *testing.T) {
+	m := New(nil, DefaultOptions, []*Migration{{ID: lockMigrationID}})
+	err := m.Migrate()
+	assert.Equal(t, &ReservedIDError{ID: lockMigrationID}, err)
+	assert.Contains(t, Hint(err), "lock the migration table")
+}
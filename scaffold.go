@@ -0,0 +1,174 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ScaffoldFormat is the kind of files Create generates for a migration.
+type ScaffoldFormat int
+
+const (
+	// ScaffoldSQL generates the empty up and down files read by
+	// LoadMigrations.
+	ScaffoldSQL ScaffoldFormat = iota
+	// ScaffoldGo generates a Go file declaring the Migration.
+	ScaffoldGo
+)
+
+// nonWord matches the characters replaced in the names of new migrations.
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create generates the skeleton of a new migration in a directory, created
+// when missing, and returns the paths of the files. The ID of the migration
+// is the current time followed by the name, ie. 201906121200_create_people,
+// and sorts after the migrations of the directory. Existing files are never
+// overwritten.
+func Create(dir, name string, format ScaffoldFormat) ([]string, error) {
+	slug := strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return nil, fmt.Errorf(`sqlxmigrate: Invalid migration name "%s"`, name)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	prefix, err := scaffoldTimestamp(dir, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	id := prefix + "_" + slug
+
+	files := make(map[string]string)
+	switch format {
+	case ScaffoldSQL:
+		files[id+upSuffix] = ""
+		files[id+downSuffix] = ""
+	case ScaffoldGo:
+		pkg, err := scaffoldPackage(dir)
+		if err != nil {
+			return nil, err
+		}
+		files[id+".go"] = fmt.Sprintf(goScaffold, pkg, goIdentifier(id), id)
+	default:
+		return nil, fmt.Errorf("sqlxmigrate: Unknown scaffold format %d", format)
+	}
+
+	var paths []string
+	for _, suffix := range []string{upSuffix, downSuffix, ".go"} {
+		content, ok := files[id+suffix]
+		if !ok {
+			continue
+		}
+		p := filepath.Join(dir, id+suffix)
+		if err := createFile(p, content); err != nil {
+			return paths, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// goScaffold is the Go file of a new migration, formatted with its package,
+// the name of its variable and its ID.
+const goScaffold = `package %s
+
+import (
+	"database/sql"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+var %s = &sqlxmigrate.Migration{
+	ID: %q,
+	Migrate: func(tx *sql.Tx) error {
+		return nil
+	},
+	Rollback: func(tx *sql.Tx) error {
+		return nil
+	},
+}
+`
+
+// scaffoldTimestamp returns the timestamp prefix of a new migration of a
+// directory, the current minute unless a migration of the directory already
+// uses it or a later one.
+func scaffoldTimestamp(dir string, now time.Time) (string, error) {
+	prefix := now.UTC().Format("200601021504")
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	last := ""
+	for _, f := range files {
+		if p := timestampPrefix(f.Name()); len(p) == len(prefix) && p > last {
+			last = p
+		}
+	}
+	if last < prefix {
+		return prefix, nil
+	}
+	return nextTimestamp(last)
+}
+
+// scaffoldPackage returns the package of the Go files of a directory, or the
+// name of the directory when it has none.
+func scaffoldPackage(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	for _, p := range matches {
+		f, err := parser.ParseFile(token.NewFileSet(), p, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		if name := f.Name.Name; !strings.HasSuffix(name, "_test") {
+			return name, nil
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	name := nonWord.ReplaceAllString(strings.ToLower(filepath.Base(abs)), "")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "migrations"
+	}
+	return name, nil
+}
+
+// goIdentifier returns the name of the variable of a migration, ie.
+// migration201906121200CreatePeople.
+func goIdentifier(id string) string {
+	var b strings.Builder
+	b.WriteString("migration")
+	for _, part := range strings.Split(id, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// createFile writes a new file, failing when it exists.
+func createFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,69 @@
+package sqlxmigrate
+
+import (
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSQL(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+
+	paths, err := Create(dir, "Create people!", ScaffoldSQL)
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	assert.Regexp(t, `/\d{12}_create_people\.up\.sql$`, paths[0])
+	assert.Regexp(t, `/\d{12}_create_people\.down\.sql$`, paths[1])
+
+	migrations, err := LoadMigrations(dir)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, filepath.Base(paths[0][:len(paths[0])-len(upSuffix)]), migrations[0].ID)
+
+	_, err = Create(dir, "!!", ScaffoldSQL)
+	assert.Error(t, err)
+}
+
+func TestCreateGo(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "migrations.go"), []byte("package schema\n"), 0644))
+
+	paths, err := Create(dir, "create people", ScaffoldGo)
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	src, err := ioutil.ReadFile(paths[0])
+	require.NoError(t, err)
+	formatted, err := format.Source(src)
+	require.NoError(t, err)
+	assert.Equal(t, string(formatted), string(src))
+	assert.Contains(t, string(src), "package schema\n")
+	assert.Regexp(t, `var migration\d{12}CreatePeople = &sqlxmigrate.Migration\{\n\tID: "\d{12}_create_people",`, string(src))
+}
+
+func TestScaffoldTimestamp(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2019, 6, 12, 12, 0, 30, 0, time.UTC)
+
+	prefix, err := scaffoldTimestamp(dir, now)
+	require.NoError(t, err)
+	assert.Equal(t, "201906121200", prefix)
+
+	// A new migration sorts after the ones of the directory.
+	f, err := os.Create(filepath.Join(dir, "201906121200_create_people.up.sql"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	prefix, err = scaffoldTimestamp(dir, now)
+	require.NoError(t, err)
+	assert.Equal(t, "201906121201", prefix)
+}
+
+func TestGoIdentifier(t *testing.T) {
+	assert.Equal(t, "migration201906121200CreatePeople", goIdentifier("201906121200_create_people"))
+}
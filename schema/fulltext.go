@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// FullTextSearch sets up the full-text search of the text columns of a
+// table in a single migration.
+//
+// On PostgreSQL, the migration adds a tsvector column kept up to date by a
+// trigger, fills it for the existing rows, then indexes it with a GIN index.
+// On MySQL, it creates a FULLTEXT index on the columns. The other databases
+// fail with sqlxmigrate.ErrUnsupportedDriver.
+type FullTextSearch struct {
+	// Table is the name of the table.
+	Table string
+	// Columns are the text columns searched.
+	Columns []string
+	// VectorColumn is the name of the tsvector column on PostgreSQL.
+	// Defaults to "search_vector".
+	VectorColumn string
+	// Config is the text search configuration on PostgreSQL. Defaults to
+	// "english".
+	Config string
+}
+
+// Migration returns the migration setting up the full-text search on the
+// database of the driver.
+func (f *FullTextSearch) Migration(driverName, id string) *sqlxmigrate.Migration {
+	return &sqlxmigrate.Migration{
+		ID: id,
+		MigrateTx: func(tx *sqlxmigrate.Tx) error {
+			stmts, err := f.setupSQL(driverName)
+			if err != nil {
+				return err
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		RollbackTx: func(tx *sqlxmigrate.Tx) error {
+			stmts, err := f.teardownSQL(driverName)
+			if err != nil {
+				return err
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// MatchSQL returns the condition matching the rows to the search terms of
+// the ? bindvar, ie. for
+// "SELECT id FROM people WHERE " + search.MatchSQL(driverName).
+func (f *FullTextSearch) MatchSQL(driverName string) string {
+	if databaseOf(driverName) == mysql {
+		return fmt.Sprintf("MATCH (%s) AGAINST (?)", strings.Join(f.Columns, ", "))
+	}
+	return fmt.Sprintf("%s @@ plainto_tsquery('%s', ?)", f.vectorColumn(), f.config())
+}
+
+func (f *FullTextSearch) vectorColumn() string {
+	if f.VectorColumn == "" {
+		return "search_vector"
+	}
+	return f.VectorColumn
+}
+
+func (f *FullTextSearch) config() string {
+	if f.Config == "" {
+		return "english"
+	}
+	return f.Config
+}
+
+func (f *FullTextSearch) triggerName() string {
+	return f.Table + "_" + f.vectorColumn() + "_update"
+}
+
+func (f *FullTextSearch) indexName(driverName string) string {
+	if databaseOf(driverName) == mysql {
+		return sqlxmigrate.DefaultNamingConvention.IndexName(f.Table, f.Columns...)
+	}
+	return sqlxmigrate.DefaultNamingConvention.IndexName(f.Table, f.vectorColumn())
+}
+
+// vectorSQL returns the expression of the tsvector of a row.
+func (f *FullTextSearch) vectorSQL() string {
+	values := make([]string, 0, len(f.Columns))
+	for _, c := range f.Columns {
+		values = append(values, fmt.Sprintf("coalesce(%s, '')", c))
+	}
+	return fmt.Sprintf("to_tsvector('%s', %s)", f.config(), strings.Join(values, " || ' ' || "))
+}
+
+func (f *FullTextSearch) setupSQL(driverName string) ([]string, error) {
+	switch databaseOf(driverName) {
+	case postgres:
+		// The trigger is installed before the existing rows are filled, so
+		// the rows written meanwhile are covered.
+		return []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s tsvector", f.Table, f.vectorColumn()),
+			fmt.Sprintf("CREATE TRIGGER %s BEFORE INSERT OR UPDATE ON %s FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(%s, 'pg_catalog.%s', %s)",
+				f.triggerName(), f.Table, f.vectorColumn(), f.config(), strings.Join(f.Columns, ", ")),
+			fmt.Sprintf("UPDATE %s SET %s = %s", f.Table, f.vectorColumn(), f.vectorSQL()),
+			CreateGINIndex(f.Table, f.vectorColumn()).SQL(driverName),
+		}, nil
+	case mysql:
+		return []string{
+			fmt.Sprintf("CREATE FULLTEXT INDEX %s ON %s (%s)", f.indexName(driverName), f.Table, strings.Join(f.Columns, ", ")),
+		}, nil
+	default:
+		return nil, sqlxmigrate.ErrUnsupportedDriver
+	}
+}
+
+func (f *FullTextSearch) teardownSQL(driverName string) ([]string, error) {
+	switch databaseOf(driverName) {
+	case postgres:
+		return []string{
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", f.triggerName(), f.Table),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", f.Table, f.vectorColumn()),
+		}, nil
+	case mysql:
+		return []string{
+			fmt.Sprintf("DROP INDEX %s ON %s", f.indexName(driverName), f.Table),
+		}, nil
+	default:
+		return nil, sqlxmigrate.ErrUnsupportedDriver
+	}
+}
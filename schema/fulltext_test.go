@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+	"github.com/geeks-accelerator/sqlxmigrate/sqlxmigratetest"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFullTextSearchSQL(t *testing.T) {
+	search := &FullTextSearch{Table: "books", Columns: []string{"title", "summary"}}
+
+	stmts, err := search.setupSQL("postgres")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"ALTER TABLE books ADD COLUMN search_vector tsvector",
+		"CREATE TRIGGER books_search_vector_update BEFORE INSERT OR UPDATE ON books FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.english', title, summary)",
+		"UPDATE books SET search_vector = to_tsvector('english', coalesce(title, '') || ' ' || coalesce(summary, ''))",
+		"CREATE INDEX idx_books_search_vector ON books USING GIN (search_vector)",
+	}, stmts)
+	stmts, err = search.teardownSQL("postgres")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"DROP TRIGGER IF EXISTS books_search_vector_update ON books",
+		"ALTER TABLE books DROP COLUMN search_vector",
+	}, stmts)
+	assert.Equal(t, "search_vector @@ plainto_tsquery('english', ?)", search.MatchSQL("postgres"))
+
+	stmts, err = search.setupSQL("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CREATE FULLTEXT INDEX idx_books_title_summary ON books (title, summary)"}, stmts)
+	stmts, err = search.teardownSQL("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DROP INDEX idx_books_title_summary ON books"}, stmts)
+	assert.Equal(t, "MATCH (title, summary) AGAINST (?)", search.MatchSQL("mysql"))
+
+	_, err = search.setupSQL("sqlite3")
+	assert.Equal(t, sqlxmigrate.ErrUnsupportedDriver, err)
+}
+
+func TestFullTextSearch(t *testing.T) {
+	sqlxmigratetest.ForEachDialect(t, func(t *testing.T, db *sqlx.DB) {
+		options := *sqlxmigrate.DefaultOptions
+		options.TableName = "fulltext_migrations"
+
+		_, err := db.Exec("CREATE TABLE fulltext_books (id int PRIMARY KEY, title varchar(100), summary text)")
+		require.NoError(t, err)
+		defer func() {
+			_, err := db.Exec("DROP TABLE fulltext_books")
+			assert.NoError(t, err)
+			_, err = db.Exec("DROP TABLE fulltext_migrations")
+			assert.NoError(t, err)
+		}()
+		_, err = db.Exec(`INSERT INTO fulltext_books (id, title, summary) VALUES (1, 'Migrations', 'Evolving the schemas of databases')`)
+		require.NoError(t, err)
+
+		search := &FullTextSearch{Table: "fulltext_books", Columns: []string{"title", "summary"}}
+		m := sqlxmigrate.New(db, &options, []*sqlxmigrate.Migration{search.Migration(db.DriverName(), "201608301400")})
+
+		err = m.Migrate()
+		if databaseOf(db.DriverName()) != postgres && databaseOf(db.DriverName()) != mysql {
+			assert.True(t, errors.Is(err, sqlxmigrate.ErrUnsupportedDriver))
+			return
+		}
+		require.NoError(t, err)
+
+		_, err = db.Exec(`INSERT INTO fulltext_books (id, title, summary) VALUES (2, 'Cooking', 'Recipes for databases')`)
+		require.NoError(t, err)
+
+		var ids []int
+		require.NoError(t, db.Select(&ids, db.Rebind("SELECT id FROM fulltext_books WHERE "+search.MatchSQL(db.DriverName())+" ORDER BY id"), "databases"))
+		assert.Equal(t, []int{1, 2}, ids)
+
+		require.NoError(t, m.RollbackLast())
+	})
+}
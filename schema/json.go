@@ -0,0 +1,233 @@
+package schema
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+	"github.com/jmoiron/sqlx"
+)
+
+// InvalidJSONError is returned when a row holds text which isn't valid JSON
+// while its column is converted to JSON.
+type InvalidJSONError struct {
+	Table  string
+	Column string
+	Key    int64
+}
+
+func (e *InvalidJSONError) Error() string {
+	return fmt.Sprintf(`schema: Column "%s" of the row %d of table "%s" isn't valid JSON`, e.Column, e.Key, e.Table)
+}
+
+// Hint implements sqlxmigrate.Hinter.
+func (e *InvalidJSONError) Hint() string {
+	return "The column wasn't converted. Fix or clear the invalid rows in a migration running before the conversion."
+}
+
+// jsonPath returns the expression extracting a top-level key of a JSON
+// column as text.
+func jsonPath(driverName, column, key string) string {
+	key = strings.ReplaceAll(key, "'", "''")
+	switch databaseOf(driverName) {
+	case postgres:
+		return fmt.Sprintf("(%s->>'%s')", column, key)
+	case mysql:
+		// The collation of the cast matches the one of ->>, so queries
+		// using ->> can use the index.
+		return fmt.Sprintf("(CAST(%s->>'$.%s' AS CHAR(255)) COLLATE utf8mb4_bin)", column, key)
+	case sqlServer:
+		return fmt.Sprintf("JSON_VALUE(%s, '$.%s')", column, key)
+	default:
+		return fmt.Sprintf("json_extract(%s, '$.%s')", column, key)
+	}
+}
+
+type jsonIndex struct {
+	table  string
+	column string
+	key    string
+}
+
+// CreateJSONIndex returns the statement creating an index on a top-level key
+// of a JSON column, named by sqlxmigrate.DefaultNamingConvention. MySQL
+// indexes expressions since 8.0.13, and SQL Server indexes a computed column
+// named <column>_<key> added with the index.
+func CreateJSONIndex(table, column, key string) Statement {
+	return &jsonIndex{table: table, column: column, key: key}
+}
+
+func (i *jsonIndex) SQL(driverName string) string {
+	name := sqlxmigrate.DefaultNamingConvention.IndexName(i.table, i.column, i.key)
+	path := jsonPath(driverName, i.column, i.key)
+	if databaseOf(driverName) == sqlServer {
+		computed := i.column + "_" + i.key
+		return fmt.Sprintf("ALTER TABLE %s ADD %s AS %s; CREATE INDEX %s ON %s (%s)", i.table, computed, path, name, i.table, computed)
+	}
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", name, i.table, path)
+}
+
+type ginIndex struct {
+	table  string
+	column string
+}
+
+// CreateGINIndex returns the statement creating a GIN index on a jsonb
+// column, which serves the containment and existence operators of any key.
+// It is only supported by PostgreSQL, CreateJSONIndex indexes a key on every
+// database.
+func CreateGINIndex(table, column string) Statement {
+	return &ginIndex{table: table, column: column}
+}
+
+func (i *ginIndex) SQL(driverName string) string {
+	name := sqlxmigrate.DefaultNamingConvention.IndexName(i.table, i.column)
+	return fmt.Sprintf("CREATE INDEX %s ON %s USING GIN (%s)", name, i.table, i.column)
+}
+
+// JSONConversion converts a text column holding JSON documents to the JSON
+// type of the database. The existing rows are first validated in batches, so
+// the conversion fails with an InvalidJSONError naming the first invalid row
+// instead of a cast error, without changing the column.
+//
+// The column becomes jsonb on PostgreSQL and JSON on MySQL. SQLite and SQL
+// Server have no JSON type: the column stays text, SQL Server checking the
+// new values with an ISJSON constraint.
+type JSONConversion struct {
+	// Table is the name of the table.
+	Table string
+	// Column is the name of the text column.
+	Column string
+	// Type is the type of the column restored on rollback. Defaults to the
+	// Text type.
+	Type string
+	// KeyColumn is the integer primary key used to validate in batches.
+	KeyColumn string
+	// BatchSize is the number of rows validated per query. Defaults to 1000.
+	BatchSize int
+}
+
+// Migration returns the migration converting the column on the database of
+// the driver.
+func (c *JSONConversion) Migration(driverName, id string) *sqlxmigrate.Migration {
+	return &sqlxmigrate.Migration{
+		ID: id,
+		MigrateTx: func(tx *sqlxmigrate.Tx) error {
+			if err := c.validate(tx, driverName); err != nil {
+				return err
+			}
+			if stmt := c.convertSQL(driverName); stmt != "" {
+				_, err := tx.Exec(stmt)
+				return err
+			}
+			return nil
+		},
+		RollbackTx: func(tx *sqlxmigrate.Tx) error {
+			if stmt := c.revertSQL(driverName); stmt != "" {
+				_, err := tx.Exec(stmt)
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func (c *JSONConversion) constraintName() string {
+	return "ck_" + c.Table + "_" + c.Column + "_json"
+}
+
+func (c *JSONConversion) convertSQL(driverName string) string {
+	switch databaseOf(driverName) {
+	case postgres:
+		return fmt.Sprintf("ALTER TABLE %[1]s ALTER COLUMN %[2]s TYPE jsonb USING %[2]s::jsonb", c.Table, c.Column)
+	case mysql:
+		return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s JSON", c.Table, c.Column)
+	case sqlServer:
+		return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (ISJSON(%s) = 1)", c.Table, c.constraintName(), c.Column)
+	case sqlite:
+		return ""
+	default:
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DATA TYPE JSON", c.Table, c.Column)
+	}
+}
+
+func (c *JSONConversion) revertSQL(driverName string) string {
+	typ := c.Type
+	if typ == "" {
+		typ = Text
+	}
+	typ = columnType(driverName, typ)
+
+	switch databaseOf(driverName) {
+	case postgres:
+		return fmt.Sprintf("ALTER TABLE %[1]s ALTER COLUMN %[2]s TYPE %[3]s USING %[2]s::text", c.Table, c.Column, typ)
+	case mysql:
+		return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", c.Table, c.Column, typ)
+	case sqlServer:
+		return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", c.Table, c.constraintName())
+	case sqlite:
+		return ""
+	default:
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DATA TYPE %s", c.Table, c.Column, typ)
+	}
+}
+
+// batchQuery returns the query selecting the keys and values of a batch of
+// rows following a key.
+func (c *JSONConversion) batchQuery(driverName string, batchSize int) string {
+	if databaseOf(driverName) == sqlServer {
+		return fmt.Sprintf("SELECT TOP %[4]d %[1]s, %[2]s FROM %[3]s WHERE %[1]s > @p1 ORDER BY %[1]s", c.KeyColumn, c.Column, c.Table, batchSize)
+	}
+	query := fmt.Sprintf("SELECT %[1]s, %[2]s FROM %[3]s WHERE %[1]s > ? ORDER BY %[1]s LIMIT %[4]d", c.KeyColumn, c.Column, c.Table, batchSize)
+	return sqlx.Rebind(sqlx.BindType(driverName), query)
+}
+
+// validate checks the values of the column are valid JSON, in batches of
+// rows ordered by key.
+func (c *JSONConversion) validate(tx *sqlxmigrate.Tx, driverName string) error {
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	query := c.batchQuery(driverName, batchSize)
+
+	var last int64
+	if err := tx.QueryRow(fmt.Sprintf("SELECT coalesce(min(%s), 0) - 1 FROM %s", c.KeyColumn, c.Table)).Scan(&last); err != nil {
+		return err
+	}
+
+	for {
+		n, err := c.validateBatch(tx, query, &last)
+		if err != nil {
+			return err
+		}
+		if n < batchSize {
+			return nil
+		}
+	}
+}
+
+// validateBatch validates the rows following the last key, moving it to the
+// key of the last row, and returns the number of rows.
+func (c *JSONConversion) validateBatch(tx *sqlxmigrate.Tx, query string, last *int64) (int, error) {
+	rows, err := tx.Query(query, *last)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(last, &value); err != nil {
+			return n, err
+		}
+		n++
+		if value.Valid && !json.Valid([]byte(value.String)) {
+			return n, &InvalidJSONError{Table: c.Table, Column: c.Column, Key: *last}
+		}
+	}
+	return n, rows.Err()
+}
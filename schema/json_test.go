@@ -0,0 +1,93 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+	"github.com/geeks-accelerator/sqlxmigrate/sqlxmigratetest"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONColumn(t *testing.T) {
+	for driverName, want := range map[string]string{
+		"postgres":  "ALTER TABLE people ADD COLUMN settings jsonb",
+		"mysql":     "ALTER TABLE people ADD COLUMN settings JSON",
+		"sqlite3":   "ALTER TABLE people ADD COLUMN settings TEXT",
+		"sqlserver": "ALTER TABLE people ADD settings NVARCHAR(MAX)",
+	} {
+		assert.Equal(t, want, AddColumn("people", "settings", JSON).SQL(driverName), driverName)
+	}
+}
+
+func TestCreateJSONIndex(t *testing.T) {
+	index := CreateJSONIndex("people", "settings", "locale")
+	for driverName, want := range map[string]string{
+		"postgres":  "CREATE INDEX idx_people_settings_locale ON people ((settings->>'locale'))",
+		"mysql":     "CREATE INDEX idx_people_settings_locale ON people ((CAST(settings->>'$.locale' AS CHAR(255)) COLLATE utf8mb4_bin))",
+		"sqlite3":   "CREATE INDEX idx_people_settings_locale ON people (json_extract(settings, '$.locale'))",
+		"sqlserver": "ALTER TABLE people ADD settings_locale AS JSON_VALUE(settings, '$.locale'); CREATE INDEX idx_people_settings_locale ON people (settings_locale)",
+	} {
+		assert.Equal(t, want, index.SQL(driverName), driverName)
+	}
+
+	assert.Equal(t, "CREATE INDEX idx_people_settings ON people USING GIN (settings)", CreateGINIndex("people", "settings").SQL("postgres"))
+}
+
+func TestJSONConversionSQL(t *testing.T) {
+	c := &JSONConversion{Table: "people", Column: "settings", KeyColumn: "id"}
+	for driverName, want := range map[string][2]string{
+		"postgres": {
+			"ALTER TABLE people ALTER COLUMN settings TYPE jsonb USING settings::jsonb",
+			"ALTER TABLE people ALTER COLUMN settings TYPE text USING settings::text",
+		},
+		"mysql": {
+			"ALTER TABLE people MODIFY COLUMN settings JSON",
+			"ALTER TABLE people MODIFY COLUMN settings TEXT",
+		},
+		"sqlserver": {
+			"ALTER TABLE people ADD CONSTRAINT ck_people_settings_json CHECK (ISJSON(settings) = 1)",
+			"ALTER TABLE people DROP CONSTRAINT ck_people_settings_json",
+		},
+		"sqlite3": {"", ""},
+	} {
+		assert.Equal(t, want[0], c.convertSQL(driverName), driverName)
+		assert.Equal(t, want[1], c.revertSQL(driverName), driverName)
+	}
+
+	assert.Equal(t, "SELECT id, settings FROM people WHERE id > $1 ORDER BY id LIMIT 10", c.batchQuery("postgres", 10))
+	assert.Equal(t, "SELECT TOP 10 id, settings FROM people WHERE id > @p1 ORDER BY id", c.batchQuery("mssql", 10))
+}
+
+func TestJSONConversion(t *testing.T) {
+	sqlxmigratetest.ForEachDialect(t, func(t *testing.T, db *sqlx.DB) {
+		options := *sqlxmigrate.DefaultOptions
+		options.TableName = "json_migrations"
+
+		_, err := db.Exec("CREATE TABLE json_people (id int PRIMARY KEY, settings varchar(100))")
+		require.NoError(t, err)
+		defer func() {
+			_, err := db.Exec("DROP TABLE json_people")
+			assert.NoError(t, err)
+			_, err = db.Exec("DROP TABLE json_migrations")
+			assert.NoError(t, err)
+		}()
+		_, err = db.Exec(`INSERT INTO json_people (id, settings) VALUES (1, '{"locale": "fr"}'), (2, NULL), (3, '[1, 2]'), (4, '{"locale": ')`)
+		require.NoError(t, err)
+
+		conversion := &JSONConversion{Table: "json_people", Column: "settings", Type: "varchar(100)", KeyColumn: "id", BatchSize: 2}
+		m := sqlxmigrate.New(db, &options, []*sqlxmigrate.Migration{conversion.Migration(db.DriverName(), "201608301400")})
+
+		var invalid *InvalidJSONError
+		require.True(t, errors.As(m.Migrate(), &invalid))
+		assert.Equal(t, int64(4), invalid.Key)
+		assert.NotEmpty(t, sqlxmigrate.Hint(invalid))
+
+		_, err = db.Exec(`UPDATE json_people SET settings = '{}' WHERE id = 4`)
+		require.NoError(t, err)
+		require.NoError(t, m.Migrate())
+		require.NoError(t, m.RollbackLast())
+	})
+}
@@ -0,0 +1,305 @@
+// Package schema builds the statements of common schema changes for the
+// database of a driver, so simple migrations don't need hand-written SQL per
+// database:
+//
+//	MigrateTx: func(tx *sqlxmigrate.Tx) error {
+//		_, err := tx.Exec(schema.CreateTable("people").
+//			Column("id", schema.Serial, schema.PrimaryKey).
+//			Column("name", schema.Text, schema.NotNull).
+//			Column("created_at", schema.Timestamp, schema.NotNull, schema.DefaultNow).
+//			SQL(db.DriverName()))
+//		return err
+//	},
+//
+// The portable types, ie. Serial or Text, are translated for the database,
+// the other types are used as is.
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+)
+
+// The portable types of the columns.
+const (
+	// Serial is an auto-incremented integer.
+	Serial = "serial"
+	// BigSerial is an auto-incremented 64 bits integer.
+	BigSerial = "bigserial"
+	// Text is a string of unlimited length.
+	Text = "text"
+	// Boolean is a boolean.
+	Boolean = "boolean"
+	// Timestamp is a point in time, see sqlxmigrate.TimestampType.
+	Timestamp = "timestamptz"
+	// JSON is a JSON document: jsonb on PostgreSQL, and text on SQLite and
+	// SQL Server, which have no JSON type.
+	JSON = "json"
+)
+
+// Statement is a schema change.
+type Statement interface {
+	// SQL returns the statement for the database of the driver.
+	SQL(driverName string) string
+}
+
+type database int
+
+const (
+	ansi database = iota
+	postgres
+	mysql
+	sqlite
+	sqlServer
+)
+
+func databaseOf(driverName string) database {
+	switch {
+	case strings.Contains(driverName, "postgres") || strings.Contains(driverName, "pgx"):
+		return postgres
+	case strings.Contains(driverName, "mysql"):
+		return mysql
+	case strings.Contains(driverName, "sqlite"):
+		return sqlite
+	case strings.Contains(driverName, "sqlserver") || strings.Contains(driverName, "mssql"):
+		return sqlServer
+	default:
+		return ansi
+	}
+}
+
+// columnTypes are the portable types of each database.
+var columnTypes = map[string]map[database]string{
+	Serial: {
+		ansi:      "INTEGER GENERATED BY DEFAULT AS IDENTITY",
+		postgres:  "serial",
+		mysql:     "INT AUTO_INCREMENT",
+		sqlite:    "INTEGER",
+		sqlServer: "INT IDENTITY(1,1)",
+	},
+	BigSerial: {
+		ansi:      "BIGINT GENERATED BY DEFAULT AS IDENTITY",
+		postgres:  "bigserial",
+		mysql:     "BIGINT AUTO_INCREMENT",
+		sqlite:    "INTEGER",
+		sqlServer: "BIGINT IDENTITY(1,1)",
+	},
+	Text: {
+		ansi:      "TEXT",
+		postgres:  "text",
+		mysql:     "TEXT",
+		sqlite:    "TEXT",
+		sqlServer: "NVARCHAR(MAX)",
+	},
+	Boolean: {
+		ansi:      "BOOLEAN",
+		postgres:  "boolean",
+		mysql:     "BOOLEAN",
+		sqlite:    "BOOLEAN",
+		sqlServer: "BIT",
+	},
+	JSON: {
+		ansi:      "JSON",
+		postgres:  "jsonb",
+		mysql:     "JSON",
+		sqlite:    "TEXT",
+		sqlServer: "NVARCHAR(MAX)",
+	},
+}
+
+// columnType returns the type of a column for the database of the driver.
+func columnType(driverName, typ string) string {
+	if typ == Timestamp {
+		return sqlxmigrate.TimestampType(driverName)
+	}
+	if types, ok := columnTypes[typ]; ok {
+		return types[databaseOf(driverName)]
+	}
+	return typ
+}
+
+// ColumnOption is a constraint or the default of a column.
+type ColumnOption func(*column)
+
+var (
+	// PrimaryKey makes the column the primary key of the table, or part of
+	// it when several columns are.
+	PrimaryKey ColumnOption = func(c *column) { c.primaryKey = true }
+	// NotNull makes the column NOT NULL.
+	NotNull ColumnOption = func(c *column) { c.notNull = true }
+	// Unique makes the values of the column unique.
+	Unique ColumnOption = func(c *column) { c.unique = true }
+	// DefaultNow defaults the column to the current time, see
+	// sqlxmigrate.CurrentTimestamp.
+	DefaultNow ColumnOption = func(c *column) { c.defaultNow = true }
+)
+
+// Default defaults the column to the given SQL expression, ie. "'active'".
+func Default(expr string) ColumnOption {
+	return func(c *column) { c.defaultExpr = expr }
+}
+
+// References makes the column a foreign key to the column of a table.
+func References(table, col string) ColumnOption {
+	return func(c *column) { c.references = fmt.Sprintf("%s (%s)", table, col) }
+}
+
+type column struct {
+	name        string
+	typ         string
+	primaryKey  bool
+	notNull     bool
+	unique      bool
+	defaultNow  bool
+	defaultExpr string
+	references  string
+}
+
+func newColumn(name, typ string, opts []ColumnOption) *column {
+	c := &column{name: name, typ: typ}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// definition returns the definition of the column, with its primary key
+// unless the primary key of the table spans several columns.
+func (c *column) definition(driverName string, inlinePrimaryKey bool) string {
+	def := []string{c.name, columnType(driverName, c.typ)}
+	if c.notNull {
+		def = append(def, "NOT NULL")
+	}
+	if c.defaultNow {
+		def = append(def, "DEFAULT "+sqlxmigrate.CurrentTimestamp(driverName))
+	} else if c.defaultExpr != "" {
+		def = append(def, "DEFAULT "+c.defaultExpr)
+	}
+	if c.primaryKey && inlinePrimaryKey {
+		def = append(def, "PRIMARY KEY")
+	}
+	if c.unique {
+		def = append(def, "UNIQUE")
+	}
+	if c.references != "" {
+		def = append(def, "REFERENCES "+c.references)
+	}
+	return strings.Join(def, " ")
+}
+
+// Table builds a CREATE TABLE statement.
+type Table struct {
+	name    string
+	columns []*column
+}
+
+// CreateTable returns the builder of the statement creating a table.
+func CreateTable(name string) *Table {
+	return &Table{name: name}
+}
+
+// Column adds a column to the table.
+func (t *Table) Column(name, typ string, opts ...ColumnOption) *Table {
+	t.columns = append(t.columns, newColumn(name, typ, opts))
+	return t
+}
+
+// SQL implements Statement.
+func (t *Table) SQL(driverName string) string {
+	var primaryKey []string
+	for _, c := range t.columns {
+		if c.primaryKey {
+			primaryKey = append(primaryKey, c.name)
+		}
+	}
+
+	defs := make([]string, 0, len(t.columns)+1)
+	for _, c := range t.columns {
+		defs = append(defs, c.definition(driverName, len(primaryKey) == 1))
+	}
+	if len(primaryKey) > 1 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", ")))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s)", t.name, strings.Join(defs, ", "))
+}
+
+type addColumn struct {
+	table  string
+	column *column
+}
+
+// AddColumn returns the statement adding a column to a table.
+func AddColumn(table, name, typ string, opts ...ColumnOption) Statement {
+	return &addColumn{table: table, column: newColumn(name, typ, opts)}
+}
+
+// SQL leaves out the COLUMN keyword on SQL Server, which rejects it.
+func (a *addColumn) SQL(driverName string) string {
+	def := a.column.definition(driverName, true)
+	if databaseOf(driverName) == sqlServer {
+		return fmt.Sprintf("ALTER TABLE %s ADD %s", a.table, def)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", a.table, def)
+}
+
+type dropColumn struct {
+	table string
+	name  string
+}
+
+// DropColumn returns the statement dropping a column of a table. SQLite
+// drops columns since 3.35.
+func DropColumn(table, name string) Statement {
+	return &dropColumn{table: table, name: name}
+}
+
+// SQL returns the statement, which is the same for every database.
+func (d *dropColumn) SQL(driverName string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", d.table, d.name)
+}
+
+// Index builds a CREATE INDEX statement.
+type Index struct {
+	table   string
+	columns []string
+	name    string
+	unique  bool
+}
+
+// CreateIndex returns the builder of the statement creating an index on the
+// columns of a table, named by sqlxmigrate.DefaultNamingConvention.
+func CreateIndex(table string, columns ...string) *Index {
+	return &Index{table: table, columns: columns}
+}
+
+// Named names the index.
+func (i *Index) Named(name string) *Index {
+	i.name = name
+	return i
+}
+
+// Unique makes the index unique.
+func (i *Index) Unique() *Index {
+	i.unique = true
+	return i
+}
+
+// SQL returns the statement, which is the same for every database.
+func (i *Index) SQL(driverName string) string {
+	name := i.name
+	if name == "" {
+		if i.unique {
+			name = sqlxmigrate.DefaultNamingConvention.UniqueIndexName(i.table, i.columns...)
+		} else {
+			name = sqlxmigrate.DefaultNamingConvention.IndexName(i.table, i.columns...)
+		}
+	}
+
+	unique := ""
+	if i.unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, name, i.table, strings.Join(i.columns, ", "))
+}
@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+	"github.com/geeks-accelerator/sqlxmigrate/sqlxmigratetest"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTable(t *testing.T) {
+	table := CreateTable("people").
+		Column("id", Serial, PrimaryKey).
+		Column("name", Text, NotNull, Unique).
+		Column("status", "varchar(20)", Default("'active'")).
+		Column("created_at", Timestamp, NotNull, DefaultNow)
+
+	for driverName, want := range map[string]string{
+		"postgres":  "CREATE TABLE people (id serial PRIMARY KEY, name text NOT NULL UNIQUE, status varchar(20) DEFAULT 'active', created_at timestamptz NOT NULL DEFAULT now())",
+		"mysql":     "CREATE TABLE people (id INT AUTO_INCREMENT PRIMARY KEY, name TEXT NOT NULL UNIQUE, status varchar(20) DEFAULT 'active', created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6))",
+		"sqlite3":   "CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, status varchar(20) DEFAULT 'active', created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		"sqlserver": "CREATE TABLE people (id INT IDENTITY(1,1) PRIMARY KEY, name NVARCHAR(MAX) NOT NULL UNIQUE, status varchar(20) DEFAULT 'active', created_at DATETIMEOFFSET(6) NOT NULL DEFAULT SYSDATETIMEOFFSET())",
+	} {
+		assert.Equal(t, want, table.SQL(driverName), driverName)
+	}
+
+	table = CreateTable("memberships").
+		Column("person_id", "int", PrimaryKey, References("people", "id")).
+		Column("group_id", "int", PrimaryKey)
+	assert.Equal(t, "CREATE TABLE memberships (person_id int REFERENCES people (id), group_id int, PRIMARY KEY (person_id, group_id))", table.SQL("postgres"))
+}
+
+func TestAlterTable(t *testing.T) {
+	add := AddColumn("people", "active", Boolean, NotNull, Default("1"))
+	assert.Equal(t, "ALTER TABLE people ADD COLUMN active boolean NOT NULL DEFAULT 1", add.SQL("postgres"))
+	assert.Equal(t, "ALTER TABLE people ADD active BIT NOT NULL DEFAULT 1", add.SQL("mssql"))
+
+	assert.Equal(t, "ALTER TABLE people DROP COLUMN active", DropColumn("people", "active").SQL("mysql"))
+}
+
+func TestCreateIndex(t *testing.T) {
+	assert.Equal(t, "CREATE INDEX idx_people_name ON people (name)", CreateIndex("people", "name").SQL("postgres"))
+	assert.Equal(t, "CREATE UNIQUE INDEX uq_people_name_email ON people (name, email)", CreateIndex("people", "name", "email").Unique().SQL("postgres"))
+	assert.Equal(t, "CREATE INDEX people_by_name ON people (name)", CreateIndex("people", "name").Named("people_by_name").SQL("postgres"))
+}
+
+func TestMigrate(t *testing.T) {
+	sqlxmigratetest.ForEachDialect(t, func(t *testing.T, db *sqlx.DB) {
+		driverName := db.DriverName()
+		exec := func(tx *sqlxmigrate.Tx, stmts ...Statement) error {
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt.SQL(driverName)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// The migration table isn't shared with the tests of the other
+		// packages, which may run at the same time.
+		options := *sqlxmigrate.DefaultOptions
+		options.TableName = "builder_migrations"
+		m := sqlxmigrate.New(db, &options, []*sqlxmigrate.Migration{
+			{
+				ID: "201608301400",
+				MigrateTx: func(tx *sqlxmigrate.Tx) error {
+					return exec(tx,
+						CreateTable("builder_people").
+							Column("id", Serial, PrimaryKey).
+							Column("name", "varchar(100)", NotNull).
+							Column("created_at", Timestamp, NotNull, DefaultNow),
+						CreateIndex("builder_people", "name"))
+				},
+				RollbackTx: func(tx *sqlxmigrate.Tx) error {
+					_, err := tx.Exec("DROP TABLE builder_people")
+					return err
+				},
+			},
+			{
+				ID: "201608301430",
+				MigrateTx: func(tx *sqlxmigrate.Tx) error {
+					return exec(tx, AddColumn("builder_people", "nickname", "varchar(100)"))
+				},
+				RollbackTx: func(tx *sqlxmigrate.Tx) error {
+					return exec(tx, DropColumn("builder_people", "nickname"))
+				},
+			},
+		})
+
+		require.NoError(t, m.Migrate())
+		_, err := db.Exec("INSERT INTO builder_people (name, nickname) VALUES ('alice', 'al')")
+		assert.NoError(t, err)
+
+		// SQLite drops columns since 3.35, which go-sqlite3 doesn't embed yet.
+		if driverName != "sqlite3" {
+			require.NoError(t, m.RollbackLast())
+		}
+		_, err = db.Exec("DROP TABLE builder_people")
+		assert.NoError(t, err)
+		_, err = db.Exec("DROP TABLE builder_migrations")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,175 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ShardPolicy is what a ShardedMigrator does once the migration of a shard
+// failed.
+type ShardPolicy int
+
+const (
+	// ShardContinue migrates the other shards, reporting every failure.
+	ShardContinue ShardPolicy = iota
+	// ShardStopOnFirstError starts no other shard once one failed. The
+	// shards being migrated complete their run.
+	ShardStopOnFirstError
+)
+
+// ShardError is the error a shard failed with.
+type ShardError struct {
+	// Shard is the index of the database of the shard.
+	Shard int
+	Err   error
+}
+
+func (e *ShardError) Error() string {
+	return fmt.Sprintf("shard %d: %v", e.Shard, e.Err)
+}
+
+func (e *ShardError) Unwrap() error {
+	return e.Err
+}
+
+// ShardsError is returned when the migration of shards failed.
+type ShardsError struct {
+	// Errors are the errors of the failed shards, by shard.
+	Errors []*ShardError
+	// Skipped are the shards not migrated after a failure with
+	// ShardStopOnFirstError.
+	Skipped []int
+}
+
+func (e *ShardsError) Error() string {
+	errs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err.Error()
+	}
+	msg := fmt.Sprintf("sqlxmigrate: %d shards failed: %s", len(e.Errors), strings.Join(errs, "; "))
+	if len(e.Skipped) > 0 {
+		msg += fmt.Sprintf(" (%d shards skipped)", len(e.Skipped))
+	}
+	return msg
+}
+
+// Hint implements Hinter.
+func (e *ShardsError) Hint() string {
+	return "The shards that failed and the skipped ones are migrated by the next run, the others are left unchanged by it."
+}
+
+// ShardedMigrator applies the same migrations to many databases, ie. the
+// shards of a partitioned application, running a bounded number of them
+// concurrently. Each shard has its own Sqlxmigrate, so it records its
+// migrations in its own migration table.
+type ShardedMigrator struct {
+	// Concurrency is how many shards are migrated at once. Defaults to 4.
+	Concurrency int
+	// Policy is what happens once a shard failed. Defaults to
+	// ShardContinue.
+	Policy ShardPolicy
+
+	shards []*Sqlxmigrate
+}
+
+// NewShardedMigrator returns the migrator applying the migrations to each
+// database with the given options.
+func NewShardedMigrator(dbs []*sqlx.DB, options *Options, migrations []*Migration) *ShardedMigrator {
+	s := &ShardedMigrator{Concurrency: 4}
+	for _, db := range dbs {
+		s.shards = append(s.shards, New(db, options, migrations))
+	}
+	return s
+}
+
+// Shards returns the Sqlxmigrate of each database, in order, ie. to set their
+// logger or register hooks.
+func (s *ShardedMigrator) Shards() []*Sqlxmigrate {
+	return s.shards
+}
+
+// Migrate executes the migrations that did not run yet on every shard.
+func (s *ShardedMigrator) Migrate() error {
+	return s.MigrateContext(context.Background())
+}
+
+// MigrateContext is Migrate with a context aborting the runs when done.
+func (s *ShardedMigrator) MigrateContext(ctx context.Context) error {
+	_, err := s.MigrateWithResultContext(ctx)
+	return err
+}
+
+// MigrateWithResult executes the migrations that did not run yet on every
+// shard and reports the outcome of the run of each shard, nil for a shard
+// skipped. The error is a *ShardsError once a shard failed.
+func (s *ShardedMigrator) MigrateWithResult() ([]*Result, error) {
+	return s.MigrateWithResultContext(context.Background())
+}
+
+// MigrateWithResultContext is MigrateWithResult with a context aborting the
+// runs when done.
+func (s *ShardedMigrator) MigrateWithResultContext(ctx context.Context) ([]*Result, error) {
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		failed  bool
+		results = make([]*Result, len(s.shards))
+		errs    = make([]error, len(s.shards))
+		skipped []int
+	)
+
+	sem := make(chan struct{}, concurrency)
+	for i, g := range s.shards {
+		sem <- struct{}{}
+
+		mu.Lock()
+		stop := failed && s.Policy == ShardStopOnFirstError
+		mu.Unlock()
+		if stop {
+			<-sem
+			skipped = append(skipped, i)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, g *Sqlxmigrate) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			res, err := g.MigrateWithResultContext(ctx)
+			if err != nil {
+				g.logf("Shard %d - failed - %v", i, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[i], errs[i] = res, err
+			if err != nil {
+				failed = true
+			}
+		}(i, g)
+	}
+	wg.Wait()
+
+	shardsErr := &ShardsError{Skipped: skipped}
+	for i, err := range errs {
+		if err != nil {
+			shardsErr.Errors = append(shardsErr.Errors, &ShardError{Shard: i, Err: err})
+		}
+	}
+	if len(shardsErr.Errors) > 0 {
+		return results, shardsErr
+	}
+	return results, nil
+}
@@ -0,0 +1,96 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openShards opens SQLite databases in a temporary directory.
+func openShards(t *testing.T, n int) []*sqlx.DB {
+	dir := t.TempDir()
+	var dbs []*sqlx.DB
+	for i := 0; i < n; i++ {
+		db, err := sqlx.Open("sqlite3", filepath.Join(dir, fmt.Sprintf("shard%d.db", i)))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		dbs = append(dbs, db)
+	}
+	return dbs
+}
+
+func TestShardedMigrator(t *testing.T) {
+	forEachDatabase(t, func(*sqlx.DB) {
+		dbs := openShards(t, 5)
+		s := NewShardedMigrator(dbs, DefaultOptions, migrations)
+		s.Concurrency = 2
+
+		results, err := s.MigrateWithResult()
+		require.NoError(t, err)
+		require.Len(t, results, 5)
+		for i, db := range dbs {
+			assert.Equal(t, []string{"201608301400", "201608301430"}, resultIDs(results[i]), "shard %d", i)
+
+			var count int
+			require.NoError(t, db.Get(&count, "SELECT count(*) FROM migrations"))
+			assert.Equal(t, 2, count, "shard %d", i)
+		}
+
+		results, err = s.MigrateWithResult()
+		require.NoError(t, err)
+		for _, res := range results {
+			assert.Empty(t, res.Migrations)
+		}
+	}, "sqlite3")
+}
+
+func TestShardedMigratorFailure(t *testing.T) {
+	forEachDatabase(t, func(*sqlx.DB) {
+		dbs := openShards(t, 4)
+		_, err := dbs[1].Exec(`CREATE TABLE people (id int)`)
+		require.NoError(t, err)
+
+		s := NewShardedMigrator(dbs, DefaultOptions, migrations)
+		results, err := s.MigrateWithResult()
+
+		var shardsErr *ShardsError
+		require.True(t, errors.As(err, &shardsErr))
+		require.Len(t, shardsErr.Errors, 1)
+		assert.Equal(t, 1, shardsErr.Errors[0].Shard)
+		assert.Empty(t, shardsErr.Skipped)
+		assert.NotEmpty(t, Hint(err))
+		for _, i := range []int{0, 2, 3} {
+			assert.Equal(t, []string{"201608301400", "201608301430"}, resultIDs(results[i]), "shard %d", i)
+		}
+	}, "sqlite3")
+}
+
+func TestShardedMigratorStopOnFirstError(t *testing.T) {
+	forEachDatabase(t, func(*sqlx.DB) {
+		dbs := openShards(t, 4)
+		_, err := dbs[1].Exec(`CREATE TABLE people (id int)`)
+		require.NoError(t, err)
+
+		s := NewShardedMigrator(dbs, DefaultOptions, migrations)
+		s.Concurrency = 1
+		s.Policy = ShardStopOnFirstError
+		results, err := s.MigrateWithResult()
+
+		var shardsErr *ShardsError
+		require.True(t, errors.As(err, &shardsErr))
+		require.Len(t, shardsErr.Errors, 1)
+		assert.Equal(t, 1, shardsErr.Errors[0].Shard)
+		assert.Equal(t, []int{2, 3}, shardsErr.Skipped)
+		assert.Nil(t, results[2])
+		assert.Nil(t, results[3])
+
+		var count int
+		require.NoError(t, dbs[2].Get(&count, "SELECT count(*) FROM sqlite_master WHERE name = 'migrations'"))
+		assert.Zero(t, count)
+	}, "sqlite3")
+}
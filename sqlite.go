@@ -0,0 +1,64 @@
+package sqlxmigrate
+
+import (
+	"strings"
+)
+
+// sqliteSchema returns the name of an attached SQLite database, "main" when
+// the table name isn't qualified.
+func sqliteSchema(schema *string) string {
+	if schema == nil {
+		return "main"
+	}
+	return *schema
+}
+
+// sqliteMaster returns the catalog table of an attached SQLite database.
+func sqliteMaster(schema *string) string {
+	return sqliteSchema(schema) + ".sqlite_master"
+}
+
+// sqliteAlterConstraints are the keywords following ADD or DROP in the
+// ALTER TABLE statements changing constraints, which SQLite doesn't support.
+var sqliteAlterConstraints = map[string]bool{
+	"CONSTRAINT": true,
+	"PRIMARY":    true,
+	"FOREIGN":    true,
+	"UNIQUE":     true,
+	"CHECK":      true,
+	"INDEX":      true,
+	"KEY":        true,
+}
+
+// unsupportedSQLiteAlters returns the ALTER TABLE statements of a script that
+// SQLite can't run: it only renames tables and adds, renames or drops
+// columns, one change per statement.
+func unsupportedSQLiteAlters(driverName, script string) []string {
+	var unsupported []string
+	for _, stmt := range splitStatements(driverName, script) {
+		normalized := normalizeStatement(stmt)
+		words := strings.Fields(normalized)
+		if len(words) < 4 || words[0] != "ALTER" || words[1] != "TABLE" {
+			continue
+		}
+
+		// The action follows the table name.
+		action, next := words[3], ""
+		if len(words) > 4 {
+			next = words[4]
+		}
+
+		supported := false
+		switch action {
+		case "RENAME":
+			supported = true
+		case "ADD", "DROP":
+			multiple := strings.Contains(normalized, ", ADD ") || strings.Contains(normalized, ", DROP ")
+			supported = !sqliteAlterConstraints[next] && !multiple
+		}
+		if !supported {
+			unsupported = append(unsupported, strings.TrimSpace(stmt))
+		}
+	}
+	return unsupported
+}
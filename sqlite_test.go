@@ -0,0 +1,15 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlxmigrate
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	databases = append(databases, database{
+		name:    "sqlite3",
+		connEnv: "SQLITE_CONN_STRING",
+	})
+}
@@ -0,0 +1,68 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// columnType returns the definition of a column of the tables created by the
+// migrator for the database.
+func (g *Sqlxmigrate) columnType(definition string) string {
+	if g.isSQLServer() {
+		return sqlServerColumnType(definition)
+	}
+	return definition
+}
+
+// sqlServerColumnType maps a column definition to SQL Server. The TIMESTAMP
+// of SQL Server is a row version generated by the server, which can't be
+// written like an IDENTITY column, DATETIME2 is used instead.
+func sqlServerColumnType(definition string) string {
+	if strings.HasPrefix(definition, "TIMESTAMP") {
+		return "DATETIME2" + strings.TrimPrefix(definition, "TIMESTAMP")
+	}
+	return definition
+}
+
+// lockSQLServer waits for an application lock owned by the session of a
+// dedicated connection. sp_getapplock returns -1 once the timeout elapsed.
+func (g *Sqlxmigrate) lockSQLServer(ctx context.Context) (func(), error) {
+	conn, err := g.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := g.lockName()
+	timeout := -1
+	if g.options.LockTimeout > 0 {
+		timeout = int(g.options.LockTimeout.Milliseconds())
+	}
+
+	query := g.rebind("DECLARE @result int; EXEC @result = sp_getapplock @Resource = ?, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = ?; SELECT @result")
+	g.logf("lock %s", query)
+
+	var result int
+	if err := conn.QueryRowContext(ctx, query, name, timeout).Scan(&result); err != nil {
+		conn.Close()
+		err = queryError(err, query)
+		return nil, err
+	}
+	if result == -1 {
+		conn.Close()
+		return nil, ErrLockTimeout
+	} else if result < 0 {
+		conn.Close()
+		return nil, fmt.Errorf(`sqlxmigrate: Could not take the application lock "%s", sp_getapplock returned %d`, name, result)
+	}
+
+	return func() {
+		query := g.rebind("EXEC sp_releaseapplock @Resource = ?, @LockOwner = 'Session'")
+		g.logf("unlock %s", query)
+
+		if _, err := conn.ExecContext(context.Background(), query, name); err != nil {
+			g.logf("unlock failed - %v", err)
+		}
+		conn.Close()
+	}, nil
+}
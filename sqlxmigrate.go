@@ -1,18 +1,25 @@
 package sqlxmigrate
 
 import (
+	"context"
+	"crypto"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-	"github.com/pkg/errors"
 )
 
 const (
 	initSchemaMigrationID = "SCHEMA_INIT"
+	// lockMigrationID is the ID of the lock row of the migration table.
+	lockMigrationID = "SQLXMIGRATE_LOCK"
 )
 
 // MigrateFunc is the func signature for migrating.
@@ -21,6 +28,22 @@ type MigrateFunc func(*sql.Tx) error
 // RollbackFunc is the func signature for rollbacking.
 type RollbackFunc func(*sql.Tx) error
 
+// MigrateContextFunc is the func signature for migrating with the context of
+// the run.
+type MigrateContextFunc func(context.Context, *sql.Tx) error
+
+// RollbackContextFunc is the func signature for rollbacking with the context
+// of the run.
+type RollbackContextFunc func(context.Context, *sql.Tx) error
+
+// NoTxFunc is the func signature for migrating or rollbacking outside of a
+// transaction.
+type NoTxFunc func(*sqlx.DB) error
+
+// TxFunc is the func signature for migrating or rollbacking with the
+// instrumented transaction.
+type TxFunc func(*Tx) error
+
 // InitSchemaFunc is the func signature for initializing the schema.
 type InitSchemaFunc func(*sqlx.DB) error
 
@@ -32,16 +55,232 @@ type Options struct {
 	IDColumnName string
 	// IDColumnSize is the length of the migration id column
 	IDColumnSize int
+	// OrphanedMigrations is how the runs and Validate report IDs stored in
+	// the migration table that don't match any migration, ie. once a branch
+	// whose migrations were applied is abandoned: SeverityWarn warns,
+	// SeverityError fails with an OrphanedMigrationsError, SeverityIgnore
+	// skips the check. Defaults to SeverityWarn.
+	OrphanedMigrations Severity
+	// OutOfOrder is how the runs report the pending migrations whose ID
+	// sorts before the last applied migration, ie. once a branch adding a
+	// migration with an older timestamp is merged: SeverityWarn applies them
+	// with a warning, SeverityError fails the run and Validate with an
+	// OutOfOrderError, SeverityIgnore applies them silently. Defaults to
+	// SeverityWarn.
+	OutOfOrder Severity
+	// IDPattern is the format the IDs of the migrations must match, ie.
+	// regexp.MustCompile(`^\d{12}_[a-z0-9_]+$`), checked by the runs and
+	// Validate. The contract migrations, whose IDs are the ones of their
+	// expand migration suffixed by "_contract", aren't checked. Disabled when
+	// nil.
+	IDPattern *regexp.Regexp
+	// EnforceIDOrder fails the runs and Validate when the migrations aren't
+	// sorted by ID, compared with CompareIDs, so a mis-ordered slice isn't
+	// applied in the wrong order.
+	EnforceIDOrder bool
+	// CompareIDs compares the IDs for EnforceIDOrder and OutOfOrder instead
+	// of the CompareIDs function, ie. for IDs which are semantic versions.
+	CompareIDs func(a, b string) int
+	// ReservedIDs and ReservedIDPrefixes reserve IDs, ie. for the migrations
+	// of a framework embedding sqlxmigrate: the runs and Validate fail with a
+	// ReservedIDError for the migrations using them without
+	// Migration.Reserved. SCHEMA_INIT and SQLXMIGRATE_LOCK are always
+	// reserved.
+	ReservedIDs        []string
+	ReservedIDPrefixes []string
+	// RequireRollback makes the runs and Validate fail with a
+	// MissingRollbackError on a migration without rollback. The
+	// irreversible migrations set their Rollback to NoRollback.
+	RequireRollback bool
+	// Policies check the statements of the SQL scripts of the migrations in
+	// Validate, ie. AllowSchemas, DenySuperuser and IndexNaming.
+	Policies []Policy
+	// AuditTableName is the table recording every migration and rollback with
+	// its statistics. Leave empty to disable the audit.
+	AuditTableName string
+	// MaxRowsAffected aborts a migration when a single statement executed
+	// through the instrumented transaction affects more rows, even when the
+	// migration ignores the error. The statements executed through the
+	// *sql.Tx of Migrate and MigrateContext, or by MigrateNoTx, bypass it.
+	// Disabled when 0.
+	MaxRowsAffected int64
+	// RunBudget caps the duration of a run: once exceeded no new migration
+	// is started and the remaining ones are reported as pending. Disabled
+	// when 0.
+	RunBudget time.Duration
+	// PauseFunc is called between migrations, the run stops cleanly when it
+	// returns true, ie. when an operator set a flag in a table.
+	PauseFunc func() (bool, error)
+	// AttestationSigner signs the attestation of the applied migrations
+	// added to the result of successful runs. Disabled when nil.
+	AttestationSigner crypto.Signer
+	// CacheAppliedIDs caches the IDs of the applied migrations of each
+	// migration table in the process, for services migrating lazily, ie. per
+	// tenant. The cache is invalidated when the process applies or rolls back
+	// migrations, InvalidateCache drops it.
+	CacheAppliedIDs bool
+	// UseLock serializes the runs and rollbacks of the instances sharing the
+	// database with an advisory lock on PostgreSQL and MySQL, a lock row in
+	// the migration table otherwise.
+	UseLock bool
+	// LockTimeout is how long a run waits for the lock before failing with
+	// ErrLockTimeout. Waits until the context is done when 0.
+	LockTimeout time.Duration
+	// Pooler is whether the database is reached through a transaction
+	// pooler, which disables the features relying on the session. Defaults
+	// to PoolerDetect.
+	Pooler PoolerMode
+	// WrapTx wraps the transaction a migration executes its statements with,
+	// ie. with the middleware of a query logger or an APM agent, or to tag
+	// the statements with the ID of the migration. It covers MigrateSQL,
+	// RollbackSQL and the statements executed through the instrumented
+	// transaction; the functions receiving a *sql.Tx or a *sqlx.DB bypass it.
+	WrapTx func(migrationID string, tx Execer) Execer
+	// DisableStatementTags stops prepending a comment identifying the
+	// migration and the run, ie. /* sqlxmigrate id=201608301400 run=... */,
+	// to the statements of MigrateSQL and RollbackSQL.
+	DisableStatementTags bool
+	// OnFailure defines how the runs handle a failing migration. Defaults
+	// to FailureAbort. The migrations disabling the transaction always run
+	// their RollbackNoTx on failure, nothing undoes them otherwise.
+	OnFailure FailurePolicy
+	// TxRetries is how many times the transaction of a run is executed again
+	// after a serialization failure, SQLSTATE 40001, ie. when CockroachDB
+	// aborts it because of contention. Its migrations run again, so they
+	// must not have side effects outside of the transaction. Defaults to 3
+	// on CockroachDB when 0, disabled by a negative value.
+	TxRetries int
+	// TombstoneRollbacks keeps the row of a rolled back migration in the
+	// migration table, setting its rolled_back_at column instead of deleting
+	// it. Every instance sharing the migration table must set it: the
+	// releases before it see the rolled back migrations as applied.
+	TombstoneRollbacks bool
+	// Flags tells whether the feature flags of Migration.Flag are enabled.
+	Flags FlagProvider
+	// Tracer traces the runs and their migrations, ie. with the otel
+	// subpackage.
+	Tracer Tracer
+	// ReportIndexes adds the duplicate and unused indexes found by
+	// IndexReport to the warnings of the successful runs.
+	ReportIndexes bool
+	// BloatThreshold is the estimated share of bloat of the tables of
+	// Migration.HeavyDMLTables above which the runs applying the migration
+	// warn, ie. 0.2. Only PostgreSQL and MySQL are supported. Disabled when
+	// 0.
+	BloatThreshold float64
+	// VacuumHeavyDML vacuums the tables of Migration.HeavyDMLTables once the
+	// runs applying the migration committed, with VACUUM (ANALYZE) on
+	// PostgreSQL and OPTIMIZE TABLE on MySQL.
+	VacuumHeavyDML bool
+	// CollectUsage adds the rows read and written and the lock wait of each
+	// migration to its Usage and to the audit table, read from the counters
+	// of PostgreSQL and MySQL, the latter with performance_schema enabled.
+	CollectUsage bool
+	// JSONLog emits every log message as a line of JSON, a LogEvent, so the
+	// log pipelines can index the events of the runs. The default logger
+	// has no prefix nor flags then.
+	JSONLog bool
+	// RedactLiterals masks the string and number literals of the statements
+	// logged, and of the statement of a MigrationError, with "?".
+	RedactLiterals bool
+	// RedactStatements replaces the statements logged that match one of the
+	// patterns, and the statement of a MigrationError, with "[redacted]".
+	RedactStatements []*regexp.Regexp
 }
 
+// Severity defines how a problem found while validating migrations is reported.
+type Severity int
+
+const (
+	// SeverityWarn logs the problem.
+	SeverityWarn Severity = iota
+	// SeverityError returns the problem as an error.
+	SeverityError
+	// SeverityIgnore skips the check.
+	SeverityIgnore
+)
+
 // Migration represents a database migration (a modification to be made on the database).
 type Migration struct {
 	// ID is the migration identifier. Usually a timestamp like "201601021504".
 	ID string
 	// Migrate is a function that will br executed while running this migration.
 	Migrate MigrateFunc
-	// Rollback will be executed on rollback. Can be nil.
+	// Rollback will be executed on rollback. Can be nil, or NoRollback to
+	// mark the migration irreversible.
 	Rollback RollbackFunc
+	// MigrateContext can be used instead of Migrate to receive the context
+	// of the run, so long running statements are canceled with it.
+	MigrateContext MigrateContextFunc
+	// RollbackContext can be used instead of Rollback to receive the context
+	// of the rollback.
+	RollbackContext RollbackContextFunc
+	// MigrateTx can be used instead of Migrate to receive the instrumented
+	// transaction, which accounts the statements executed by the migration.
+	MigrateTx TxFunc
+	// RollbackTx can be used instead of Rollback to receive the instrumented
+	// transaction.
+	RollbackTx TxFunc
+	// DisableTransaction runs the migration outside of the transaction of the
+	// run with MigrateNoTx, ie. for CREATE INDEX CONCURRENTLY. The migrations
+	// applied before it are committed first, and it is recorded as applied
+	// once it succeeded.
+	DisableTransaction bool
+	// MigrateNoTx is executed when DisableTransaction is set.
+	MigrateNoTx NoTxFunc
+	// RollbackNoTx is executed outside of a transaction on rollback when
+	// DisableTransaction is set. The other rollback functions run in a
+	// transaction when it is nil.
+	RollbackNoTx NoTxFunc
+	// MigrateSQL is a SQL script executed when none of Migrate,
+	// MigrateContext and MigrateTx are defined. Its statements are split on
+	// semicolons, or the delimiter set by a DELIMITER line, outside of
+	// quotes, comments and dollar-quoted bodies, executed one by one, listed
+	// by Plan and checked by Options.Policies.
+	MigrateSQL string
+	// RollbackSQL is a SQL script executed on rollback when none of Rollback,
+	// RollbackContext and RollbackTx are defined.
+	RollbackSQL string
+	// Checksum identifies the definition of the migration, ie. the SHA-256
+	// of its script. It is recorded in the migration table when set, and
+	// Validate fails once it doesn't match the recorded one. Defaults to the
+	// SHA-256 of MigrateSQL when the migration executes it.
+	Checksum string
+	// Flag gates the migration on a feature flag of Options.Flags: while
+	// the flag is disabled, runs defer the migration instead of applying
+	// it, and the first run once the flag is enabled applies it.
+	Flag string
+	// NotBefore defers the migration until a time, ie. the contract phase
+	// dropping a column once the release no longer reading it is deployed
+	// everywhere: the runs before it leave the migration pending, the first
+	// run after it applies it.
+	NotBefore time.Time
+	// Contract declares the cleanup the migration schedules, added to the
+	// migrations as a contract migration run once a safety window passed.
+	Contract *Contract
+	// DependsOn are the IDs of the migrations applied before this one. The
+	// runs order the migrations so they follow their dependencies, keeping
+	// the given order otherwise, and defer a migration while one of its
+	// dependencies is deferred.
+	DependsOn []string
+	// HeavyDMLTables are the tables the migration updates or deletes many
+	// rows of, ie. with a backfill, which leaves them bloated. The runs
+	// applying it vacuum them with Options.VacuumHeavyDML, or report their
+	// bloat with Options.BloatThreshold.
+	HeavyDMLTables []string
+	// Reserved allows the migration to use an ID reserved with
+	// Options.ReservedIDs or Options.ReservedIDPrefixes, ie. for the
+	// migrations of the framework that reserved them.
+	Reserved bool
+	// Group makes the migrations sharing it, which must follow each other,
+	// an atomic unit: they are applied in the same transaction, the
+	// targeted runs and the pauses don't stop in the middle of the group,
+	// and the rollbacks undo the whole group. The migrations of a group
+	// can't disable the transaction.
+	Group string
+
+	// expandID is the ID of the expand migration of a contract migration.
+	expandID string
 }
 
 // Sqlxmigrate represents a collection of all migrations of a database schema.
@@ -52,17 +291,69 @@ type Sqlxmigrate struct {
 	migrations []*Migration
 	initSchema InitSchemaFunc
 	log        *log.Logger
+	formatter  MessageFormatter
+	history    HistoryStore
+
+	initChecksum  string
+	initThroughID string
+	// squashedThroughID is the last migration squashed with Squash.
+	squashedThroughID string
+
+	states       map[string]State
+	onTransition []func(Transition)
+
+	beforeEach     []func(*Migration) error
+	afterEach      []func(*Migration, time.Duration, error)
+	beforeRollback []func(*Migration) error
+	afterRollback  []func(*Migration, time.Duration, error)
+
+	indexSets []*IndexSet
+
+	pauseRequested int32
+	pausedTarget   *string
+
+	// searchPath is the schema the migrations are run in, the default
+	// search path when empty. It is checked by checkSchemaName.
+	searchPath string
+
+	pooler       pooler
+	capabilities *Capabilities
+	readiness    readiness
+
+	// runID identifies the current run in the statement tags and the
+	// attestation.
+	runID string
 }
 
 // ReservedIDError is returned when a migration is using a reserved ID
 type ReservedIDError struct {
 	ID string
+	// Prefix is the prefix of Options.ReservedIDPrefixes the ID starts with,
+	// if any.
+	Prefix string
 }
 
 func (e *ReservedIDError) Error() string {
+	if e.Prefix != "" {
+		return fmt.Sprintf(`sqlxmigrate: Reserved migration ID: "%s" starts with the reserved prefix "%s"`, e.ID, e.Prefix)
+	}
 	return fmt.Sprintf(`sqlxmigrate: Reserved migration ID: "%s"`, e.ID)
 }
 
+// Hint implements Hinter.
+func (e *ReservedIDError) Hint() string {
+	switch {
+	case e.Prefix != "":
+		return fmt.Sprintf(`The IDs starting with "%s" are reserved by Options.ReservedIDPrefixes, rename the migration.`, e.Prefix)
+	case e.ID == lockMigrationID:
+		return fmt.Sprintf(`"%s" is used to lock the migration table, rename the migration.`, lockMigrationID)
+	case e.ID == initSchemaMigrationID:
+		return fmt.Sprintf(`"%s" is used to record the init schema, rename the migration.`, initSchemaMigrationID)
+	default:
+		return fmt.Sprintf(`"%s" is reserved by Options.ReservedIDs, rename the migration.`, e.ID)
+	}
+}
+
 // DuplicatedIDError is returned when more than one migration have the same ID
 type DuplicatedIDError struct {
 	ID string
@@ -72,6 +363,11 @@ func (e *DuplicatedIDError) Error() string {
 	return fmt.Sprintf(`sqlxmigrate: Duplicated migration ID: "%s"`, e.ID)
 }
 
+// Hint implements Hinter.
+func (e *DuplicatedIDError) Hint() string {
+	return "Give each migration a unique ID, ie. a new timestamp."
+}
+
 var (
 	// DefaultOptions can be used if you don't want to think about options.
 	DefaultOptions = &Options{
@@ -97,6 +393,60 @@ var (
 	// ErrMigrationIDDoesNotExist is returned when migrating or rolling back to a migration ID that
 	// does not exist in the list of migrations
 	ErrMigrationIDDoesNotExist = errors.New("sqlxmigrate: Tried to migrate to an ID that doesn't exist")
+
+	// ErrRunPaused is returned when a run stopped because a pause was
+	// requested, Resume continues it
+	ErrRunPaused = errors.New("sqlxmigrate: Run paused")
+
+	// ErrNotPaused is returned by Resume when no run is paused
+	ErrNotPaused = errors.New("sqlxmigrate: No paused run to resume")
+
+	// ErrInvalidRollbackRange is returned when the migration to roll back
+	// from comes before the migration to roll back to
+	ErrInvalidRollbackRange = errors.New("sqlxmigrate: The migration to roll back from must come after the migration to roll back to")
+
+	// ErrMissingTimestamp is returned when a migration ID is expected to
+	// start with a timestamp but doesn't
+	ErrMissingTimestamp = errors.New("sqlxmigrate: Migration ID doesn't start with a timestamp")
+
+	// ErrUnsupportedDriver is returned when a feature isn't supported by
+	// the database driver
+	ErrUnsupportedDriver = errors.New("sqlxmigrate: Unsupported database driver")
+
+	// ErrInvalidAttestation is returned when the signature of an
+	// attestation doesn't match its content
+	ErrInvalidAttestation = errors.New("sqlxmigrate: Invalid attestation signature")
+
+	// ErrMissingMigrateNoTx is returned when a migration disabling the
+	// transaction has no MigrateNoTx function
+	ErrMissingMigrateNoTx = errors.New("sqlxmigrate: Migration disabling the transaction has no MigrateNoTx function")
+
+	// ErrReplicaLagInTransaction is returned by Batched when
+	// BatchOptions.MaxReplicaLag is set: its batches reach the replicas once
+	// the migration commits, waiting for them only holds the locks longer
+	ErrReplicaLagInTransaction = errors.New("sqlxmigrate: MaxReplicaLag requires BatchedDB, the batches of Batched are replicated once the migration commits")
+
+	// ErrLockTimeout is returned when another instance held the lock for
+	// longer than Options.LockTimeout
+	ErrLockTimeout = errors.New("sqlxmigrate: Timed out waiting for the migration lock")
+
+	// ErrTooManyRollbackSteps is returned by RollbackN when fewer migrations
+	// are applied than the steps to roll back
+	ErrTooManyRollbackSteps = errors.New("sqlxmigrate: More rollback steps than applied migrations")
+
+	// ErrMissingSetName is returned by NewManifest when a migration set has
+	// no name.
+	ErrMissingSetName = errors.New("sqlxmigrate: Missing name in migration set")
+
+	// ErrUnknownAppliedMigration is wrapped by OrphanedMigrationsError.
+	ErrUnknownAppliedMigration = errors.New("sqlxmigrate: Applied migration is missing from the list of migrations")
+
+	// ErrOutOfOrderMigration is wrapped by OutOfOrderError.
+	ErrOutOfOrderMigration = errors.New("sqlxmigrate: Migration sorts before the last applied migration")
+
+	// ErrNoMigrationAtTime is returned by MigrateToTime and RollbackToTime
+	// when no migration has a timestamp before the given time.
+	ErrNoMigrationAtTime = errors.New("sqlxmigrate: No migration before the given time")
 )
 
 // New returns a new Sqlxmigrate.
@@ -112,13 +462,23 @@ func New(db *sqlx.DB, options *Options, migrations []*Migration) *Sqlxmigrate {
 	}
 
 	l := log.New(os.Stdout, "sqlxmigrate : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+	if options.JSONLog {
+		l = log.New(os.Stdout, "", 0)
+	}
 
-	return &Sqlxmigrate{
+	migrations = withContracts(migrations)
+	if ordered, err := orderDependencies(migrations); err == nil {
+		migrations = ordered
+	}
+
+	g := &Sqlxmigrate{
 		db:         db,
 		options:    options,
 		migrations: migrations,
 		log:        l,
 	}
+	g.history = &tableHistory{g: g}
+	return g
 }
 
 // SetLogger allows the default logger to be overwritten
@@ -126,6 +486,32 @@ func (g *Sqlxmigrate) SetLogger(logger *log.Logger) {
 	g.log = logger
 }
 
+// MessageFormatter formats a log message from its format and arguments. The
+// format strings are stable so they can be used as keys to translate the
+// messages, or to add links to runbooks to the failure messages.
+type MessageFormatter func(format string, args ...interface{}) string
+
+// SetMessageFormatter sets the function formatting the log messages,
+// fmt.Sprintf by default.
+func (g *Sqlxmigrate) SetMessageFormatter(formatter MessageFormatter) {
+	g.formatter = formatter
+}
+
+// logf formats and logs a message.
+func (g *Sqlxmigrate) logf(format string, args ...interface{}) {
+	args = g.redactArgs(args)
+	var msg string
+	if g.formatter != nil {
+		msg = g.formatter(format, args...)
+	} else {
+		msg = fmt.Sprintf(format, args...)
+	}
+	if g.options.JSONLog {
+		msg = g.jsonLogLine(format, args, msg)
+	}
+	g.log.Output(2, msg)
+}
+
 // InitSchema sets a function that is run if no migration is found.
 // The idea is preventing to run all migrations when a new clean database
 // is being migrating. In this function you should create all tables and
@@ -136,70 +522,445 @@ func (g *Sqlxmigrate) InitSchema(initSchema InitSchemaFunc) {
 
 // Migrate executes all migrations that did not run yet.
 func (g *Sqlxmigrate) Migrate() error {
+	return g.MigrateContext(context.Background())
+}
+
+// MigrateContext executes all migrations that did not run yet. The run is
+// aborted, and its transaction rolled back, when the context is done.
+func (g *Sqlxmigrate) MigrateContext(ctx context.Context) error {
+	_, err := g.MigrateWithResultContext(ctx)
+	return err
+}
+
+// MigrateWithResult executes all migrations that did not run yet and reports
+// the outcome of the run.
+func (g *Sqlxmigrate) MigrateWithResult() (*Result, error) {
+	return g.MigrateWithResultContext(context.Background())
+}
+
+// MigrateWithResultContext is MigrateWithResult with a context aborting the
+// run when done.
+func (g *Sqlxmigrate) MigrateWithResultContext(ctx context.Context) (*Result, error) {
 	if !g.hasMigrations() {
-		return ErrNoMigrationDefined
+		return nil, ErrNoMigrationDefined
 	}
 	var targetMigrationID string
 	if len(g.migrations) > 0 {
 		targetMigrationID = g.migrations[len(g.migrations)-1].ID
 	}
-	return g.migrate(targetMigrationID)
+	return g.migrate(ctx, targetMigrationID)
 }
 
 // MigrateTo executes all migrations that did not run yet up to the migration that matches `migrationID`.
 func (g *Sqlxmigrate) MigrateTo(migrationID string) error {
+	return g.MigrateToContext(context.Background(), migrationID)
+}
+
+// MigrateToContext is MigrateTo with a context aborting the run when done.
+func (g *Sqlxmigrate) MigrateToContext(ctx context.Context, migrationID string) error {
+	_, err := g.MigrateToWithResultContext(ctx, migrationID)
+	return err
+}
+
+// MigrateToWithResult executes all migrations that did not run yet up to the
+// migration that matches `migrationID` and reports the outcome of the run.
+func (g *Sqlxmigrate) MigrateToWithResult(migrationID string) (*Result, error) {
+	return g.MigrateToWithResultContext(context.Background(), migrationID)
+}
+
+// MigrateToWithResultContext is MigrateToWithResult with a context aborting
+// the run when done.
+func (g *Sqlxmigrate) MigrateToWithResultContext(ctx context.Context, migrationID string) (*Result, error) {
 	if err := g.checkIDExist(migrationID); err != nil {
-		return err
+		return nil, err
 	}
-	return g.migrate(migrationID)
+	return g.migrate(ctx, g.groupEnd(migrationID))
 }
 
 // migrate
-func (g *Sqlxmigrate) migrate(migrationID string) error {
+func (g *Sqlxmigrate) migrate(ctx context.Context, migrationID string) (res *Result, err error) {
+	start := time.Now()
+
+	ctx, end := g.startRun(ctx, DirectionMigrate)
+	defer func() { end(err) }()
+
 	if !g.hasMigrations() {
-		return ErrNoMigrationDefined
+		return nil, ErrNoMigrationDefined
 	}
 
 	if err := g.checkReservedID(); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := g.checkDuplicatedID(); err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := g.createMigrationTableIfNotExists(); err != nil {
-		return err
+	if err := g.checkIDFormat(); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkIDOrder(); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkDependencies(); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkGroups(); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkRollbacks(); err != nil {
+		return nil, err
+	}
+
+	if _, err := g.probeCapabilities(ctx); err != nil {
+		return nil, err
+	}
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	trackWAL := g.trackWAL(ctx)
+	defer func() { trackWAL(res) }()
+
+	if err := g.compatibilityCheck(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := g.history.Init(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := g.createAuditTableIfNotExists(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkMissingID(); err != nil {
+		return nil, err
+	}
+
+	if g.options.OrphanedMigrations == SeverityError {
+		if err := g.checkOrphanedMigrations(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := g.checkOutOfOrder(ctx, migrationID); err != nil {
+		return nil, err
+	}
+
+	res = &Result{}
+	if err := g.collectWarnings(ctx, res, migrationID); err != nil {
+		return res, err
+	}
+
+	if err := g.checkSquashed(ctx); err != nil {
+		return res, err
 	}
 
 	if g.initSchema != nil {
-		canInitializeSchema, err := g.canInitializeSchema()
+		canInitializeSchema, err := g.canInitializeSchema(ctx)
 		if err != nil {
-			return err
+			return res, err
 		}
 		if canInitializeSchema {
-			if err := g.runInitSchema(); err != nil {
-				return err
+			if err := g.runInitSchema(ctx); err != nil {
+				return res, err
 			}
-			return nil
 		}
 	}
 
-	if err := g.begin(); err != nil {
-		return err
+	planned, err := g.planMigrations(ctx, migrationID)
+	if err != nil {
+		return res, err
+	}
+	for _, m := range g.migrations {
+		if state, ok := g.states[m.ID]; !ok {
+			// The migrations that already ran aren't planned.
+			res.Skipped = append(res.Skipped, m.ID)
+		} else if state == StateDeferred {
+			res.Deferred = append(res.Deferred, m.ID)
+		}
+		if m.ID == migrationID {
+			break
+		}
+	}
+	if len(planned) == 0 {
+		return g.completeRun(ctx, res, migrationID)
+	}
+
+	if err := g.begin(ctx); err != nil {
+		return res, g.failMigrations(planned, err)
 	}
 
 	defer g.rollback()
 
-	for _, migration := range g.migrations {
-		if err := g.runMigration(migration); err != nil {
-			return err
+	for _, migration := range planned {
+		if err := g.transition(migration, StateLocked, nil); err != nil {
+			return res, g.failMigrations(planned, err)
+		}
+	}
+
+	// A transaction failing with a serialization failure is retried from
+	// its first migration: replayed are the migrations that already passed
+	// the checks of the run budget and the pause before the failure.
+	var paused bool
+	// failed is the error of the first failed migration with
+	// FailureContinueOthers.
+	var failed error
+	committed, replayed, recorded, retries := 0, 0, len(res.Migrations), 0
+	for {
+		for i := committed; i < len(planned); i++ {
+			migration := planned[i]
+			if err := ctx.Err(); err != nil {
+				return res, g.failMigrations(planned, err)
+			}
+			// The run doesn't stop in the middle of a group.
+			if i >= replayed && !continuesGroup(planned, i) {
+				if g.options.RunBudget > 0 && time.Since(start) > g.options.RunBudget {
+					if err := g.skipPending(res, planned[i:]); err != nil {
+						return res, g.failMigrations(planned, err)
+					}
+					g.warn(res, Warning{Code: WarningRunBudgetExceeded, Message: fmt.Sprintf("run budget of %s exceeded, %d migrations left pending", g.options.RunBudget, len(res.Pending))})
+					planned = planned[:i]
+					break
+				}
+				if paused, err = g.shouldPause(); err != nil {
+					return res, g.failMigrations(planned, err)
+				} else if paused {
+					if err := g.skipPending(res, planned[i:]); err != nil {
+						return res, g.failMigrations(planned, err)
+					}
+					planned = planned[:i]
+					break
+				}
+			}
+			if migration.DisableTransaction {
+				// The migrations applied so far are committed before running a
+				// migration outside of the transaction.
+				if err := g.commitMigrations(planned[committed:i]); err != nil {
+					if retry, rerr := g.retryTx(ctx, err, &retries, planned[committed:i]); rerr != nil {
+						return res, g.failMigrations(planned, rerr)
+					} else if retry {
+						res.Migrations = res.Migrations[:recorded]
+						replayed = i + 1
+						i = committed - 1
+						continue
+					}
+					return res, g.failMigrations(planned, err)
+				}
+				committed = i
+				if err := g.runMigrationNoTx(ctx, res, migration); err != nil {
+					if g.options.OnFailure != FailureContinueOthers {
+						return res, g.failMigrations(planned, err)
+					}
+					if failed == nil {
+						failed = err
+					}
+					var cerr error
+					if planned, cerr = g.continueAfterFailure(res, planned, i, i, err); cerr != nil {
+						return res, g.failMigrations(planned, cerr)
+					}
+				}
+				committed, recorded = i+1, len(res.Migrations)
+				if err := g.begin(ctx); err != nil {
+					return res, g.failMigrations(planned, err)
+				}
+				continue
+			}
+			if err := g.runMigration(ctx, res, migration); err != nil {
+				if retry, rerr := g.retryTx(ctx, err, &retries, planned[committed:i+1]); rerr != nil {
+					return res, g.failMigrations(planned, rerr)
+				} else if retry {
+					res.Migrations = res.Migrations[:recorded]
+					replayed = i + 1
+					i = committed - 1
+					continue
+				}
+				// Never run the rollback of the migration in the aborted
+				// transaction.
+				g.rollback()
+				switch g.options.OnFailure {
+				case FailureContinueOthers:
+					if failed == nil {
+						failed = err
+					}
+					res.Migrations = res.Migrations[:recorded]
+					var cerr error
+					if planned, cerr = g.continueAfterFailure(res, planned, committed, i, err); cerr != nil {
+						return res, g.failMigrations(planned, cerr)
+					}
+					committed = i + 1
+					if err := g.begin(ctx); err != nil {
+						return res, g.failMigrations(planned, err)
+					}
+					continue
+				case FailureRollbackMigration:
+					g.rollbackFailed(ctx, migration)
+				}
+				return res, g.failMigrations(planned, err)
+			}
+			// Each migration, or group, is committed on its own so a failure
+			// only undoes it.
+			if g.options.OnFailure == FailureContinueOthers && i+1 < len(planned) && !continuesGroup(planned, i+1) {
+				if err := g.commitMigrations(planned[committed : i+1]); err != nil {
+					if retry, rerr := g.retryTx(ctx, err, &retries, planned[committed:i+1]); rerr != nil {
+						return res, g.failMigrations(planned, rerr)
+					} else if retry {
+						res.Migrations = res.Migrations[:recorded]
+						replayed = i + 1
+						i = committed - 1
+						continue
+					}
+					return res, g.failMigrations(planned, err)
+				}
+				committed, recorded = i+1, len(res.Migrations)
+				if err := g.begin(ctx); err != nil {
+					return res, g.failMigrations(planned, err)
+				}
+			}
+		}
+
+		err := g.commitMigrations(planned[committed:])
+		if err == nil {
+			break
+		}
+		if retry, rerr := g.retryTx(ctx, err, &retries, planned[committed:]); rerr != nil {
+			return res, g.failMigrations(planned, rerr)
+		} else if !retry {
+			return res, g.failMigrations(planned, err)
+		}
+		res.Migrations = res.Migrations[:recorded]
+		replayed = len(planned)
+	}
+
+	if paused {
+		g.pausedTarget = &migrationID
+		g.logf("Run paused - %d migrations left pending", len(res.Pending))
+	}
+	if failed != nil || paused {
+		if err := g.setVersion(ctx, res); err != nil {
+			return res, err
+		}
+		if failed != nil {
+			g.logf("Run failed - %d migrations failed, %d left pending", len(res.Failed), len(res.Pending))
+			return res, failed
+		}
+		return res, ErrRunPaused
+	}
+	return g.completeRun(ctx, res, migrationID)
+}
+
+// completeRun reconciles the declared index sets once the run applied all
+// the migrations, reports the indexes and the bloat, then attests the run.
+func (g *Sqlxmigrate) completeRun(ctx context.Context, res *Result, migrationID string) (*Result, error) {
+	if err := g.setVersion(ctx, res); err != nil {
+		return res, err
+	}
+	if migrationID == "" || migrationID == g.migrations[len(g.migrations)-1].ID {
+		changes, err := g.reconcileIndexes(ctx)
+		res.Indexes = append(res.Indexes, changes...)
+		if err != nil {
+			return res, err
+		}
+	}
+	if err := g.reportIndexes(ctx, res); err != nil {
+		return res, err
+	}
+	if err := g.adviseBloat(ctx, res); err != nil {
+		return res, err
+	}
+	return g.attest(ctx, res)
+}
+
+// planMigrations returns the migrations that did not run yet up to the
+// migration that matches `migrationID`, once moved to the planned state.
+func (g *Sqlxmigrate) planMigrations(ctx context.Context, migrationID string) ([]*Migration, error) {
+	g.states = make(map[string]State, len(g.migrations))
+
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := g.contractSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var planned []*Migration
+	plannedIDs := make(map[string]bool)
+
+	// The migrations of a group are planned once the whole group was
+	// checked, and deferred with it when one of them is deferred.
+	var grouped []*Migration
+	var groupDeferred bool
+	planGroup := func() error {
+		for _, m := range grouped {
+			if groupDeferred {
+				g.logf("Migration %s - deferred with group %s", m.ID, m.Group)
+				delete(plannedIDs, m.ID)
+				if err := g.transition(m, StateDeferred, nil); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := g.transition(m, StatePlanned, nil); err != nil {
+				return err
+			}
+			planned = append(planned, m)
+		}
+		grouped, groupDeferred = nil, false
+		return nil
+	}
+
+	for i, migration := range g.migrations {
+		if !continuesGroup(g.migrations, i) {
+			if err := planGroup(); err != nil {
+				return nil, err
+			}
+		}
+		g.logf("Migration %s - checking", migration.ID)
+
+		if _, migrationRan := applied[migration.ID]; migrationRan {
+			g.logf("Migration %s - already ran", migration.ID)
+		} else if dep := pendingDependency(migration, applied, plannedIDs); dep != "" {
+			g.logf("Migration %s - deferred until %s is applied", migration.ID, dep)
+			if err := g.transition(migration, StateDeferred, nil); err != nil {
+				return nil, err
+			}
+			groupDeferred = groupDeferred || migration.Group != ""
+		} else if deferred, err := g.deferMigration(ctx, migration, schedule); err != nil {
+			return nil, err
+		} else if deferred {
+			if err := g.transition(migration, StateDeferred, nil); err != nil {
+				return nil, err
+			}
+			groupDeferred = groupDeferred || migration.Group != ""
+		} else if migration.Group != "" {
+			grouped = append(grouped, migration)
+			plannedIDs[migration.ID] = true
+		} else {
+			if err := g.transition(migration, StatePlanned, nil); err != nil {
+				return nil, err
+			}
+			planned = append(planned, migration)
+			plannedIDs[migration.ID] = true
 		}
+
 		if migrationID != "" && migration.ID == migrationID {
 			break
 		}
 	}
-	return g.commit()
+	if err := planGroup(); err != nil {
+		return nil, err
+	}
+	return planned, nil
 }
 
 // There are migrations to apply if either there's a defined
@@ -209,12 +970,24 @@ func (g *Sqlxmigrate) hasMigrations() bool {
 }
 
 // Check whether any migration is using a reserved ID.
-// For now there's only have one reserved ID, but there may be more in the future.
 func (g *Sqlxmigrate) checkReservedID() error {
 	for _, m := range g.migrations {
-		if m.ID == initSchemaMigrationID {
+		if m.ID == initSchemaMigrationID || m.ID == lockMigrationID {
 			return &ReservedIDError{ID: m.ID}
 		}
+		if m.Reserved {
+			continue
+		}
+		for _, id := range g.options.ReservedIDs {
+			if m.ID == id {
+				return &ReservedIDError{ID: m.ID}
+			}
+		}
+		for _, prefix := range g.options.ReservedIDPrefixes {
+			if strings.HasPrefix(m.ID, prefix) {
+				return &ReservedIDError{ID: m.ID, Prefix: prefix}
+			}
+		}
 	}
 	return nil
 }
@@ -241,21 +1014,46 @@ func (g *Sqlxmigrate) checkIDExist(migrationID string) error {
 
 // RollbackLast undo the last migration
 func (g *Sqlxmigrate) RollbackLast() error {
+	return g.RollbackLastContext(context.Background())
+}
+
+// RollbackLastContext is RollbackLast with a context aborting the rollback
+// when done.
+func (g *Sqlxmigrate) RollbackLastContext(ctx context.Context) (err error) {
 	if len(g.migrations) == 0 {
 		return ErrNoMigrationDefined
 	}
 
-	if err := g.begin(); err != nil {
+	ctx, end := g.startRun(ctx, DirectionRollback)
+	defer func() { end(err) }()
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := g.createAuditTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	if err := g.begin(ctx); err != nil {
 		return err
 	}
 	defer g.rollback()
 
-	lastRunMigration, err := g.getLastRunMigration()
+	lastRunMigration, err := g.getLastRunMigration(ctx)
 	if err != nil {
 		return err
 	}
 
-	if err := g.rollbackMigration(lastRunMigration); err != nil {
+	if lastRunMigration.Group != "" {
+		if err := g.rollbackGroup(ctx, lastRunMigration.Group); err != nil {
+			return err
+		}
+		return g.commit()
+	}
+	if err := g.rollbackMigration(ctx, lastRunMigration); err != nil {
 		return err
 	}
 	return g.commit()
@@ -264,6 +1062,12 @@ func (g *Sqlxmigrate) RollbackLast() error {
 // RollbackTo undoes migrations up to the given migration that matches the `migrationID`.
 // Migration with the matching `migrationID` is not rolled back.
 func (g *Sqlxmigrate) RollbackTo(migrationID string) error {
+	return g.RollbackToContext(context.Background(), migrationID)
+}
+
+// RollbackToContext is RollbackTo with a context aborting the rollback when
+// done.
+func (g *Sqlxmigrate) RollbackToContext(ctx context.Context, migrationID string) (err error) {
 	if len(g.migrations) == 0 {
 		return ErrNoMigrationDefined
 	}
@@ -272,22 +1076,38 @@ func (g *Sqlxmigrate) RollbackTo(migrationID string) error {
 		return err
 	}
 
-	if err := g.begin(); err != nil {
+	ctx, end := g.startRun(ctx, DirectionRollback)
+	defer func() { end(err) }()
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := g.createAuditTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	if err := g.begin(ctx); err != nil {
 		return err
 	}
 	defer g.rollback()
 
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The group of the migration is kept whole.
+	migrationID = g.groupEnd(migrationID)
 	for i := len(g.migrations) - 1; i >= 0; i-- {
 		migration := g.migrations[i]
 		if migration.ID == migrationID {
 			break
 		}
-		migrationRan, err := g.migrationRan(migration)
-		if err != nil {
-			return err
-		}
-		if migrationRan {
-			if err := g.rollbackMigration(migration); err != nil {
+		if _, migrationRan := applied[migration.ID]; migrationRan {
+			if err := g.rollbackMigration(ctx, migration); err != nil {
 				return err
 			}
 		}
@@ -295,16 +1115,102 @@ func (g *Sqlxmigrate) RollbackTo(migrationID string) error {
 	return g.commit()
 }
 
-func (g *Sqlxmigrate) getLastRunMigration() (*Migration, error) {
-	for i := len(g.migrations) - 1; i >= 0; i-- {
-		migration := g.migrations[i]
+// RollbackN undoes the last `steps` applied migrations, in reverse order. It
+// fails with ErrTooManyRollbackSteps, without undoing anything, when fewer
+// migrations are applied.
+func (g *Sqlxmigrate) RollbackN(steps int) error {
+	return g.RollbackNContext(context.Background(), steps)
+}
 
-		migrationRan, err := g.migrationRan(migration)
-		if err != nil {
-			return nil, err
+// RollbackNContext is RollbackN with a context aborting the rollback when
+// done.
+func (g *Sqlxmigrate) RollbackNContext(ctx context.Context, steps int) error {
+	if steps < 0 {
+		return ErrTooManyRollbackSteps
+	}
+	return g.rollbackSteps(ctx, steps)
+}
+
+// Reset undoes every applied migration, in reverse order. The schema
+// initialized by InitSchema remains, the migrations it includes being undone.
+func (g *Sqlxmigrate) Reset() error {
+	return g.ResetContext(context.Background())
+}
+
+// ResetContext is Reset with a context aborting the rollback when done.
+func (g *Sqlxmigrate) ResetContext(ctx context.Context) error {
+	return g.rollbackSteps(ctx, -1)
+}
+
+// rollbackSteps undoes the last `steps` applied migrations in a single
+// transaction, every applied migration when negative.
+func (g *Sqlxmigrate) rollbackSteps(ctx context.Context, steps int) (err error) {
+	if len(g.migrations) == 0 {
+		return ErrNoMigrationDefined
+	}
+
+	ctx, end := g.startRun(ctx, DirectionRollback)
+	defer func() { end(err) }()
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return err
+	}
+
+	// A group counts as a single step.
+	var undo []*Migration
+	var undone int
+	var unitApplied bool
+	for i := len(g.migrations) - 1; i >= 0 && (steps < 0 || undone < steps); i-- {
+		if _, ok := applied[g.migrations[i].ID]; ok {
+			undo = append(undo, g.migrations[i])
+			unitApplied = true
+		}
+		if unitApplied && !continuesGroup(g.migrations, i) {
+			undone++
+			unitApplied = false
+		}
+	}
+	if steps > undone {
+		return ErrTooManyRollbackSteps
+	}
+	if len(undo) == 0 {
+		return nil
+	}
+
+	if err := g.createAuditTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	if err := g.begin(ctx); err != nil {
+		return err
+	}
+	defer g.rollback()
+
+	for _, migration := range undo {
+		if err := g.rollbackMigration(ctx, migration); err != nil {
+			return err
 		}
+	}
+	return g.commit()
+}
 
-		if migrationRan {
+func (g *Sqlxmigrate) getLastRunMigration(ctx context.Context) (*Migration, error) {
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(g.migrations) - 1; i >= 0; i-- {
+		migration := g.migrations[i]
+
+		if _, migrationRan := applied[migration.ID]; migrationRan {
 			return migration, nil
 		}
 	}
@@ -313,192 +1219,387 @@ func (g *Sqlxmigrate) getLastRunMigration() (*Migration, error) {
 
 // RollbackMigration undo a migration.
 func (g *Sqlxmigrate) RollbackMigration(m *Migration) error {
-	if err := g.begin(); err != nil {
+	return g.RollbackMigrationContext(context.Background(), m)
+}
+
+// RollbackMigrationContext is RollbackMigration with a context aborting the
+// rollback when done.
+func (g *Sqlxmigrate) RollbackMigrationContext(ctx context.Context, m *Migration) (err error) {
+	ctx, end := g.startRun(ctx, DirectionRollback)
+	defer func() { end(err) }()
+
+	unlock, err := g.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := g.createAuditTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	if err := g.begin(ctx); err != nil {
 		return err
 	}
 	defer g.rollback()
 
-	if err := g.rollbackMigration(m); err != nil {
+	if err := g.rollbackMigration(ctx, m); err != nil {
 		return err
 	}
 	return g.commit()
 }
 
-func (g *Sqlxmigrate) rollbackMigration(m *Migration) error {
-	if m.Rollback == nil {
+func (g *Sqlxmigrate) rollbackMigration(ctx context.Context, m *Migration) error {
+	if !m.hasRollback() {
 		return ErrRollbackImpossible
 	}
-	g.log.Printf("Migration %s rollback", m.ID)
+	g.logf("Migration %s rollback", m.ID)
 
-	if err := m.Rollback(g.tx); err != nil {
+	if err := g.runBeforeHooks(g.beforeRollback, m); err != nil {
 		return err
 	}
 
-	sql := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", g.options.TableName, g.options.IDColumnName)
-	sql = g.db.Rebind(sql)
-	g.log.Printf("Migration %s rollback - %s", m.ID, sql)
+	var stats Stats
+	if m.DisableTransaction && m.RollbackNoTx != nil {
+		// The rollbacks done so far are committed before rolling back a
+		// migration outside of the transaction.
+		if err := g.commit(); err != nil {
+			return err
+		}
+		start := time.Now()
+		_, end := g.startMigration(ctx, m.ID, DirectionRollback)
+		err := m.RollbackNoTx(g.db)
+		end(Stats{}, err)
+		runAfterHooks(g.afterRollback, m, time.Since(start), err)
+		if err != nil {
+			return &MigrationError{MigrationID: m.ID, Direction: DirectionRollback, Err: err}
+		}
+		if err := g.begin(ctx); err != nil {
+			return err
+		}
+	} else {
+		start := time.Now()
+		mctx, end := g.startMigration(ctx, m.ID, DirectionRollback)
+		tx := g.newTx(mctx, m.ID)
+		err := m.rollback(tx)
+		end(tx.stats, err)
+		runAfterHooks(g.afterRollback, m, time.Since(start), err)
+		if err != nil {
+			return tx.migrationError(DirectionRollback, err)
+		}
+		stats = tx.stats
+	}
 
-	if _, err := g.tx.Exec(sql, m.ID); err != nil {
+	if err := g.insertAudit(ctx, m.ID, auditRollback, stats, nil); err != nil {
 		return err
 	}
 
-	return nil
+	return g.history.Remove(ctx, g.tx, m.ID)
 }
 
-func (g *Sqlxmigrate) runInitSchema() error {
+// runInitSchema initializes the schema and records the migrations it
+// includes as applied: every migration, or the ones up to the migration set
+// with InitSchemaSQL.
+func (g *Sqlxmigrate) runInitSchema(ctx context.Context) error {
 	if err := g.initSchema(g.db); err != nil {
 		return err
 	}
-	if err := g.insertMigration(initSchemaMigrationID); err != nil {
+
+	if err := g.begin(ctx); err != nil {
 		return err
 	}
+	defer g.rollback()
 
-	return nil
+	if err := g.insertMigration(ctx, initSchemaMigrationID); err != nil {
+		return err
+	}
+	if err := g.storeInitChecksum(ctx); err != nil {
+		return err
+	}
+	for _, migration := range g.migrations {
+		if err := g.insertMigration(ctx, migration.ID); err != nil {
+			return err
+		}
+		if migration.ID == g.initThroughID {
+			break
+		}
+	}
+
+	return g.commit()
 }
 
-func (g *Sqlxmigrate) runMigration(migration *Migration) error {
-	if len(migration.ID) == 0 {
-		return ErrMissingID
+// runMigration executes a locked migration and records it as applied, as
+// part of the transaction of the run.
+func (g *Sqlxmigrate) runMigration(ctx context.Context, res *Result, migration *Migration) error {
+	if err := g.transition(migration, StateRunning, nil); err != nil {
+		return err
 	}
-	g.log.Printf("Migration %s - checking", migration.ID)
 
-	migrationRan, err := g.migrationRan(migration)
-	if err != nil {
+	if err := g.runBeforeHooks(g.beforeEach, migration); err != nil {
 		return err
 	}
-	if migrationRan {
-		g.log.Printf("Migration %s - already ran", migration.ID)
-	} else {
-		g.log.Printf("Migration %s - starting", migration.ID)
 
-		if err := migration.Migrate(g.tx); err != nil {
-			g.log.Printf("Migration %s - failed - %v", migration.ID, err)
+	before, collected := g.readUsageCounters(ctx)
+	start := time.Now()
+	mctx, end := g.startMigration(ctx, migration.ID, DirectionMigrate)
+	tx := g.newTx(mctx, migration.ID)
+	err := migration.migrate(tx)
+	if err == nil && tx.tooManyRows != nil {
+		// The migration ignored the error, the statement must not be
+		// committed.
+		err = tx.tooManyRows
+	}
+	if err != nil {
+		end(tx.stats, err)
+		runAfterHooks(g.afterEach, migration, time.Since(start), err)
+		g.logf("Migration %s - failed - %v", migration.ID, err)
+		if hint := Hint(err); hint != "" {
+			g.logf("Migration %s - hint - %s", migration.ID, hint)
+		}
+		// The transaction is aborted, the run handles the failure with
+		// Options.OnFailure.
+		return tx.migrationError(DirectionMigrate, err)
+	}
 
-			if rerr := migration.Rollback(g.tx); rerr != nil {
-				if strings.Contains(rerr.Error(), "current transaction is aborted") {
-					g.log.Printf("Migration %s - Rollback skipped, transaction is aborted", migration.ID)
-				} else {
-					g.log.Printf("Migration %s - Rollback failed - %v", migration.ID, rerr)
-				}
-			}
+	duration := time.Since(start)
+	end(tx.stats, nil)
+	runAfterHooks(g.afterEach, migration, duration, nil)
 
-			return err
+	usage := Usage{WallTime: duration, DBTime: tx.dbTime}
+	if collected {
+		if after, ok := g.readUsageCounters(ctx); ok {
+			usage.addCounters(before, after)
 		}
+	}
 
-		if err := g.insertMigration(migration.ID); err != nil {
-			return err
-		}
+	// The transaction is rolled back as soon as the context is done.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		g.log.Printf("Migration %s - complete", migration.ID)
+	if err := g.transition(migration, StateRecording, nil); err != nil {
+		return err
 	}
+
+	if err := g.insertMigration(ctx, migration.ID); err != nil {
+		return err
+	}
+
+	if err := g.storeMetadata(ctx, migration, duration); err != nil {
+		return err
+	}
+
+	if err := g.insertAudit(ctx, migration.ID, auditMigrate, tx.stats, &usage); err != nil {
+		return err
+	}
+
+	res.Migrations = append(res.Migrations, MigrationResult{
+		ID:    migration.ID,
+		Stats: tx.stats,
+		Usage: usage,
+	})
+
+	g.logf("Migration %s - complete - %d statements, %d rows affected in %s", migration.ID, tx.stats.Statements, tx.stats.RowsAffected, duration)
 	return nil
 }
 
-func (g *Sqlxmigrate) createMigrationTableIfNotExists() error {
-	if ok, err := g.HasTable(g.options.TableName); ok || err != nil {
+// runMigrationNoTx executes a migration disabling the transaction, then
+// records it as applied in its own transaction.
+func (g *Sqlxmigrate) runMigrationNoTx(ctx context.Context, res *Result, migration *Migration) error {
+	if g.searchPath != "" {
+		return fmt.Errorf(`sqlxmigrate: Migration "%s" disables the transaction and can't run in schema "%s"`, migration.ID, g.searchPath)
+	}
+
+	if err := g.transition(migration, StateRunning, nil); err != nil {
+		return err
+	}
+
+	if err := g.runBeforeHooks(g.beforeEach, migration); err != nil {
 		return err
 	}
 
-	sql := fmt.Sprintf("CREATE TABLE %s (%s VARCHAR(%d) PRIMARY KEY)", g.options.TableName, g.options.IDColumnName, g.options.IDColumnSize)
-	g.log.Printf("createMigrationTableIfNotExists %s", sql)
+	start := time.Now()
+	_, end := g.startMigration(ctx, migration.ID, DirectionMigrate)
+	if err := migration.migrateNoTx(g.db); err != nil {
+		end(Stats{}, err)
+		runAfterHooks(g.afterEach, migration, time.Since(start), err)
+		g.logf("Migration %s - failed - %v", migration.ID, err)
+		if hint := Hint(err); hint != "" {
+			g.logf("Migration %s - hint - %s", migration.ID, hint)
+		}
 
-	if _, err := g.db.Exec(sql); err != nil {
-		err = errors.WithMessagef(err, "Query failed %s", sql)
+		if migration.RollbackNoTx != nil {
+			if rerr := migration.RollbackNoTx(g.db); rerr != nil {
+				g.logf("Migration %s - Rollback failed - %v", migration.ID, rerr)
+			}
+		}
+
+		return &MigrationError{MigrationID: migration.ID, Direction: DirectionMigrate, Err: err}
+	}
+	duration := time.Since(start)
+	end(Stats{}, nil)
+	runAfterHooks(g.afterEach, migration, duration, nil)
+
+	if err := g.transition(migration, StateRecording, nil); err != nil {
+		return err
+	}
+
+	if err := g.begin(ctx); err != nil {
 		return err
 	}
+	defer g.rollback()
+
+	if err := g.insertMigration(ctx, migration.ID); err != nil {
+		return err
+	}
+
+	if err := g.storeMetadata(ctx, migration, duration); err != nil {
+		return err
+	}
+
+	// Only the wall time of a migration outside of a transaction is known.
+	usage := Usage{WallTime: duration}
+	if err := g.insertAudit(ctx, migration.ID, auditMigrate, Stats{}, &usage); err != nil {
+		return err
+	}
+
+	if err := g.commitMigrations([]*Migration{migration}); err != nil {
+		return err
+	}
+
+	res.Migrations = append(res.Migrations, MigrationResult{ID: migration.ID, Usage: usage})
+
+	g.logf("Migration %s - complete - outside of a transaction in %s", migration.ID, duration)
 	return nil
 }
 
-func (g *Sqlxmigrate) migrationRan(m *Migration) (bool, error) {
-	var count int
+// commitMigrations commits the transaction of the run and moves the
+// migrations it applied to the done state.
+func (g *Sqlxmigrate) commitMigrations(migrations []*Migration) error {
+	if err := g.commit(); err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if err := g.transition(migration, StateDone, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createMigrationTableIfNotExists creates the migration table, or upgrades
+// it to the version of this release.
+func (g *Sqlxmigrate) createMigrationTableIfNotExists(ctx context.Context) error {
+	if ok, err := g.tableExists(ctx, g.options.TableName); err != nil {
+		return err
+	} else if ok {
+		return g.upgradeMigrationTable(ctx)
+	}
 
-	query := fmt.Sprintf("SELECT count(0) FROM %s WHERE %s = ?", g.options.TableName, g.options.IDColumnName)
-	query = g.db.Rebind(query)
-	g.log.Printf("Migration %s - %s", m.ID, query)
+	sql := g.dialect().CreateVersionTableSQL(g.options.TableName, g.options.IDColumnName, g.options.IDColumnSize)
+	g.logf("createMigrationTableIfNotExists %s", sql)
 
-	err := g.db.QueryRow(query, m.ID).Scan(&count)
-	if err != nil {
-		err = errors.WithMessagef(err, "Query failed %s", query)
-		return false, err
+	if _, err := g.db.ExecContext(ctx, sql); err != nil {
+		err = queryError(err, sql)
+		return err
 	}
+	return g.upgradeMigrationTable(ctx)
+}
 
-	return count > 0, err
+// appliedIDs returns the IDs of all the migrations recorded in the history.
+func (g *Sqlxmigrate) appliedIDs(ctx context.Context) ([]string, error) {
+	if g.options.CacheAppliedIDs {
+		return g.cachedAppliedIDs(ctx)
+	}
+	return g.readAppliedIDs(ctx)
 }
 
-// The schema can be initialised only if it hasn't been initialised yet
-// and no other migration has been applied already.
-func (g *Sqlxmigrate) canInitializeSchema() (bool, error) {
-	migrationRan, err := g.migrationRan(&Migration{ID: initSchemaMigrationID})
+// readAppliedIDs reads the IDs of the applied migrations from the history.
+func (g *Sqlxmigrate) readAppliedIDs(ctx context.Context) ([]string, error) {
+	return g.history.Applied(ctx)
+}
+
+// unknownAppliedIDs returns the IDs recorded in the migration table that
+// don't match any of the migrations.
+func (g *Sqlxmigrate) unknownAppliedIDs(ctx context.Context) ([]string, error) {
+	appliedIDs, err := g.appliedIDs(ctx)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if migrationRan {
-		return false, nil
+
+	lookup := make(map[string]struct{}, len(g.migrations))
+	for _, m := range g.migrations {
+		lookup[m.ID] = struct{}{}
 	}
 
-	// If the ID doesn't exist, we also want the list of migrations to be empty
-	var count int
-	query := fmt.Sprintf("SELECT count(0) FROM %s", g.options.TableName)
-	g.log.Printf("canInitializeSchema %s", query)
+	var unknown []string
+	for _, id := range appliedIDs {
+		if _, ok := lookup[id]; ok || id == initSchemaMigrationID {
+			continue
+		}
+		unknown = append(unknown, id)
+	}
+	sort.Strings(unknown)
+
+	return unknown, nil
+}
 
-	err = g.db.QueryRow(query).Scan(&count)
+// The schema can be initialised only if it hasn't been initialised yet
+// and no other migration has been applied already.
+func (g *Sqlxmigrate) canInitializeSchema(ctx context.Context) (bool, error) {
+	// The init schema ID is recorded with the other migrations, so the
+	// history must be empty.
+	appliedIDs, err := g.appliedIDs(ctx)
 	if err != nil {
-		err = errors.WithMessagef(err, "Query failed %s", query)
 		return false, err
 	}
+	return len(appliedIDs) == 0, nil
+}
 
-	return count == 0, err
+// insertMigration records a migration as applied, as part of the current
+// transaction.
+func (g *Sqlxmigrate) insertMigration(ctx context.Context, id string) error {
+	return g.history.Record(ctx, g.tx, id)
 }
 
-func (g *Sqlxmigrate) insertMigration(id string) error {
-	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?)", g.options.TableName, g.options.IDColumnName)
-	sql = g.db.Rebind(sql)
-	g.log.Printf("Migration %s - %s", id, sql)
+func (g *Sqlxmigrate) begin(ctx context.Context) error {
+	// The schema is interpolated in the SET statement.
+	if g.searchPath != "" {
+		if err := checkSchemaName(g.searchPath); err != nil {
+			return err
+		}
+	}
 
-	if _, err := g.db.Exec(sql, id); err != nil {
-		err = errors.WithMessagef(err, "Query failed %s", sql)
+	var err error
+	g.tx, err = g.db.BeginTx(ctx, nil)
+	if err != nil || g.searchPath == "" {
 		return err
 	}
 
-	return nil
-}
+	sql := fmt.Sprintf("SET LOCAL search_path TO %s", g.searchPath)
+	g.logf("begin %s", sql)
 
-func (g *Sqlxmigrate) begin() error {
-	var err error
-	g.tx, err = g.db.Begin()
-	return err
+	if _, err := g.tx.ExecContext(ctx, sql); err != nil {
+		g.rollback()
+		err = queryError(err, sql)
+		return err
+	}
+	return nil
 }
 
 func (g *Sqlxmigrate) commit() error {
 	err := g.tx.Commit()
 	g.tx = nil
+	if g.options.CacheAppliedIDs {
+		g.InvalidateCache()
+	}
 	return err
 }
 
 func (g *Sqlxmigrate) rollback() {
 	if g.tx != nil {
 		g.tx.Rollback()
-		g.log.Printf("tx.rollback executed")
+		g.logf("tx.rollback executed")
 		g.tx = nil
 	}
 }
-
-func (g *Sqlxmigrate) HasTable(tableName string) (bool, error) {
-	query := fmt.Sprintf("SELECT 1 FROM %s", tableName)
-	g.log.Printf("HasTable %s - %s", tableName, query)
-
-	if _, err := g.db.Exec(query); err != nil {
-		if strings.Contains(err.Error(), "does not exist") {
-			// postgres error
-			return false, nil
-		} else if strings.Contains(err.Error(), "doesn't exist") {
-			// mysql table error
-			return false, nil
-		} else if strings.Contains(err.Error(), "no such table") {
-			// sqlite table error
-			return false, nil
-        }
-		return false, err
-	}
-	return true, nil
-}
@@ -2,6 +2,7 @@ package sqlxmigrate
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -145,6 +146,41 @@ func TestRollbackTo(t *testing.T) {
 	})
 }
 
+func TestRollbackN(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.Migrate())
+
+		assert.Equal(t, ErrTooManyRollbackSteps, m.RollbackN(4))
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+
+		require.NoError(t, m.RollbackN(2))
+		assert.True(t, m.hasTable("people"))
+		assert.False(t, m.hasTable("pets"))
+		assert.False(t, m.hasTable("books"))
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+
+		require.NoError(t, m.RollbackN(0))
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestReset(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.Migrate())
+
+		require.NoError(t, m.Reset())
+		assert.False(t, m.hasTable("people"))
+		assert.False(t, m.hasTable("pets"))
+		assert.False(t, m.hasTable("books"))
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+
+		// Nothing left to roll back.
+		require.NoError(t, m.Reset())
+	})
+}
+
 // If initSchema is defined, but no migrations are provided,
 // then initSchema is executed.
 func TestInitSchemaNoMigrations(t *testing.T) {
@@ -274,6 +310,39 @@ func TestReservedID(t *testing.T) {
 	})
 }
 
+func TestConfiguredReservedIDs(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		framework := &Migration{
+			ID:       "FRAMEWORK_0001",
+			Reserved: true,
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+		options := *DefaultOptions
+		options.ReservedIDs = []string{"201608301430"}
+		options.ReservedIDPrefixes = []string{"FRAMEWORK_"}
+
+		// The migrations of the framework use the reserved IDs.
+		m := New(db, &options, []*Migration{framework, migrations[0]})
+		require.NoError(t, m.Validate())
+
+		err := New(db, &options, []*Migration{framework, migrations[0], {ID: "FRAMEWORK_0002"}}).Migrate()
+		var reservedErr *ReservedIDError
+		require.True(t, errors.As(err, &reservedErr))
+		assert.Equal(t, &ReservedIDError{ID: "FRAMEWORK_0002", Prefix: "FRAMEWORK_"}, reservedErr)
+		assert.Contains(t, Hint(err), "ReservedIDPrefixes")
+
+		err = New(db, &options, migrations).Validate()
+		assert.Equal(t, &ReservedIDError{ID: "201608301430"}, err)
+		assert.Contains(t, Hint(err), "ReservedIDs")
+
+		// The IDs of sqlxmigrate stay reserved.
+		err = New(db, &options, []*Migration{{ID: "SCHEMA_INIT", Reserved: true}}).Validate()
+		assert.Equal(t, &ReservedIDError{ID: "SCHEMA_INIT"}, err)
+	})
+}
+
 func TestDuplicatedID(t *testing.T) {
 	forEachDatabase(t, func(db *sqlx.DB) {
 		migrationsDuplicatedID := []*Migration{
@@ -359,7 +428,7 @@ func tableCount(t *testing.T, db *sqlx.DB, tableName string) (count int) {
 	return
 }
 
-func forEachDatabase(t *testing.T, fn func(database *sqlx.DB), dialects ...string) {
+func forEachDatabase(t testing.TB, fn func(database *sqlx.DB), dialects ...string) {
 	if len(databases) == 0 {
 		panic("No database choosen for testing!")
 	}
@@ -377,7 +446,7 @@ func forEachDatabase(t *testing.T, fn func(database *sqlx.DB), dialects ...strin
 			defer db.Close()
 
 			// ensure tables do not exists
-			assert.NoError(t, dropTableIfExists(db, "migrations", "people", "pets", "animals", "cars"))
+			assert.NoError(t, dropTableIfExists(db, "migrations", "pets", "people", "books", "animals", "cars"))
 
 			fn(db)
 		}()
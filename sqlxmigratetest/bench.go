@@ -0,0 +1,63 @@
+package sqlxmigratetest
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/geeks-accelerator/sqlxmigrate"
+	"github.com/jmoiron/sqlx"
+)
+
+// NoopMigrations returns n migrations that don't change anything, with
+// increasing IDs, to measure the overhead of the migrator itself.
+func NoopMigrations(n int) []*sqlxmigrate.Migration {
+	migrations := make([]*sqlxmigrate.Migration, n)
+	for i := range migrations {
+		migrations[i] = &sqlxmigrate.Migration{
+			ID: fmt.Sprintf("%012d", i+1),
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+			Rollback: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+	}
+	return migrations
+}
+
+// MigrateBench measures a run applying n no-op migrations to an empty
+// migration table of the database, so the overhead of each migration can be
+// compared between databases and releases. The migration table is dropped
+// before each run, outside of the measure.
+func MigrateBench(b *testing.B, db *sqlx.DB, options *sqlxmigrate.Options, n int) {
+	if options == nil {
+		options = sqlxmigrate.DefaultOptions
+	}
+	migrations := NoopMigrations(n)
+
+	var elapsed time.Duration
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		opts := *options
+		m := sqlxmigrate.New(db, &opts, migrations)
+		m.SetLogger(log.New(ioutil.Discard, "", 0))
+		if _, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", opts.TableName)); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		start := time.Now()
+		if err := m.Migrate(); err != nil {
+			b.Fatal(err)
+		}
+		elapsed += time.Since(start)
+	}
+	b.ReportMetric(float64(elapsed.Nanoseconds())/float64(b.N*n), "ns/migration")
+}
@@ -0,0 +1,12 @@
+//go:build mssql
+// +build mssql
+
+package sqlxmigratetest
+
+import (
+	_ "github.com/denisenkom/go-mssqldb"
+)
+
+func init() {
+	Register("sqlserver", "MSSQL_CONN_STRING")
+}
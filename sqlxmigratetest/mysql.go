@@ -0,0 +1,12 @@
+//go:build mysql
+// +build mysql
+
+package sqlxmigratetest
+
+import (
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	Register("mysql", "MYSQL_CONN_STRING")
+}
@@ -0,0 +1,12 @@
+//go:build postgresql
+// +build postgresql
+
+package sqlxmigratetest
+
+import (
+	_ "github.com/lib/pq"
+)
+
+func init() {
+	Register("postgres", "PG_CONN_STRING")
+}
@@ -0,0 +1,12 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlxmigratetest
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	Register("sqlite3", "SQLITE_CONN_STRING")
+}
@@ -0,0 +1,101 @@
+// Package sqlxmigratetest runs the migration tests of a project against each
+// database it supports, as the tests of sqlxmigrate do.
+//
+// The dialects are registered by build tags, so a test run only needs the
+// databases it was built for:
+//
+//	go test -tags 'postgresql mysql' ./...
+//
+// The postgresql tag registers PostgreSQL connected to by the PG_CONN_STRING
+// environment variable, the mysql tag MySQL connected to by
+// MYSQL_CONN_STRING. The variables are also read from a .env file. Other
+// drivers are registered with Register.
+package sqlxmigratetest
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	// Loads the connection strings of the .env file.
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// Dialect is a database the tests run against.
+type Dialect struct {
+	// Name is the name of the database driver, ie. "postgres".
+	Name string
+	// ConnEnv is the environment variable holding the connection string.
+	ConnEnv string
+}
+
+var (
+	mu       sync.Mutex
+	dialects []Dialect
+)
+
+// Register adds a dialect the tests run against, the driver must be
+// imported. It is usually called by the init function of a test file built
+// with a tag.
+func Register(name, connEnv string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, d := range dialects {
+		if d.Name == name {
+			return
+		}
+	}
+	dialects = append(dialects, Dialect{Name: name, ConnEnv: connEnv})
+}
+
+// Dialects returns the registered dialects.
+func Dialects() []Dialect {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Dialect(nil), dialects...)
+}
+
+// ForEachDialect runs fn in a subtest for each registered dialect, connected
+// to the database of the dialect, or only for the ones named by `only`. The
+// test fails when no dialect is registered, so a test run missing its build
+// tags doesn't pass silently, and a dialect without connection string is
+// skipped.
+func ForEachDialect(t *testing.T, fn func(t *testing.T, db *sqlx.DB), only ...string) {
+	registered := Dialects()
+	if len(registered) == 0 {
+		t.Fatal("sqlxmigratetest: No dialect registered, build the tests with the tags of the databases, ie. -tags postgresql")
+	}
+
+	for _, d := range registered {
+		if len(only) > 0 && !contains(only, d.Name) {
+			continue
+		}
+
+		d := d
+		t.Run(d.Name, func(t *testing.T) {
+			dsn := os.Getenv(d.ConnEnv)
+			if dsn == "" {
+				t.Skipf("sqlxmigratetest: %s is not set", d.ConnEnv)
+			}
+
+			db, err := sqlx.Open(d.Name, dsn)
+			if err != nil {
+				t.Fatalf("sqlxmigratetest: Could not connect to database %s: %v", d.Name, err)
+			}
+			defer db.Close()
+
+			fn(t, db)
+		})
+	}
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, straw := range haystack {
+		if straw == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package sqlxmigratetest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver is registered so dialects can be opened without a database.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("not a database")
+}
+
+func init() {
+	sql.Register("sqlxmigratetest_a", fakeDriver{})
+	sql.Register("sqlxmigratetest_b", fakeDriver{})
+}
+
+func TestForEachDialect(t *testing.T) {
+	saved := Dialects()
+	defer func() { dialects = saved }()
+	dialects = nil
+
+	os.Setenv("SQLXMIGRATETEST_A", "a")
+	defer os.Unsetenv("SQLXMIGRATETEST_A")
+
+	Register("sqlxmigratetest_a", "SQLXMIGRATETEST_A")
+	Register("sqlxmigratetest_a", "SQLXMIGRATETEST_A")
+	Register("sqlxmigratetest_b", "SQLXMIGRATETEST_B")
+	assert.Len(t, Dialects(), 2)
+
+	var ran []string
+	ForEachDialect(t, func(t *testing.T, db *sqlx.DB) {
+		ran = append(ran, db.DriverName())
+	})
+	// b has no connection string and is skipped.
+	assert.Equal(t, []string{"sqlxmigratetest_a"}, ran)
+
+	ran = nil
+	ForEachDialect(t, func(t *testing.T, db *sqlx.DB) {
+		ran = append(ran, db.DriverName())
+	}, "sqlxmigratetest_b")
+	assert.Empty(t, ran)
+}
+
+func TestNoopMigrations(t *testing.T) {
+	migrations := NoopMigrations(3)
+	assert.Len(t, migrations, 3)
+	assert.Equal(t, "000000000001", migrations[0].ID)
+	assert.Equal(t, "000000000003", migrations[2].ID)
+	assert.NoError(t, migrations[0].Migrate(nil))
+}
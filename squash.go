@@ -0,0 +1,88 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SquashError is returned when a database that was migrated before a squash
+// misses some of the squashed migrations, which the run can't apply.
+type SquashError struct {
+	ThroughID string
+	// Missing are the IDs of the squashed migrations the database misses.
+	Missing []string
+}
+
+func (e *SquashError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migrations squashed through "%s" were never applied: %s`, e.ThroughID, strings.Join(e.Missing, ", "))
+}
+
+// Hint implements Hinter.
+func (e *SquashError) Hint() string {
+	return "The database is older than the squash. Migrate it with a release from before the squash first, then upgrade."
+}
+
+// Squash compacts the migrations up to the one that matches `throughID` into
+// `initSchema`, the consolidated schema they create, ie. generated with
+// pg_dump --schema-only or mysqldump --no-data from a database migrated
+// through `throughID`.
+//
+// A fresh database is initialized with `initSchema`, as with InitSchema, and
+// the squashed migrations are recorded as applied; the following ones are
+// applied by the run. A database that already has migrations must have
+// applied every squashed migration, the runs and Validate fail with a
+// SquashError otherwise. The squashed migrations are never run, so their
+// functions can be removed, only their IDs are needed.
+func (g *Sqlxmigrate) Squash(throughID string, initSchema InitSchemaFunc) {
+	g.initSchema = initSchema
+	g.initThroughID = throughID
+	g.squashedThroughID = throughID
+}
+
+// squashedIDs returns the IDs of the migrations set with Squash.
+func (g *Sqlxmigrate) squashedIDs() []string {
+	if g.squashedThroughID == "" {
+		return nil
+	}
+
+	var ids []string
+	for _, m := range g.migrations {
+		ids = append(ids, m.ID)
+		if m.ID == g.squashedThroughID {
+			break
+		}
+	}
+	return ids
+}
+
+// checkSquashed checks that a database with migrations applied every
+// squashed migration. Fresh databases are initialized by the run.
+func (g *Sqlxmigrate) checkSquashed(ctx context.Context) error {
+	if g.squashedThroughID == "" {
+		return nil
+	}
+	if err := g.checkIDExist(g.squashedThroughID); err != nil {
+		return err
+	}
+
+	appliedIDs, err := g.appliedIDs(ctx)
+	if err != nil || len(appliedIDs) == 0 {
+		return err
+	}
+	applied := make(map[string]struct{}, len(appliedIDs))
+	for _, id := range appliedIDs {
+		applied[id] = struct{}{}
+	}
+
+	var missing []string
+	for _, id := range g.squashedIDs() {
+		if _, ok := applied[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return &SquashError{ThroughID: g.squashedThroughID, Missing: missing}
+	}
+	return nil
+}
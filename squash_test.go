@@ -0,0 +1,80 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// squashedMigrations are the migrations with the first two squashed, only
+// their IDs left.
+var squashedMigrations = []*Migration{
+	{ID: "201608301400"},
+	{ID: "201608301430"},
+	extendedMigrations[2],
+}
+
+func squashSchema(db *sqlx.DB) error {
+	for _, stmt := range []string{
+		`CREATE TABLE people (id int PRIMARY KEY, name varchar(255))`,
+		`CREATE TABLE pets (id int PRIMARY KEY, name varchar(255), person_id int)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSquash(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		// A fresh database is initialized with the squashed schema.
+		m := New(db, DefaultOptions, squashedMigrations)
+		m.Squash("201608301430", squashSchema)
+		require.NoError(t, m.Migrate())
+		assert.True(t, m.hasTable("pets"))
+		assert.True(t, m.hasTable("books"))
+		assert.Equal(t, 4, tableCount(t, db, "migrations"))
+		assert.NoError(t, m.Validate())
+
+		// The squashed migrations must exist.
+		m = New(db, DefaultOptions, migrations)
+		m.Squash("201807221927", squashSchema)
+		assert.Equal(t, ErrMigrationIDDoesNotExist, m.Validate())
+	})
+}
+
+func TestSquashOlderDatabase(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		// The database migrated before the squash has every squashed
+		// migration.
+		require.NoError(t, New(db, DefaultOptions, migrations).Migrate())
+
+		m := New(db, DefaultOptions, squashedMigrations)
+		m.Squash("201608301430", squashSchema)
+		require.NoError(t, m.Migrate())
+		assert.True(t, m.hasTable("books"))
+		assert.Equal(t, 3, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestSquashMissingMigrations(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		// The database only applied the first squashed migration.
+		require.NoError(t, New(db, DefaultOptions, migrations[:1]).Migrate())
+
+		m := New(db, DefaultOptions, squashedMigrations)
+		m.Squash("201608301430", squashSchema)
+		err := m.Migrate()
+		var squashErr *SquashError
+		require.True(t, errors.As(err, &squashErr))
+		assert.Equal(t, []string{"201608301430"}, squashErr.Missing)
+		assert.NotEmpty(t, Hint(err))
+		assert.False(t, m.hasTable("books"))
+
+		assert.True(t, errors.As(m.Validate(), &squashErr))
+	})
+}
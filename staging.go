@@ -0,0 +1,178 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// schemaNameRe matches the unquoted identifiers of PostgreSQL, the schema
+// names the runs interpolate in their SQL.
+var schemaNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// InvalidSchemaError is returned when the name of a staging or tenant schema
+// isn't a plain identifier.
+type InvalidSchemaError struct {
+	Schema string
+}
+
+func (e *InvalidSchemaError) Error() string {
+	return fmt.Sprintf("sqlxmigrate: Invalid schema name %q", e.Schema)
+}
+
+// Hint implements Hinter.
+func (e *InvalidSchemaError) Hint() string {
+	return "Use a schema name made of letters, digits and underscores, not starting with a digit, of at most 63 characters."
+}
+
+// checkSchemaName checks that a schema name can be interpolated in SQL
+// unquoted.
+func checkSchemaName(schema string) error {
+	if !schemaNameRe.MatchString(schema) {
+		return &InvalidSchemaError{Schema: schema}
+	}
+	return nil
+}
+
+// NotRehearsedError is returned by Promote when pending migrations weren't
+// soft-applied to the staging schema.
+type NotRehearsedError struct {
+	Schema string
+	IDs    []string
+}
+
+func (e *NotRehearsedError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migrations "%s" weren't soft-applied to schema "%s"`, strings.Join(e.IDs, `", "`), e.Schema)
+}
+
+// Hint implements Hinter.
+func (e *NotRehearsedError) Hint() string {
+	return fmt.Sprintf(`Run SoftApply with schema "%s" and review its result before promoting the migrations.`, e.Schema)
+}
+
+// SoftApply rehearses the pending migrations against a staging schema, ie. a
+// snapshot of the production tables, recording them in a staging migration
+// table of the same name in that schema. The staging migration table starts
+// from the migrations applied to the default schema. Promote applies the
+// rehearsed migrations once approved. Only PostgreSQL is supported.
+func (g *Sqlxmigrate) SoftApply(schema string) (*Result, error) {
+	return g.softApply(context.Background(), schema)
+}
+
+func (g *Sqlxmigrate) softApply(ctx context.Context, schema string) (*Result, error) {
+	if !g.isPostgres() {
+		return nil, ErrUnsupportedDriver
+	}
+
+	staged, err := g.staging(schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := staged.createMigrationTableIfNotExists(ctx); err != nil {
+		return nil, err
+	}
+	if err := g.seedStaging(ctx, staged); err != nil {
+		return nil, err
+	}
+
+	return staged.MigrateWithResultContext(ctx)
+}
+
+// Promote applies to the default schema the pending migrations soft-applied
+// to the staging schema, in a single transaction. It fails with a
+// NotRehearsedError when a pending migration coming before the last
+// rehearsed one wasn't soft-applied.
+func (g *Sqlxmigrate) Promote(schema string) (*Result, error) {
+	return g.promote(context.Background(), schema)
+}
+
+func (g *Sqlxmigrate) promote(ctx context.Context, schema string) (*Result, error) {
+	staged, err := g.staging(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	stagedIDs, err := staged.appliedIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rehearsed := make(map[string]bool, len(stagedIDs))
+	for _, id := range stagedIDs {
+		rehearsed[id] = true
+	}
+
+	pending, err := g.pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the migrations coming after the last rehearsed one can be left
+	// out, they are promoted later.
+	var lastID string
+	var missing []string
+	for _, m := range pending {
+		if !rehearsed[m.ID] {
+			missing = append(missing, m.ID)
+			continue
+		}
+		if len(missing) > 0 {
+			return nil, &NotRehearsedError{Schema: schema, IDs: missing}
+		}
+		lastID = m.ID
+	}
+	if lastID == "" {
+		return &Result{}, nil
+	}
+
+	return g.MigrateToWithResultContext(ctx, lastID)
+}
+
+// staging returns a copy of g running the migrations in a staging schema.
+// The init schema and the audit table are left out. It fails with an
+// InvalidSchemaError when the name of the schema isn't a plain identifier.
+func (g *Sqlxmigrate) staging(schema string) (*Sqlxmigrate, error) {
+	if err := checkSchemaName(schema); err != nil {
+		return nil, err
+	}
+
+	options := *g.options
+	options.TableName = schema + "." + g.options.TableName
+	options.AuditTableName = ""
+
+	staged := New(g.db, &options, g.migrations)
+	staged.log = g.log
+	staged.formatter = g.formatter
+	staged.onTransition = g.onTransition
+	staged.beforeEach, staged.afterEach = g.beforeEach, g.afterEach
+	staged.beforeRollback, staged.afterRollback = g.beforeRollback, g.afterRollback
+	staged.searchPath = schema
+	return staged, nil
+}
+
+// seedStaging records the migrations applied to the default schema in the
+// staging migration table, the staging schema being a snapshot of it.
+func (g *Sqlxmigrate) seedStaging(ctx context.Context, staged *Sqlxmigrate) error {
+	applied, err := g.appliedIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := staged.begin(ctx); err != nil {
+		return err
+	}
+	defer staged.rollback()
+
+	sql := fmt.Sprintf("INSERT INTO %[1]s (%[2]s) SELECT CAST(? AS VARCHAR(%[3]d)) WHERE NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[2]s = ?)",
+		staged.options.TableName, g.options.IDColumnName, g.options.IDColumnSize)
+	sql = g.rebind(sql)
+	g.logf("seedStaging %s", sql)
+
+	for _, id := range applied {
+		if _, err := staged.tx.ExecContext(ctx, sql, id, id); err != nil {
+			err = queryError(err, sql)
+			return err
+		}
+	}
+	return staged.commit()
+}
@@ -0,0 +1,79 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createStagingSchema(t *testing.T, db *sqlx.DB) {
+	_, err := db.Exec("DROP SCHEMA IF EXISTS sqlxmigrate_staging CASCADE")
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE SCHEMA sqlxmigrate_staging")
+	require.NoError(t, err)
+	// the snapshot of the production tables
+	_, err = db.Exec(`CREATE TABLE sqlxmigrate_staging.people (LIKE people INCLUDING ALL)`)
+	require.NoError(t, err)
+}
+
+func TestSoftApplyAndPromote(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+		createStagingSchema(t, db)
+		defer db.Exec("DROP SCHEMA sqlxmigrate_staging CASCADE")
+
+		res, err := m.SoftApply("sqlxmigrate_staging")
+		require.NoError(t, err)
+		assert.Len(t, res.Migrations, 1)
+		assert.True(t, m.hasTable("sqlxmigrate_staging.pets"))
+		assert.False(t, m.hasTable("pets"))
+		assert.Equal(t, 2, tableCount(t, db, "sqlxmigrate_staging.migrations"))
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+
+		res, err = m.Promote("sqlxmigrate_staging")
+		require.NoError(t, err)
+		assert.Len(t, res.Migrations, 1)
+		assert.True(t, m.hasTable("pets"))
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+	}, "postgres")
+}
+
+func TestPromoteNotRehearsed(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.MigrateTo("201608301400"))
+		createStagingSchema(t, db)
+		defer db.Exec("DROP SCHEMA sqlxmigrate_staging CASCADE")
+
+		_, err := db.Exec("CREATE TABLE sqlxmigrate_staging.migrations (id VARCHAR(255) PRIMARY KEY)")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO sqlxmigrate_staging.migrations (id) VALUES ('201608301400'), ('201807221927')")
+		require.NoError(t, err)
+
+		_, err = m.Promote("sqlxmigrate_staging")
+		assert.Equal(t, &NotRehearsedError{Schema: "sqlxmigrate_staging", IDs: []string{"201608301430"}}, err)
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+	}, "postgres")
+}
+
+func TestSoftApplyUnsupportedDriver(t *testing.T) {
+	m := New(sqlx.NewDb(nil, "mysql"), DefaultOptions, migrations)
+
+	_, err := m.SoftApply("staging")
+	assert.Equal(t, ErrUnsupportedDriver, err)
+}
+
+func TestSoftApplyInvalidSchema(t *testing.T) {
+	m := New(sqlx.NewDb(nil, "postgres"), DefaultOptions, migrations)
+	schema := "staging; DROP TABLE people; --"
+
+	_, err := m.SoftApply(schema)
+	assert.Equal(t, &InvalidSchemaError{Schema: schema}, err)
+	_, err = m.Promote(schema)
+	assert.Equal(t, &InvalidSchemaError{Schema: schema}, err)
+	_, err = m.Promote(`"staging"`)
+	assert.Equal(t, &InvalidSchemaError{Schema: `"staging"`}, err)
+}
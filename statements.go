@@ -0,0 +1,138 @@
+package sqlxmigrate
+
+import (
+	"strings"
+)
+
+// statement is a statement of a SQL script with the delimiter ending it.
+type statement struct {
+	text      string
+	delimiter string
+}
+
+// splitStatements splits a SQL script into statements on semicolons, ignoring
+// the ones inside quotes, comments and Postgres dollar-quoted bodies. Empty
+// statements are dropped and the semicolons are not included. The # comments
+// and the backslash escapes in quotes are only MySQL's: # is an operator of
+// Postgres, and backslashes are plain characters in standard strings.
+//
+// As with the mysql client, a DELIMITER line starting a statement changes
+// the delimiter of the following statements, ie. to create the procedures
+// and triggers of MySQL whose bodies contain semicolons. The DELIMITER lines
+// aren't statements, the comments before them are dropped.
+func splitStatements(driverName, script string) []string {
+	var stmts []string
+	for _, stmt := range splitScript(driverName, script) {
+		stmts = append(stmts, stmt.text)
+	}
+	return stmts
+}
+
+// splitScript is splitStatements keeping the delimiter of each statement, the
+// one in effect at its end when it isn't terminated.
+func splitScript(driverName, script string) []statement {
+	var (
+		mysql = isMySQLDriver(driverName)
+		stmts []statement
+		start int
+		// pending is set once the statement has text besides comments.
+		pending   bool
+		delimiter = ";"
+	)
+
+	add := func(end int) {
+		if text := strings.TrimSpace(script[start:end]); text != "" {
+			stmts = append(stmts, statement{text: text, delimiter: delimiter})
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case strings.HasPrefix(script[i:], delimiter):
+			add(i)
+			i += len(delimiter) - 1
+			start, pending = i+1, false
+		case !pending && isDelimiterCommand(script[i:]):
+			end := skipUntil(script, i, "\n")
+			if delimiter = strings.TrimSpace(script[i+len("DELIMITER") : end]); delimiter == "" {
+				delimiter = ";"
+			}
+			i, start = end, end
+		case c == '\'' || c == '"' || c == '`':
+			pending = true
+			i = skipQuoted(script, i, c, mysql)
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			i = skipUntil(script, i+2, "\n") - 1
+		case c == '#' && mysql:
+			i = skipUntil(script, i+1, "\n") - 1
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			i = skipUntil(script, i+2, "*/") + 1
+		case c == '$':
+			pending = true
+			if tag := dollarTag(script[i:]); tag != "" {
+				i = skipUntil(script, i+len(tag), tag) + len(tag) - 1
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			pending = true
+		}
+	}
+	add(len(script))
+
+	return stmts
+}
+
+// isDelimiterCommand returns whether s starts with the DELIMITER command of
+// the mysql client.
+func isDelimiterCommand(s string) bool {
+	const command = "DELIMITER"
+	return len(s) > len(command) && strings.EqualFold(s[:len(command)], command) &&
+		(s[len(command)] == ' ' || s[len(command)] == '\t')
+}
+
+// skipQuoted returns the index of the quote closing the one at i. Doubled
+// quotes, and backslash escapes when `backslash` is set, don't close the
+// quote.
+func skipQuoted(s string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && backslash:
+			j++
+		case s[j] == quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(s) - 1
+}
+
+// skipUntil returns the index of the first occurrence of sep in s after i,
+// or the end of s when not found.
+func skipUntil(s string, i int, sep string) int {
+	if i >= len(s) {
+		return len(s)
+	}
+	if j := strings.Index(s[i:], sep); j >= 0 {
+		return i + j
+	}
+	return len(s)
+}
+
+// dollarTag returns the dollar quote tag starting s, ie. $$ or $body$, or an
+// empty string when s doesn't start with a tag.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
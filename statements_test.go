@@ -0,0 +1,87 @@
+package sqlxmigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name       string
+		driverName string
+		script     string
+		expected   []string
+	}{
+		{
+			name:     "simple",
+			script:   "CREATE TABLE people (id int);\nDROP TABLE pets;",
+			expected: []string{"CREATE TABLE people (id int)", "DROP TABLE pets"},
+		},
+		{
+			name:     "no trailing semicolon",
+			script:   "SELECT 1; SELECT 2",
+			expected: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:     "quotes",
+			script:   `INSERT INTO people (name) VALUES ('a;b'), ('it''s;'); SELECT "x;y" FROM t`,
+			expected: []string{`INSERT INTO people (name) VALUES ('a;b'), ('it''s;')`, `SELECT "x;y" FROM t`},
+		},
+		{
+			name:     "comments",
+			script:   "-- first; comment\nSELECT 1; /* second; comment */ SELECT 2;",
+			expected: []string{"-- first; comment\nSELECT 1", "/* second; comment */ SELECT 2"},
+		},
+		{
+			name:     "dollar quoted",
+			script:   "CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN NEW.a := 1; RETURN NEW; END; $body$ LANGUAGE plpgsql; SELECT $1",
+			expected: []string{"CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN NEW.a := 1; RETURN NEW; END; $body$ LANGUAGE plpgsql", "SELECT $1"},
+		},
+		{
+			name:     "delimiter",
+			script:   "-- the trigger\nDELIMITER $$\nCREATE TRIGGER t BEFORE INSERT ON people FOR EACH ROW BEGIN SET NEW.a = 1; SET NEW.b = 2; END$$\nDELIMITER ;\nSELECT 1; SELECT 2",
+			expected: []string{"CREATE TRIGGER t BEFORE INSERT ON people FOR EACH ROW BEGIN SET NEW.a = 1; SET NEW.b = 2; END", "SELECT 1", "SELECT 2"},
+		},
+		{
+			name:     "delimiter in a statement",
+			script:   "SELECT delimiter FROM t; SELECT 2",
+			expected: []string{"SELECT delimiter FROM t", "SELECT 2"},
+		},
+		{
+			name:       "mysql comments",
+			driverName: "mysql",
+			script:     "# first; comment\nSELECT 1; SELECT a # second; comment\n FROM t",
+			expected:   []string{"# first; comment\nSELECT 1", "SELECT a # second; comment\n FROM t"},
+		},
+		{
+			name:       "mysql backslash escapes",
+			driverName: "mysql",
+			script:     `SELECT 'it\'s;'; SELECT 2`,
+			expected:   []string{`SELECT 'it\'s;'`, "SELECT 2"},
+		},
+		{
+			name:       "postgres hash operator",
+			driverName: "postgres",
+			script:     "SELECT a # b; SELECT data #> '{a}' FROM t",
+			expected:   []string{"SELECT a # b", "SELECT data #> '{a}' FROM t"},
+		},
+		{
+			name:       "postgres backslash",
+			driverName: "postgres",
+			script:     `SELECT 'C:\'; SELECT 2`,
+			expected:   []string{`SELECT 'C:\'`, "SELECT 2"},
+		},
+		{
+			name:     "empty",
+			script:   " ; ;\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitStatements(tt.driverName, tt.script))
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MigrationStatus is the state of a migration in the database.
+type MigrationStatus struct {
+	ID      string
+	Applied bool
+	// AppliedAt is when the migration was last applied, read from the
+	// migration table or else from the audit table. Nil when unknown, ie.
+	// for the migrations applied before the table was upgraded.
+	AppliedAt *time.Time
+	// Duration is how long the migration took to apply. Nil when unknown.
+	Duration *time.Duration
+	// Checksum is the checksum of the migration recorded when it was applied.
+	Checksum string
+	// ScheduledAt is when a pending migration the runs defer becomes
+	// eligible: its NotBefore time, or the end of the safety window of a
+	// contract migration. Nil once the time passed, and for the contract
+	// migrations whose expand migration isn't applied.
+	ScheduledAt *time.Time
+}
+
+// Scheduled reports whether the migration is pending until a time.
+func (s MigrationStatus) Scheduled() bool {
+	return !s.Applied && s.ScheduledAt != nil
+}
+
+// Pending reports whether the migration did not run yet.
+func (s MigrationStatus) Pending() bool {
+	return !s.Applied
+}
+
+// Status returns the status of every migration, in order.
+func (g *Sqlxmigrate) Status() ([]MigrationStatus, error) {
+	return g.status(context.Background())
+}
+
+func (g *Sqlxmigrate) status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt, err := g.auditAppliedAt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := g.appliedMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := g.contractSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	statuses := make([]MigrationStatus, 0, len(g.migrations))
+	for _, m := range g.migrations {
+		s := MigrationStatus{ID: m.ID}
+		if _, ok := applied[m.ID]; !ok {
+			eligible := schedule[m.ID]
+			if !m.NotBefore.IsZero() {
+				notBefore := m.NotBefore.UTC()
+				eligible = &notBefore
+			}
+			if eligible != nil && eligible.After(now) {
+				s.ScheduledAt = eligible
+			}
+		} else {
+			s.Applied = true
+			s.AppliedAt = appliedAt[m.ID]
+			if md, ok := metadata[m.ID]; ok {
+				if md.appliedAt != nil {
+					s.AppliedAt = md.appliedAt
+				}
+				s.Duration = md.duration
+				s.Checksum = md.checksum
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// AppliedMigration is a migration recorded as applied in the database.
+type AppliedMigration struct {
+	ID string
+	// AppliedAt is when the migration was last applied, nil when unknown as
+	// for MigrationStatus.
+	AppliedAt *time.Time
+	// Duration is how long the migration took to apply. Nil when unknown.
+	Duration *time.Duration
+	// Checksum is the checksum of the migration recorded when it was applied.
+	Checksum string
+}
+
+// CurrentVersion returns the version of the schema: the ID of the last
+// applied migration, in the order of the migrations. Empty when none is
+// applied. Use it to check at startup that the database is at the version
+// the application expects.
+func (g *Sqlxmigrate) CurrentVersion() (string, error) {
+	return g.currentVersion(context.Background())
+}
+
+func (g *Sqlxmigrate) currentVersion(ctx context.Context) (string, error) {
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var version string
+	for _, m := range g.migrations {
+		if _, ok := applied[m.ID]; ok {
+			version = m.ID
+		}
+	}
+	return version, nil
+}
+
+// LastApplied returns the last applied migration, in the order of the
+// migrations, nil when none is applied.
+func (g *Sqlxmigrate) LastApplied() (*AppliedMigration, error) {
+	return g.lastApplied(context.Background())
+}
+
+func (g *Sqlxmigrate) lastApplied(ctx context.Context) (*AppliedMigration, error) {
+	version, err := g.currentVersion(ctx)
+	if err != nil || version == "" {
+		return nil, err
+	}
+	appliedAt, err := g.auditAppliedAt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := g.appliedMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	last := &AppliedMigration{ID: version, AppliedAt: appliedAt[version]}
+	if md, ok := metadata[version]; ok {
+		if md.appliedAt != nil {
+			last.AppliedAt = md.appliedAt
+		}
+		last.Duration = md.duration
+		last.Checksum = md.checksum
+	}
+	return last, nil
+}
+
+// Pending returns the migrations that did not run yet, in order.
+func (g *Sqlxmigrate) Pending() ([]*Migration, error) {
+	return g.pending(context.Background())
+}
+
+func (g *Sqlxmigrate) pending(ctx context.Context) ([]*Migration, error) {
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*Migration
+	for _, m := range g.migrations {
+		if _, ok := applied[m.ID]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Applied returns the migrations that already ran, in order.
+func (g *Sqlxmigrate) Applied() ([]*Migration, error) {
+	return g.applied(context.Background())
+}
+
+func (g *Sqlxmigrate) applied(ctx context.Context) ([]*Migration, error) {
+	applied, err := g.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []*Migration
+	for _, m := range g.migrations {
+		if _, ok := applied[m.ID]; ok {
+			ran = append(ran, m)
+		}
+	}
+	return ran, nil
+}
+
+func (g *Sqlxmigrate) appliedSet(ctx context.Context) (map[string]struct{}, error) {
+	appliedIDs, err := g.appliedIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]struct{}, len(appliedIDs))
+	for _, id := range appliedIDs {
+		applied[id] = struct{}{}
+	}
+	return applied, nil
+}
+
+// auditAppliedAt returns when each migration was last applied according to
+// the audit table.
+func (g *Sqlxmigrate) auditAppliedAt(ctx context.Context) (map[string]*time.Time, error) {
+	appliedAt := make(map[string]*time.Time)
+	if g.options.AuditTableName == "" {
+		return appliedAt, nil
+	}
+	if ok, err := g.tableExists(ctx, g.options.AuditTableName); !ok || err != nil {
+		return appliedAt, err
+	}
+
+	sql := fmt.Sprintf("SELECT %s, executed_at FROM %s WHERE direction = ? AND executed_at IS NOT NULL ORDER BY executed_at",
+		g.options.IDColumnName, g.options.AuditTableName)
+	sql = g.rebind(sql)
+
+	rows, err := g.db.QueryContext(ctx, sql, auditMigrate)
+	if err != nil {
+		err = queryError(err, sql)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var executedAt interface{}
+		if err := rows.Scan(&id, &executedAt); err != nil {
+			return nil, err
+		}
+		t, err := parseTimestamp(executedAt)
+		if err != nil {
+			return nil, err
+		}
+		appliedAt[id] = t
+	}
+	return appliedAt, rows.Err()
+}
+
+// timestampLayouts are the formats of the timestamps returned as text, ie. by
+// MySQL without parseTime.
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07:00",
+}
+
+// parseTimestamp converts a scanned TIMESTAMP column to a UTC time.
+func parseTimestamp(v interface{}) (*time.Time, error) {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		t := v.UTC()
+		return &t, nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("sqlxmigrate: Unexpected timestamp %v", v)
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf(`sqlxmigrate: Unexpected timestamp "%s"`, s)
+}
@@ -0,0 +1,113 @@
+package sqlxmigrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPending(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+
+		pending, err := m.Pending()
+		require.NoError(t, err)
+		assert.Equal(t, extendedMigrations, pending)
+
+		require.NoError(t, m.MigrateTo("201608301430"))
+
+		pending, err = m.Pending()
+		require.NoError(t, err)
+		assert.Equal(t, extendedMigrations[2:], pending)
+	})
+}
+
+func TestStatus(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.MigrateTo("201608301430"))
+
+		statuses, err := m.Status()
+		require.NoError(t, err)
+		require.Len(t, statuses, len(extendedMigrations))
+		for _, s := range statuses[:2] {
+			assert.True(t, s.Applied)
+			assert.NotNil(t, s.AppliedAt)
+			assert.NotNil(t, s.Duration)
+			assert.Empty(t, s.Checksum)
+		}
+		assert.Equal(t, "201608301430", statuses[1].ID)
+		assert.True(t, statuses[2].Pending())
+		assert.Nil(t, statuses[2].AppliedAt)
+
+		applied, err := m.Applied()
+		require.NoError(t, err)
+		assert.Equal(t, extendedMigrations[:2], applied)
+	})
+}
+
+func TestCurrentVersion(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+
+		version, err := m.CurrentVersion()
+		require.NoError(t, err)
+		assert.Empty(t, version)
+		last, err := m.LastApplied()
+		require.NoError(t, err)
+		assert.Nil(t, last)
+
+		require.NoError(t, m.MigrateTo("201608301430"))
+
+		version, err = m.CurrentVersion()
+		require.NoError(t, err)
+		assert.Equal(t, "201608301430", version)
+
+		last, err = m.LastApplied()
+		require.NoError(t, err)
+		require.NotNil(t, last)
+		assert.Equal(t, "201608301430", last.ID)
+		assert.NotNil(t, last.AppliedAt)
+		assert.NotNil(t, last.Duration)
+	})
+}
+
+func TestStatusAppliedAt(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		require.NoError(t, dropTableIfExists(db, "migration_audit"))
+		defer dropTableIfExists(db, "migration_audit")
+
+		options := *DefaultOptions
+		options.AuditTableName = "migration_audit"
+		m := New(db, &options, migrations)
+
+		before := time.Now().UTC().Add(-time.Minute)
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		statuses, err := m.Status()
+		require.NoError(t, err)
+		require.Len(t, statuses, 2)
+		require.NotNil(t, statuses[0].AppliedAt)
+		assert.True(t, statuses[0].AppliedAt.After(before))
+		assert.Nil(t, statuses[1].AppliedAt)
+	})
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2019, 6, 12, 12, 0, 0, 500000000, time.UTC)
+	for _, v := range []interface{}{want, []byte("2019-06-12 12:00:00.5"), "2019-06-12T12:00:00.5Z"} {
+		got, err := parseTimestamp(v)
+		require.NoError(t, err)
+		assert.Equal(t, want, *got)
+	}
+
+	got, err := parseTimestamp(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = parseTimestamp("yesterday")
+	assert.EqualError(t, err, `sqlxmigrate: Unexpected timestamp "yesterday"`)
+}
@@ -0,0 +1,178 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	appliedAtColumnName    = "applied_at"
+	durationColumnName     = "duration_ms"
+	rolledBackAtColumnName = "rolled_back_at"
+)
+
+// tableUpgrade is a change of the migration table, adding a column used by
+// a feature. The upgrades are only ever appended: an upgrade released can't
+// be changed, and the columns are nullable so the previous releases can keep
+// recording migrations in an upgraded table.
+type tableUpgrade struct {
+	version    int
+	column     string
+	definition string
+}
+
+// tableUpgrades upgrade the migration table from its first version, with
+// the ID column only.
+var tableUpgrades = []tableUpgrade{
+	{version: 2, column: checksumColumnName, definition: "VARCHAR(64) NULL"},
+	{version: 3, column: appliedAtColumnName, definition: "TIMESTAMP NULL"},
+	{version: 3, column: durationColumnName, definition: "BIGINT NULL"},
+	{version: 4, column: rolledBackAtColumnName, definition: "TIMESTAMP NULL"},
+}
+
+// SchemaTableVersion returns the version of the migration table created and
+// upgraded by this release. Each version only adds nullable columns to the
+// previous one, so a table upgraded by a release remains usable by the
+// releases before it.
+func SchemaTableVersion() int {
+	return tableUpgrades[len(tableUpgrades)-1].version
+}
+
+// TableVersion returns the version of the migration table of the database,
+// 0 when the table doesn't exist. The table is upgraded to SchemaTableVersion
+// when migrations are run.
+func (g *Sqlxmigrate) TableVersion() (int, error) {
+	return g.tableVersion(context.Background())
+}
+
+func (g *Sqlxmigrate) tableVersion(ctx context.Context) (int, error) {
+	if ok, err := g.tableExists(ctx, g.options.TableName); !ok || err != nil {
+		return 0, err
+	}
+
+	version := 1
+	for _, u := range tableUpgrades {
+		ok, err := g.hasColumn(ctx, g.options.TableName, u.column)
+		if err != nil {
+			return 0, err
+		} else if !ok {
+			break
+		}
+		version = u.version
+	}
+	return version, nil
+}
+
+// upgradeMigrationTable adds the columns missing from the migration table.
+func (g *Sqlxmigrate) upgradeMigrationTable(ctx context.Context) error {
+	for _, u := range tableUpgrades {
+		ok, err := g.hasColumn(ctx, g.options.TableName, u.column)
+		if err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+
+		sql := g.dialect().AddColumnSQL(g.options.TableName, u.column, u.definition)
+		g.logf("upgradeMigrationTable %d - %s", u.version, sql)
+
+		if _, err := g.db.ExecContext(ctx, sql); err != nil {
+			err = queryError(err, sql)
+			return err
+		}
+	}
+	return nil
+}
+
+// appliedMetadata is what the migration table records about an applied
+// migration besides its ID.
+type appliedMetadata struct {
+	appliedAt *time.Time
+	duration  *time.Duration
+	checksum  string
+}
+
+// storeMetadata records when a migration was applied, its duration and its
+// checksum, as part of the current transaction.
+func (g *Sqlxmigrate) storeMetadata(ctx context.Context, migration *Migration, duration time.Duration) error {
+	h, ok := g.history.(*tableHistory)
+	if !ok {
+		return nil
+	}
+
+	var sum sql.NullString
+	if s := migration.scriptChecksum(); s != "" {
+		sum = sql.NullString{String: s, Valid: true}
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET %s = ?, %s = ?, %s = ? WHERE %s = ?",
+		g.options.TableName, appliedAtColumnName, durationColumnName, checksumColumnName, g.options.IDColumnName)
+	sql = g.rebind(sql)
+	g.logf("Migration %s - storing its metadata", migration.ID)
+
+	_, err := h.exec(ctx, g.tx, &h.metadata, sql, time.Now().UTC(), duration.Milliseconds(), sum, migration.ID)
+	return err
+}
+
+// updateChecksum records the current checksum of an applied migration, as
+// part of the current transaction. Only the migration table stores the
+// checksums.
+func (g *Sqlxmigrate) updateChecksum(ctx context.Context, migration *Migration) error {
+	h, ok := g.history.(*tableHistory)
+	if !ok {
+		return ErrUnsupportedDriver
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", g.options.TableName, checksumColumnName, g.options.IDColumnName)
+	sql = g.rebind(sql)
+	g.logf("Migration %s - storing its checksum", migration.ID)
+
+	_, err := h.exec(ctx, g.tx, &h.checksum, sql, migration.scriptChecksum(), migration.ID)
+	return err
+}
+
+// appliedMetadata returns what the migration table records about the applied
+// migrations, empty when the table wasn't upgraded yet.
+func (g *Sqlxmigrate) appliedMetadata(ctx context.Context) (map[string]appliedMetadata, error) {
+	metadata := make(map[string]appliedMetadata)
+	if !g.usesTable() {
+		return metadata, nil
+	}
+	if version, err := g.tableVersion(ctx); version < 3 || err != nil {
+		return metadata, err
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s FROM %s",
+		g.options.IDColumnName, appliedAtColumnName, durationColumnName, checksumColumnName, g.options.TableName)
+	g.logf("appliedMetadata %s", query)
+
+	rows, err := g.db.QueryContext(ctx, query)
+	if err != nil {
+		err = queryError(err, query)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var appliedAt interface{}
+		var duration sql.NullInt64
+		var sum sql.NullString
+		if err := rows.Scan(&id, &appliedAt, &duration, &sum); err != nil {
+			return nil, err
+		}
+
+		m := appliedMetadata{checksum: sum.String}
+		if m.appliedAt, err = parseTimestamp(appliedAt); err != nil {
+			return nil, err
+		}
+		if duration.Valid {
+			d := time.Duration(duration.Int64) * time.Millisecond
+			m.duration = &d
+		}
+		metadata[id] = m
+	}
+	return metadata, rows.Err()
+}
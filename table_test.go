@@ -0,0 +1,109 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableVersion(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, migrations)
+
+		version, err := m.TableVersion()
+		require.NoError(t, err)
+		assert.Equal(t, 0, version)
+
+		require.NoError(t, m.Migrate())
+
+		version, err = m.TableVersion()
+		require.NoError(t, err)
+		assert.Equal(t, SchemaTableVersion(), version)
+	})
+}
+
+func TestUpgradeMigrationTable(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		// the migration table as created by the first release
+		_, err := db.Exec("CREATE TABLE migrations (id VARCHAR(255) PRIMARY KEY)")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO migrations (id) VALUES ('201608301400')")
+		require.NoError(t, err)
+
+		m := New(db, DefaultOptions, migrations)
+
+		version, err := m.TableVersion()
+		require.NoError(t, err)
+		assert.Equal(t, 1, version)
+
+		require.NoError(t, m.MigrateTo("201608301400"))
+
+		version, err = m.TableVersion()
+		require.NoError(t, err)
+		assert.Equal(t, SchemaTableVersion(), version)
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+	})
+}
+
+func TestStoreMetadata(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		checksummed := []*Migration{{
+			ID:       "201608301400",
+			Migrate:  migrations[0].Migrate,
+			Checksum: checksum("CREATE TABLE people"),
+		}}
+		m := New(db, DefaultOptions, checksummed)
+		before := time.Now().UTC().Add(-time.Minute)
+		require.NoError(t, m.Migrate())
+
+		metadata, err := m.appliedMetadata(context.Background())
+		require.NoError(t, err)
+		md := metadata["201608301400"]
+		require.NotNil(t, md.appliedAt)
+		assert.True(t, md.appliedAt.After(before))
+		require.NotNil(t, md.duration)
+		assert.Equal(t, checksum("CREATE TABLE people"), md.checksum)
+		require.NoError(t, m.Validate())
+
+		checksummed[0].Checksum = checksum("CREATE TABLE persons")
+		assert.Equal(t, &ChecksumMismatchError{
+			ID:       "201608301400",
+			Stored:   checksum("CREATE TABLE people"),
+			Computed: checksum("CREATE TABLE persons"),
+		}, m.Validate())
+	})
+}
+
+func TestScriptChecksum(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		script := []*Migration{{
+			ID:         "201608301500",
+			MigrateSQL: "CREATE TABLE cars (id int PRIMARY KEY);\nCREATE TABLE trucks (id int PRIMARY KEY);",
+		}}
+		m := New(db, DefaultOptions, script)
+		require.NoError(t, m.Migrate())
+
+		metadata, err := m.appliedMetadata(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, checksum(script[0].MigrateSQL), metadata["201608301500"].checksum)
+		require.NoError(t, m.Validate())
+
+		script[0].MigrateSQL = "CREATE TABLE cars (id int PRIMARY KEY);"
+		var mismatch *ChecksumMismatchError
+		assert.True(t, errors.As(m.Validate(), &mismatch))
+	})
+}
+
+func TestAddColumnSQL(t *testing.T) {
+	m := New(sqlx.NewDb(nil, "postgres"), DefaultOptions, migrations)
+	assert.Equal(t, "ALTER TABLE migrations ADD COLUMN applied_at TIMESTAMP NULL", m.dialect().AddColumnSQL("migrations", appliedAtColumnName, "TIMESTAMP NULL"))
+
+	m = New(sqlx.NewDb(nil, "sqlserver"), DefaultOptions, migrations)
+	assert.Equal(t, "ALTER TABLE migrations ADD applied_at DATETIME2 NULL", m.dialect().AddColumnSQL("migrations", appliedAtColumnName, "TIMESTAMP NULL"))
+	assert.Equal(t, "ALTER TABLE migrations ADD duration_ms BIGINT NULL", m.dialect().AddColumnSQL("migrations", durationColumnName, "BIGINT NULL"))
+}
@@ -0,0 +1,30 @@
+package sqlxmigrate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// newRunID returns a random identifier of a run.
+func newRunID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// statementTag returns the comment prepended to the statements of the SQL
+// scripts of a migration, so they can be attributed to the migration and
+// the run in pg_stat_activity or the process list. Empty when
+// Options.DisableStatementTags is set.
+func (g *Sqlxmigrate) statementTag(migrationID string) string {
+	if g.options.DisableStatementTags {
+		return ""
+	}
+	// The ID can't end the comment.
+	id := strings.Replace(migrationID, "*/", "* /", -1)
+	return fmt.Sprintf("/* sqlxmigrate id=%s run=%s */ ", id, g.runID)
+}
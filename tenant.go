@@ -0,0 +1,102 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// tenants tracks the tenant schemas migrated by the process.
+var tenants = &tenantRegistry{
+	locks:    make(map[string]*sync.Mutex),
+	migrated: make(map[string]bool),
+}
+
+type tenantRegistry struct {
+	mu       sync.Mutex
+	locks    map[string]*sync.Mutex
+	migrated map[string]bool
+}
+
+// lock returns the lock of a tenant, so concurrent first uses of the tenant
+// migrate it once.
+func (r *tenantRegistry) lock(key string) *sync.Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		r.locks[key] = l
+	}
+	return l
+}
+
+func (r *tenantRegistry) isMigrated(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.migrated[key]
+}
+
+func (r *tenantRegistry) setMigrated(key string, migrated bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.migrated[key] = migrated
+}
+
+// EnsureTenantMigrated migrates the schema of a tenant the first time the
+// process uses it, so new tenants don't require a provisioning run. The
+// schema is created when missing and the migrations are recorded in a
+// migration table of the same name in it. Concurrent calls for a tenant wait
+// for the first one, later calls return once the tenant was migrated by the
+// process. The init schema and the audit table are left out. Only PostgreSQL
+// is supported.
+//
+// The name of the tenant often comes from the request, it fails with an
+// InvalidSchemaError before any SQL runs when it isn't a plain identifier.
+func (g *Sqlxmigrate) EnsureTenantMigrated(ctx context.Context, tenant string) error {
+	if !g.isPostgres() {
+		return ErrUnsupportedDriver
+	}
+
+	t, err := g.staging(tenant)
+	if err != nil {
+		return err
+	}
+	key := t.cacheKey()
+	if tenants.isMigrated(key) {
+		return nil
+	}
+
+	l := tenants.lock(key)
+	l.Lock()
+	defer l.Unlock()
+
+	// Migrated while waiting for the lock.
+	if tenants.isMigrated(key) {
+		return nil
+	}
+
+	// The name was checked by staging.
+	sql := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", tenant)
+	g.logf("EnsureTenantMigrated %s", sql)
+
+	if _, err := g.db.ExecContext(ctx, sql); err != nil {
+		err = queryError(err, sql)
+		return err
+	}
+	if err := t.MigrateContext(ctx); err != nil {
+		return err
+	}
+
+	tenants.setMigrated(key, true)
+	return nil
+}
+
+// ForgetTenant makes the next EnsureTenantMigrated of a tenant migrate its
+// schema again, ie. once the schema was dropped.
+func (g *Sqlxmigrate) ForgetTenant(tenant string) {
+	if t, err := g.staging(tenant); err == nil {
+		tenants.setMigrated(t.cacheKey(), false)
+	}
+}
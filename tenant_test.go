@@ -0,0 +1,67 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureTenantMigrated(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		for _, tenant := range []string{"sqlxmigrate_tenant_a", "sqlxmigrate_tenant_b"} {
+			_, err := db.Exec("DROP SCHEMA IF EXISTS " + tenant + " CASCADE")
+			require.NoError(t, err)
+			defer db.Exec("DROP SCHEMA " + tenant + " CASCADE")
+		}
+
+		var runs int32
+		counted := []*Migration{{
+			ID: "201608301400",
+			Migrate: func(tx *sql.Tx) error {
+				atomic.AddInt32(&runs, 1)
+				_, err := tx.Exec("CREATE TABLE people (id INTEGER PRIMARY KEY)")
+				return err
+			},
+		}}
+		m := New(db, DefaultOptions, counted)
+		defer m.ForgetTenant("sqlxmigrate_tenant_a")
+		defer m.ForgetTenant("sqlxmigrate_tenant_b")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, m.EnsureTenantMigrated(context.Background(), "sqlxmigrate_tenant_a"))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+		assert.True(t, m.hasTable("sqlxmigrate_tenant_a.people"))
+		assert.Equal(t, 1, tableCount(t, db, "sqlxmigrate_tenant_a.migrations"))
+		assert.False(t, m.hasTable("people"))
+
+		require.NoError(t, m.EnsureTenantMigrated(context.Background(), "sqlxmigrate_tenant_b"))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+		assert.True(t, m.hasTable("sqlxmigrate_tenant_b.people"))
+	}, "postgres")
+}
+
+func TestEnsureTenantMigratedUnsupportedDriver(t *testing.T) {
+	m := New(sqlx.NewDb(nil, "mysql"), DefaultOptions, migrations)
+	assert.Equal(t, ErrUnsupportedDriver, m.EnsureTenantMigrated(context.Background(), "tenant"))
+}
+
+func TestEnsureTenantMigratedInvalidName(t *testing.T) {
+	m := New(sqlx.NewDb(nil, "postgres"), DefaultOptions, migrations)
+	tenant := "tenant_42; DROP TABLE people"
+
+	assert.Equal(t, &InvalidSchemaError{Schema: tenant}, m.EnsureTenantMigrated(context.Background(), tenant))
+	m.ForgetTenant(tenant)
+}
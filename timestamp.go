@@ -0,0 +1,55 @@
+package sqlxmigrate
+
+// TimestampType returns the type of a column holding a point in time for the
+// database of the driver: timestamptz on PostgreSQL, DATETIME(6) on MySQL,
+// whose TIMESTAMP type ends in 2038, and DATETIMEOFFSET on SQL Server.
+//
+// DATETIME has no time zone on MySQL: the values are the ones of the time
+// zone of the session, which should be UTC, ie. with the loc=UTC parameter of
+// the driver. SQLite stores the values as UTC text.
+func TimestampType(driverName string) string {
+	switch {
+	case isPostgresDriver(driverName):
+		return "timestamptz"
+	case isMySQLDriver(driverName):
+		return "DATETIME(6)"
+	case isSQLiteDriver(driverName):
+		return "TIMESTAMP"
+	case isSQLServerDriver(driverName):
+		return "DATETIMEOFFSET(6)"
+	default:
+		return "TIMESTAMP WITH TIME ZONE"
+	}
+}
+
+// CurrentTimestamp returns the expression of the current time for the
+// database of the driver, with the precision of TimestampType.
+func CurrentTimestamp(driverName string) string {
+	switch {
+	case isPostgresDriver(driverName):
+		return "now()"
+	case isMySQLDriver(driverName):
+		return "CURRENT_TIMESTAMP(6)"
+	case isSQLServerDriver(driverName):
+		return "SYSDATETIMEOFFSET()"
+	default:
+		return "CURRENT_TIMESTAMP"
+	}
+}
+
+// CreatedAtColumn returns the definition of a NOT NULL timestamp column
+// defaulting to the time the row is inserted.
+func CreatedAtColumn(driverName string) string {
+	return TimestampType(driverName) + " NOT NULL DEFAULT " + CurrentTimestamp(driverName)
+}
+
+// UpdatedAtColumn returns the definition of a NOT NULL timestamp column
+// defaulting to the time the row is inserted. MySQL also sets it to the time
+// the row is updated, the other databases require the updates to set it or
+// a trigger.
+func UpdatedAtColumn(driverName string) string {
+	if isMySQLDriver(driverName) {
+		return CreatedAtColumn(driverName) + " ON UPDATE " + CurrentTimestamp(driverName)
+	}
+	return CreatedAtColumn(driverName)
+}
@@ -0,0 +1,48 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestampColumns(t *testing.T) {
+	for driverName, columns := range map[string][2]string{
+		"postgres":  {"timestamptz NOT NULL DEFAULT now()", "timestamptz NOT NULL DEFAULT now()"},
+		"pgx":       {"timestamptz NOT NULL DEFAULT now()", "timestamptz NOT NULL DEFAULT now()"},
+		"mysql":     {"DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)", "DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6)"},
+		"sqlite3":   {"TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP", "TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP"},
+		"sqlserver": {"DATETIMEOFFSET(6) NOT NULL DEFAULT SYSDATETIMEOFFSET()", "DATETIMEOFFSET(6) NOT NULL DEFAULT SYSDATETIMEOFFSET()"},
+		"mssql":     {"DATETIMEOFFSET(6) NOT NULL DEFAULT SYSDATETIMEOFFSET()", "DATETIMEOFFSET(6) NOT NULL DEFAULT SYSDATETIMEOFFSET()"},
+		"other":     {"TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP", "TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP"},
+	} {
+		assert.Equal(t, columns[0], CreatedAtColumn(driverName), driverName)
+		assert.Equal(t, columns[1], UpdatedAtColumn(driverName), driverName)
+	}
+}
+
+func TestCreatedAtColumn(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		_, err := db.Exec(fmt.Sprintf("CREATE TABLE cars (name VARCHAR(20), created_at %s, updated_at %s)",
+			CreatedAtColumn(db.DriverName()), UpdatedAtColumn(db.DriverName())))
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO cars (name) VALUES ('beetle')")
+		require.NoError(t, err)
+
+		var createdAt, updatedAt interface{}
+		require.NoError(t, db.QueryRow("SELECT created_at, updated_at FROM cars").Scan(&createdAt, &updatedAt))
+		for _, v := range []interface{}{createdAt, updatedAt} {
+			ts, err := parseTimestamp(v)
+			require.NoError(t, err)
+			require.NotNil(t, ts)
+			assert.WithinDuration(t, time.Now(), *ts, 24*time.Hour)
+		}
+
+		assert.NoError(t, dropTableIfExists(db, "cars"))
+	})
+}
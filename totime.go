@@ -0,0 +1,78 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"time"
+)
+
+// idTime returns the time of the timestamp a migration ID starts with,
+// read as UTC.
+func idTime(id string) (time.Time, error) {
+	prefix := timestampPrefix(id)
+	if layout, ok := idTimestampLayouts[len(prefix)]; ok {
+		if t, err := time.Parse(layout, prefix); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrMissingTimestamp
+}
+
+// IDAtTime returns the ID of the last migration whose timestamp isn't after
+// `t`, the migration a database was migrated to at that time. The IDs must
+// start with a timestamp formatted as 20060102150405, 200601021504 or
+// 20060102, in UTC, it fails with ErrMissingTimestamp otherwise.
+func (g *Sqlxmigrate) IDAtTime(t time.Time) (string, error) {
+	if !g.hasMigrations() {
+		return "", ErrNoMigrationDefined
+	}
+
+	var id string
+	for _, m := range g.migrations {
+		at, err := idTime(m.ID)
+		if err != nil {
+			return "", err
+		}
+		if !at.After(t) {
+			id = m.ID
+		}
+	}
+	if id == "" {
+		return "", ErrNoMigrationAtTime
+	}
+	return id, nil
+}
+
+// MigrateToTime executes the migrations that did not run yet up to the last
+// one whose timestamp isn't after `t`, ie. to bring a database to the state
+// of a past release. See IDAtTime.
+func (g *Sqlxmigrate) MigrateToTime(t time.Time) error {
+	return g.MigrateToTimeContext(context.Background(), t)
+}
+
+// MigrateToTimeContext is MigrateToTime with a context aborting the run when
+// done.
+func (g *Sqlxmigrate) MigrateToTimeContext(ctx context.Context, t time.Time) error {
+	id, err := g.IDAtTime(t)
+	if err != nil {
+		return err
+	}
+	g.logf("Migrate - %s resolved to %s", t.UTC().Format(time.RFC3339), id)
+	return g.MigrateToContext(ctx, id)
+}
+
+// RollbackToTime undoes the migrations whose timestamp is after `t`. See
+// IDAtTime.
+func (g *Sqlxmigrate) RollbackToTime(t time.Time) error {
+	return g.RollbackToTimeContext(context.Background(), t)
+}
+
+// RollbackToTimeContext is RollbackToTime with a context aborting the
+// rollback when done.
+func (g *Sqlxmigrate) RollbackToTimeContext(ctx context.Context, t time.Time) error {
+	id, err := g.IDAtTime(t)
+	if err != nil {
+		return err
+	}
+	g.logf("Rollback - %s resolved to %s", t.UTC().Format(time.RFC3339), id)
+	return g.RollbackToContext(ctx, id)
+}
@@ -0,0 +1,51 @@
+package sqlxmigrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDAtTime(t *testing.T) {
+	m := New(nil, DefaultOptions, extendedMigrations)
+
+	id, err := m.IDAtTime(time.Date(2016, 8, 30, 14, 29, 59, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "201608301400", id)
+
+	id, err = m.IDAtTime(time.Date(2016, 8, 30, 14, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "201608301430", id)
+
+	// The times in other zones are compared as UTC.
+	id, err = m.IDAtTime(time.Date(2018, 7, 22, 21, 30, 0, 0, time.FixedZone("CEST", 2*60*60)))
+	require.NoError(t, err)
+	assert.Equal(t, "201807221927", id)
+
+	_, err = m.IDAtTime(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, ErrNoMigrationAtTime, err)
+
+	m = New(nil, DefaultOptions, []*Migration{{ID: "create_people"}})
+	_, err = m.IDAtTime(time.Now())
+	assert.Equal(t, ErrMissingTimestamp, err)
+}
+
+func TestMigrateToTime(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+
+		require.NoError(t, m.MigrateToTime(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)))
+		assert.Equal(t, 2, tableCount(t, db, "migrations"))
+		assert.True(t, m.hasTable("pets"))
+		assert.False(t, m.hasTable("books"))
+
+		require.NoError(t, m.Migrate())
+		require.NoError(t, m.RollbackToTime(time.Date(2016, 8, 30, 14, 15, 0, 0, time.UTC)))
+		assert.Equal(t, 1, tableCount(t, db, "migrations"))
+		assert.True(t, m.hasTable("people"))
+		assert.False(t, m.hasTable("pets"))
+	})
+}
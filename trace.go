@@ -0,0 +1,47 @@
+package sqlxmigrate
+
+import (
+	"context"
+)
+
+// Direction is whether a migration is applied or rolled back.
+type Direction string
+
+const (
+	// DirectionMigrate is a migration being applied.
+	DirectionMigrate Direction = auditMigrate
+	// DirectionRollback is a migration being rolled back.
+	DirectionRollback Direction = auditRollback
+)
+
+// Tracer traces the runs and their migrations, ie. with the OpenTelemetry
+// tracer of the otel subpackage. The contexts it returns are the ones the
+// migrations receive, so their queries are traced as children of the
+// migration.
+type Tracer interface {
+	// StartRun is called when a run applying or rolling back migrations
+	// starts. The returned function is called with the error of the run once
+	// it ended.
+	StartRun(ctx context.Context, direction Direction) (context.Context, func(err error))
+	// StartMigration is called before each migration is applied or rolled
+	// back. The returned function is called with the statistics of the
+	// statements of the migration, zero when it disables the transaction,
+	// and the error it failed with.
+	StartMigration(ctx context.Context, migrationID string, direction Direction) (context.Context, func(stats Stats, err error))
+}
+
+// startRun starts the trace of a run when Options.Tracer is set.
+func (g *Sqlxmigrate) startRun(ctx context.Context, direction Direction) (context.Context, func(error)) {
+	if g.options.Tracer == nil {
+		return ctx, func(error) {}
+	}
+	return g.options.Tracer.StartRun(ctx, direction)
+}
+
+// startMigration starts the trace of a migration when Options.Tracer is set.
+func (g *Sqlxmigrate) startMigration(ctx context.Context, migrationID string, direction Direction) (context.Context, func(Stats, error)) {
+	if g.options.Tracer == nil {
+		return ctx, func(Stats, error) {}
+	}
+	return g.options.Tracer.StartMigration(ctx, migrationID, direction)
+}
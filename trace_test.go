@@ -0,0 +1,100 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type traceKey struct{}
+
+// recordingTracer records the spans it starts and ends.
+type recordingTracer struct {
+	spans []string
+}
+
+func (r *recordingTracer) StartRun(ctx context.Context, direction Direction) (context.Context, func(error)) {
+	r.spans = append(r.spans, fmt.Sprintf("start %s", direction))
+	return context.WithValue(ctx, traceKey{}, string(direction)), func(err error) {
+		r.spans = append(r.spans, fmt.Sprintf("end %s %v", direction, err))
+	}
+}
+
+func (r *recordingTracer) StartMigration(ctx context.Context, migrationID string, direction Direction) (context.Context, func(Stats, error)) {
+	r.spans = append(r.spans, fmt.Sprintf("start %s %s in %v", direction, migrationID, ctx.Value(traceKey{})))
+	return context.WithValue(ctx, traceKey{}, migrationID), func(stats Stats, err error) {
+		r.spans = append(r.spans, fmt.Sprintf("end %s %s %d %v", direction, migrationID, stats.Statements, err))
+	}
+}
+
+func TestTracer(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		tracer := &recordingTracer{}
+		options := *DefaultOptions
+		options.Tracer = tracer
+
+		var traced interface{}
+		traceMigration := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				traced = tx.Context().Value(traceKey{})
+				_, err := tx.Exec("SELECT 1")
+				return err
+			},
+			RollbackTx: func(tx *Tx) error {
+				return nil
+			},
+		}
+		m := New(db, &options, append(migrations[:1:1], traceMigration))
+
+		require.NoError(t, m.Migrate())
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, "201608301500", traced)
+		assert.Equal(t, []string{
+			"start migrate",
+			"start migrate 201608301400 in migrate",
+			"end migrate 201608301400 0 <nil>",
+			"start migrate 201608301500 in migrate",
+			"end migrate 201608301500 1 <nil>",
+			"end migrate <nil>",
+			"start rollback",
+			"start rollback 201608301500 in rollback",
+			"end rollback 201608301500 0 <nil>",
+			"end rollback <nil>",
+		}, tracer.spans)
+
+		require.NoError(t, m.RollbackLast())
+	})
+}
+
+func TestTracerFailedMigration(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		tracer := &recordingTracer{}
+		options := *DefaultOptions
+		options.Tracer = tracer
+
+		errFailed := errors.New("failed")
+		m := New(db, &options, []*Migration{{
+			ID: "201608301400",
+			MigrateTx: func(tx *Tx) error {
+				return errFailed
+			},
+			RollbackTx: func(tx *Tx) error {
+				return nil
+			},
+		}})
+
+		assert.True(t, errors.Is(m.Migrate(), errFailed))
+		assert.Equal(t, []string{
+			"start migrate",
+			"start migrate 201608301400 in migrate",
+			"end migrate 201608301400 0 failed",
+			`end migrate sqlxmigrate: Migration "201608301400" failed: failed`,
+		}, tracer.spans)
+	})
+}
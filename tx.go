@@ -0,0 +1,259 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TooManyRowsAffectedError is returned when a statement affects more rows
+// than allowed by Options.MaxRowsAffected.
+type TooManyRowsAffectedError struct {
+	RowsAffected    int64
+	MaxRowsAffected int64
+}
+
+func (e *TooManyRowsAffectedError) Error() string {
+	return fmt.Sprintf("sqlxmigrate: Statement affected %d rows, more than the maximum of %d, use batches for large changes", e.RowsAffected, e.MaxRowsAffected)
+}
+
+// Hint implements Hinter.
+func (e *TooManyRowsAffectedError) Hint() string {
+	return "Split the change in batches, or raise Options.MaxRowsAffected if the statement is expected to change that many rows."
+}
+
+// MigrationError is returned when a migration fails to apply or to roll
+// back, wrapping the error of the migration.
+type MigrationError struct {
+	MigrationID string
+	Direction   Direction
+	// Query is the statement that failed, when it was executed through the
+	// Tx of the migration.
+	Query string
+	Err   error
+}
+
+func (e *MigrationError) Error() string {
+	action := "Migration"
+	if e.Direction == DirectionRollback {
+		action = "Rollback of migration"
+	}
+	if e.Query != "" {
+		return fmt.Sprintf(`sqlxmigrate: %s "%s" failed running "%s": %v`, action, e.MigrationID, e.Query, e.Err)
+	}
+	return fmt.Sprintf(`sqlxmigrate: %s "%s" failed: %v`, action, e.MigrationID, e.Err)
+}
+
+// Unwrap returns the error of the migration.
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// Stats are the statistics of the statements executed through a Tx.
+type Stats struct {
+	// Statements is the number of executed statements.
+	Statements int
+	// RowsAffected is the total number of rows affected by the statements.
+	RowsAffected int64
+}
+
+// Execer executes the statements of a migration, implemented by *sql.Tx.
+// Options.WrapTx wraps it with middleware.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Tx is the transaction of a migration. It accounts the statements executed
+// with Exec and ExecContext, and runs the statements and queries through the
+// middleware of Options.WrapTx. The other methods are the ones of sql.Tx.
+type Tx struct {
+	*sql.Tx
+	g               *Sqlxmigrate
+	migrationID     string
+	ctx             context.Context
+	exec            Execer
+	tag             string
+	stats           Stats
+	maxRowsAffected int64
+	// tooManyRows is the first statement affecting more rows than
+	// maxRowsAffected. It fails the migration even when its function
+	// ignores the error.
+	tooManyRows *TooManyRowsAffectedError
+	// dbTime is the time spent executing the statements and queries.
+	dbTime time.Duration
+	// failedQuery is the last statement or query that failed.
+	failedQuery string
+}
+
+// newTx returns the instrumented transaction of a migration of the current
+// run.
+func (g *Sqlxmigrate) newTx(ctx context.Context, migrationID string) *Tx {
+	tx := &Tx{
+		Tx:              g.tx,
+		g:               g,
+		migrationID:     migrationID,
+		ctx:             ctx,
+		exec:            g.tx,
+		tag:             g.statementTag(migrationID),
+		maxRowsAffected: g.options.MaxRowsAffected,
+	}
+	if g.options.WrapTx != nil {
+		tx.exec = g.options.WrapTx(migrationID, g.tx)
+	}
+	return tx
+}
+
+// Context returns the context of the run.
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
+// Exec executes a query that doesn't return rows, with the context of the
+// run.
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(tx.ctx, query, args...)
+}
+
+// ExecContext executes a query that doesn't return rows.
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	tx.stats.Statements++
+
+	start := time.Now()
+	res, err := tx.exec.ExecContext(ctx, query, args...)
+	tx.dbTime += time.Since(start)
+	if err != nil {
+		tx.fail(query)
+		return res, err
+	}
+
+	// Not every driver reports the number of affected rows.
+	if n, err := res.RowsAffected(); err == nil {
+		tx.stats.RowsAffected += n
+
+		if tx.maxRowsAffected > 0 && n > tx.maxRowsAffected {
+			tx.fail(query)
+			err := &TooManyRowsAffectedError{RowsAffected: n, MaxRowsAffected: tx.maxRowsAffected}
+			if tx.tooManyRows == nil {
+				tx.tooManyRows = err
+			}
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// fail records the statement or query that failed, without the tag of the
+// statements of the SQL scripts, redacted as it would be logged.
+func (tx *Tx) fail(query string) {
+	tx.failedQuery = tx.g.Redact(strings.TrimPrefix(query, tx.tag))
+}
+
+// migrationError wraps the error of the migration of the transaction.
+func (tx *Tx) migrationError(direction Direction, err error) error {
+	return &MigrationError{MigrationID: tx.migrationID, Direction: direction, Query: tx.failedQuery, Err: err}
+}
+
+// Query executes a query that returns rows, with the context of the run.
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(tx.ctx, query, args...)
+}
+
+// QueryContext executes a query that returns rows.
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	// The time spent reading the rows isn't included.
+	start := time.Now()
+	rows, err := tx.exec.QueryContext(ctx, query, args...)
+	tx.dbTime += time.Since(start)
+	if err != nil {
+		tx.fail(query)
+	}
+	return rows, err
+}
+
+// QueryRow executes a query that returns at most one row, with the context
+// of the run.
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.QueryRowContext(tx.ctx, query, args...)
+}
+
+// QueryRowContext executes a query that returns at most one row.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	defer func() { tx.dbTime += time.Since(start) }()
+	return tx.exec.QueryRowContext(ctx, query, args...)
+}
+
+// Stats returns the statistics of the statements executed so far.
+func (tx *Tx) Stats() Stats {
+	return tx.stats
+}
+
+func (m *Migration) migrate(tx *Tx) error {
+	if m.MigrateTx != nil {
+		return m.MigrateTx(tx)
+	}
+	if m.MigrateContext != nil {
+		return m.MigrateContext(tx.ctx, tx.Tx)
+	}
+	if m.Migrate == nil && m.MigrateSQL != "" {
+		return tx.execScript(m.MigrateSQL)
+	}
+	return m.Migrate(tx.Tx)
+}
+
+// scriptChecksum returns the checksum of the migration, Checksum or the one
+// of MigrateSQL when the migration executes it.
+func (m *Migration) scriptChecksum() string {
+	if m.Checksum == "" && m.MigrateSQL != "" && m.Migrate == nil && m.MigrateContext == nil && m.MigrateTx == nil {
+		return checksum(m.MigrateSQL)
+	}
+	return m.Checksum
+}
+
+func (m *Migration) migrateNoTx(db *sqlx.DB) error {
+	if m.MigrateNoTx == nil {
+		return ErrMissingMigrateNoTx
+	}
+	return m.MigrateNoTx(db)
+}
+
+// execScript executes the statements of a SQL script one by one, tagged
+// with the migration and the run.
+func (tx *Tx) execScript(script string) error {
+	for _, stmt := range splitStatements(tx.g.db.DriverName(), script) {
+		if _, err := tx.Exec(tx.tag + stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migration) hasRollback() bool {
+	if m.irreversible() {
+		return false
+	}
+	return m.RollbackTx != nil || m.RollbackContext != nil || m.Rollback != nil || m.RollbackSQL != "" ||
+		(m.DisableTransaction && m.RollbackNoTx != nil)
+}
+
+func (m *Migration) rollback(tx *Tx) error {
+	if m.RollbackTx != nil {
+		return m.RollbackTx(tx)
+	}
+	if m.RollbackContext != nil {
+		return m.RollbackContext(tx.ctx, tx.Tx)
+	}
+	if m.Rollback != nil {
+		return m.Rollback(tx.Tx)
+	}
+	if m.RollbackSQL != "" {
+		return tx.execScript(m.RollbackSQL)
+	}
+	return ErrRollbackImpossible
+}
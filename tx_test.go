@@ -0,0 +1,266 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationStats(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		assert.NoError(t, dropTableIfExists(db, "migration_audit"))
+
+		seed := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				if _, err := tx.Exec(`INSERT INTO people (name) VALUES ('alice'), ('bob')`); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`UPDATE people SET name = 'carol'`)
+				return err
+			},
+			RollbackTx: func(tx *Tx) error {
+				_, err := tx.Exec(`DELETE FROM people`)
+				return err
+			},
+		}
+
+		options := &Options{AuditTableName: "migration_audit"}
+		m := New(db, options, append([]*Migration{migrations[0]}, seed))
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		require.Len(t, res.Migrations, 2)
+		assert.Equal(t, "201608301500", res.Migrations[1].ID)
+		assert.Equal(t, Stats{Statements: 2, RowsAffected: 4}, res.Migrations[1].Stats)
+
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, 0, tableCount(t, db, "people"))
+
+		var audit []struct {
+			ID           string `db:"id"`
+			Direction    string `db:"direction"`
+			Statements   int    `db:"statements"`
+			RowsAffected int64  `db:"rows_affected"`
+		}
+		require.NoError(t, db.Select(&audit, "SELECT id, direction, statements, rows_affected FROM migration_audit WHERE id = '201608301500' ORDER BY direction"))
+		require.Len(t, audit, 2)
+		assert.Equal(t, "migrate", audit[0].Direction)
+		assert.Equal(t, 2, audit[0].Statements)
+		assert.Equal(t, int64(4), audit[0].RowsAffected)
+		assert.Equal(t, "rollback", audit[1].Direction)
+		assert.Equal(t, int64(2), audit[1].RowsAffected)
+
+		assert.NoError(t, dropTableIfExists(db, "migration_audit"))
+	})
+}
+
+func TestMaxRowsAffected(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		seed := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				if _, err := tx.Exec(`INSERT INTO people (name) VALUES ('alice'), ('bob'), ('carol')`); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`UPDATE people SET name = 'dave'`)
+				return err
+			},
+			RollbackTx: func(tx *Tx) error {
+				_, err := tx.Exec(`DELETE FROM people`)
+				return err
+			},
+		}
+
+		m := New(db, &Options{MaxRowsAffected: 2}, []*Migration{migrations[0], seed})
+
+		var tooManyErr *TooManyRowsAffectedError
+		require.True(t, errors.As(m.Migrate(), &tooManyErr))
+		assert.Equal(t, int64(3), tooManyErr.RowsAffected)
+		assert.Equal(t, int64(2), tooManyErr.MaxRowsAffected)
+	})
+}
+
+func TestMaxRowsAffectedIgnored(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		seed := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				// The error is ignored, the migration fails all the same.
+				tx.Exec(`INSERT INTO people (name) VALUES ('alice'), ('bob'), ('carol')`)
+				return nil
+			},
+		}
+
+		m := New(db, &Options{MaxRowsAffected: 2}, []*Migration{migrations[0], seed})
+
+		var tooManyErr *TooManyRowsAffectedError
+		require.True(t, errors.As(m.Migrate(), &tooManyErr))
+		assert.Equal(t, int64(3), tooManyErr.RowsAffected)
+		assert.Equal(t, 0, tableCount(t, db, "migrations"))
+		assert.False(t, m.hasTable("people"))
+	}, "sqlite3", "postgres")
+}
+
+// taggingExecer prefixes the statements with the ID of the migration and
+// records them.
+type taggingExecer struct {
+	Execer
+	id         string
+	statements *[]string
+}
+
+func (e *taggingExecer) tag(query string) string {
+	query = "/* migration:" + e.id + " */ " + query
+	*e.statements = append(*e.statements, query)
+	return query
+}
+
+func (e *taggingExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.Execer.ExecContext(ctx, e.tag(query), args...)
+}
+
+func (e *taggingExecer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return e.Execer.QueryRowContext(ctx, e.tag(query), args...)
+}
+
+func TestWrapTx(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		var statements []string
+		options := *DefaultOptions
+		options.DisableStatementTags = true
+		options.WrapTx = func(migrationID string, tx Execer) Execer {
+			return &taggingExecer{Execer: tx, id: migrationID, statements: &statements}
+		}
+
+		count := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				var n int
+				return tx.QueryRow(`SELECT count(*) FROM people`).Scan(&n)
+			},
+			RollbackSQL: `SELECT 1`,
+		}
+		pets := &Migration{
+			ID:          "201608301501",
+			MigrateSQL:  `CREATE TABLE pets (id INT)`,
+			RollbackSQL: `DROP TABLE pets`,
+		}
+		m := New(db, &options, []*Migration{migrations[0], count, pets})
+
+		require.NoError(t, m.Migrate())
+		assert.True(t, m.hasTable("pets"))
+		// The first migration receives a *sql.Tx, which isn't wrapped.
+		assert.Equal(t, []string{
+			"/* migration:201608301500 */ SELECT count(*) FROM people",
+			"/* migration:201608301501 */ CREATE TABLE pets (id INT)",
+		}, statements)
+
+		statements = nil
+		require.NoError(t, m.RollbackLast())
+		assert.Equal(t, []string{"/* migration:201608301501 */ DROP TABLE pets"}, statements)
+		require.NoError(t, m.Reset())
+	})
+}
+
+// recordingExecer records the statements executed.
+type recordingExecer struct {
+	Execer
+	statements *[]string
+}
+
+func (e *recordingExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*e.statements = append(*e.statements, query)
+	return e.Execer.ExecContext(ctx, query, args...)
+}
+
+func TestStatementTags(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		var statements []string
+		options := *DefaultOptions
+		options.WrapTx = func(migrationID string, tx Execer) Execer {
+			return &recordingExecer{Execer: tx, statements: &statements}
+		}
+
+		pets := &Migration{
+			ID:          "201608301501",
+			MigrateSQL:  "CREATE TABLE pets (id INT);\nCREATE TABLE cars (id INT);",
+			RollbackSQL: "DROP TABLE cars;\nDROP TABLE pets;",
+		}
+		m := New(db, &options, []*Migration{migrations[0], pets})
+
+		require.NoError(t, m.Migrate())
+		require.Len(t, statements, 2)
+		tag := regexp.MustCompile(`^/\* sqlxmigrate id=201608301501 run=([0-9a-f]{32}) \*/ `)
+		run := tag.FindStringSubmatch(statements[0])
+		require.NotNil(t, run, statements[0])
+		assert.Equal(t, run[0]+"CREATE TABLE pets (id INT)", statements[0])
+		assert.Equal(t, run[0]+"CREATE TABLE cars (id INT)", statements[1])
+
+		// Each run has its own ID.
+		statements = nil
+		require.NoError(t, m.RollbackLast())
+		require.Len(t, statements, 2)
+		rollback := tag.FindStringSubmatch(statements[0])
+		require.NotNil(t, rollback, statements[0])
+		assert.NotEqual(t, run[1], rollback[1])
+		assert.Equal(t, rollback[0]+"DROP TABLE cars", statements[0])
+
+		require.NoError(t, m.Reset())
+	})
+}
+
+func TestStatementTagEscaped(t *testing.T) {
+	m := New(nil, DefaultOptions, nil)
+	m.runID = "run"
+	assert.Equal(t, "/* sqlxmigrate id=a* /b run=run */ ", m.statementTag("a*/b"))
+
+	options := *DefaultOptions
+	options.DisableStatementTags = true
+	assert.Equal(t, "", New(nil, &options, nil).statementTag("a"))
+}
+
+func TestMigrationError(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		failing := &Migration{
+			ID:          "201608301500",
+			MigrateSQL:  `INSERT INTO people (name) VALUES ('alice'); INSERT INTO missing (name) VALUES ('bob')`,
+			RollbackSQL: `DELETE FROM people`,
+		}
+		m := New(db, DefaultOptions, []*Migration{migrations[0], failing})
+
+		err := m.Migrate()
+		var migrationErr *MigrationError
+		require.True(t, errors.As(err, &migrationErr))
+		assert.Equal(t, "201608301500", migrationErr.MigrationID)
+		assert.Equal(t, DirectionMigrate, migrationErr.Direction)
+		// The statement tag is left out.
+		assert.Equal(t, "INSERT INTO missing (name) VALUES ('bob')", migrationErr.Query)
+		assert.Error(t, errors.Unwrap(err))
+
+		// The failing rollback of a migration outside of a transaction.
+		errRollback := errors.New("rollback failed")
+		m = New(db, DefaultOptions, []*Migration{{
+			ID:                 "201608301400",
+			DisableTransaction: true,
+			MigrateNoTx: func(db *sqlx.DB) error {
+				return nil
+			},
+			RollbackNoTx: func(db *sqlx.DB) error {
+				return errRollback
+			},
+		}})
+		require.NoError(t, m.Migrate())
+		err = m.RollbackLast()
+		assert.True(t, errors.Is(err, errRollback))
+		require.True(t, errors.As(err, &migrationErr))
+		assert.Equal(t, DirectionRollback, migrationErr.Direction)
+		assert.Empty(t, migrationErr.Query)
+	})
+}
@@ -0,0 +1,78 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"time"
+)
+
+// Usage is the resources used by a migration.
+type Usage struct {
+	// WallTime is how long the migration took.
+	WallTime time.Duration
+	// DBTime is the time spent executing the statements and queries of the
+	// instrumented transaction, measured by the client.
+	DBTime time.Duration
+	// RowsRead and RowsWritten are the rows read and written by the
+	// transaction of the migration, from pg_stat_xact_user_tables on
+	// PostgreSQL and performance_schema on MySQL. Collected with
+	// Options.CollectUsage.
+	RowsRead    int64
+	RowsWritten int64
+	// LockWait is the time the statements waited for table and metadata
+	// locks, from performance_schema on MySQL. PostgreSQL doesn't expose it.
+	// Collected with Options.CollectUsage.
+	LockWait time.Duration
+}
+
+// usageCounters are the cumulative counters of the session or transaction
+// the usage of a migration is the difference of.
+type usageCounters struct {
+	rowsRead    int64
+	rowsWritten int64
+	lockWait    time.Duration
+}
+
+// The usage queries return the rows read, the rows written and, on MySQL,
+// the lock time in picoseconds of the current transaction or session.
+const (
+	postgresUsageQuery = `SELECT coalesce(sum(seq_tup_read + coalesce(idx_tup_fetch, 0)), 0)::bigint,
+			coalesce(sum(n_tup_ins + n_tup_upd + n_tup_del), 0)::bigint
+		FROM pg_stat_xact_user_tables`
+	mysqlUsageQuery = `SELECT coalesce(sum(sum_rows_examined), 0), coalesce(sum(sum_rows_affected), 0), coalesce(sum(sum_lock_time), 0)
+		FROM performance_schema.events_statements_summary_by_thread_by_event_name
+		WHERE thread_id = (SELECT thread_id FROM performance_schema.threads WHERE processlist_id = CONNECTION_ID())`
+)
+
+// readUsageCounters reads the counters of the transaction of the run, false
+// when Options.CollectUsage isn't set or the database doesn't expose them.
+func (g *Sqlxmigrate) readUsageCounters(ctx context.Context) (usageCounters, bool) {
+	var c usageCounters
+	if !g.options.CollectUsage {
+		return c, false
+	}
+
+	switch {
+	case g.isPostgres() && !g.isCockroachDB():
+		if err := g.tx.QueryRowContext(ctx, postgresUsageQuery).Scan(&c.rowsRead, &c.rowsWritten); err != nil {
+			g.logf("Usage - counters not exposed - %v", queryError(err, postgresUsageQuery))
+			return c, false
+		}
+	case g.isMySQL():
+		var lockTime int64
+		if err := g.tx.QueryRowContext(ctx, mysqlUsageQuery).Scan(&c.rowsRead, &c.rowsWritten, &lockTime); err != nil {
+			g.logf("Usage - counters not exposed - %v", queryError(err, mysqlUsageQuery))
+			return c, false
+		}
+		c.lockWait = time.Duration(lockTime / 1000)
+	default:
+		return c, false
+	}
+	return c, true
+}
+
+// addCounters adds the counters since `before` to the usage.
+func (u *Usage) addCounters(before, after usageCounters) {
+	u.RowsRead += after.rowsRead - before.rowsRead
+	u.RowsWritten += after.rowsWritten - before.rowsWritten
+	u.LockWait += after.lockWait - before.lockWait
+}
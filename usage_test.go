@@ -0,0 +1,93 @@
+package sqlxmigrate
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		require.NoError(t, dropTableIfExists(db, "migration_audit"))
+		options := *DefaultOptions
+		options.AuditTableName = "migration_audit"
+		options.CollectUsage = true
+		m := New(db, &options, []*Migration{{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				if _, err := tx.Exec(`CREATE TABLE cars (id int PRIMARY KEY, name varchar(20))`); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`INSERT INTO cars (id, name) VALUES (1, 'a'), (2, 'b')`)
+				return err
+			},
+			Rollback: func(tx *sql.Tx) error {
+				_, err := tx.Exec(`DROP TABLE cars`)
+				return err
+			},
+		}})
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		require.Len(t, res.Migrations, 1)
+		usage := res.Migrations[0].Usage
+		assert.True(t, usage.WallTime > 0)
+		assert.True(t, usage.DBTime > 0)
+		assert.True(t, usage.DBTime <= usage.WallTime)
+
+		var wallTime, dbTime sql.NullInt64
+		require.NoError(t, db.QueryRow(`SELECT wall_time_ms, db_time_ms FROM migration_audit`).Scan(&wallTime, &dbTime))
+		assert.Equal(t, usage.WallTime.Milliseconds(), wallTime.Int64)
+		assert.Equal(t, usage.DBTime.Milliseconds(), dbTime.Int64)
+
+		// The usage of a rollback isn't recorded.
+		require.NoError(t, m.RollbackLast())
+		var rowsWritten sql.NullInt64
+		require.NoError(t, db.QueryRow(`SELECT rows_written FROM migration_audit WHERE direction = 'rollback'`).Scan(&rowsWritten))
+		assert.False(t, rowsWritten.Valid)
+	})
+}
+
+func TestUsageCounters(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.CollectUsage = true
+		m := New(db, &options, []*Migration{{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				if _, err := tx.Exec(`CREATE TABLE cars (id int PRIMARY KEY, name varchar(20))`); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`INSERT INTO cars (id, name) VALUES (1, 'a'), (2, 'b')`)
+				return err
+			},
+		}})
+
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), res.Migrations[0].Usage.RowsWritten)
+	}, "postgres", "mysql")
+}
+
+func TestUpgradeAuditTable(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		require.NoError(t, dropTableIfExists(db, "migration_audit"))
+		// The audit table before the usage columns.
+		_, err := db.Exec(`CREATE TABLE migration_audit (id VARCHAR(255) NOT NULL, direction VARCHAR(16) NOT NULL, statements INTEGER NOT NULL, rows_affected BIGINT NOT NULL, executed_at TIMESTAMP NULL)`)
+		require.NoError(t, err)
+
+		options := *DefaultOptions
+		options.AuditTableName = "migration_audit"
+		m := New(db, &options, migrations)
+		require.NoError(t, m.Migrate())
+
+		var wallTime sql.NullInt64
+		require.NoError(t, db.QueryRow(`SELECT wall_time_ms FROM migration_audit WHERE id = '201608301400'`).Scan(&wallTime))
+		assert.True(t, wallTime.Valid)
+		assert.True(t, time.Duration(wallTime.Int64)*time.Millisecond < time.Minute)
+	}, "sqlite3", "postgres", "mysql")
+}
@@ -0,0 +1,323 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TimestampConflictError is returned when two or more migrations share the
+// same timestamp prefix but have different IDs, which usually happens when
+// two branches adding migrations are merged.
+type TimestampConflictError struct {
+	Prefix string
+	IDs    []string
+}
+
+func (e *TimestampConflictError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migrations "%s" share the timestamp prefix "%s", renumber them so they apply in a deterministic order`,
+		strings.Join(e.IDs, `", "`), e.Prefix)
+}
+
+// Hint implements Hinter.
+func (e *TimestampConflictError) Hint() string {
+	return "Run RenumberPending, or `sqlxmigrate renumber`, to give the pending migrations new timestamps."
+}
+
+// OrphanedMigrationsError is returned when the migration table contains IDs
+// that don't match any migration, which usually means a migration that
+// already ran was deleted from the code.
+type OrphanedMigrationsError struct {
+	IDs []string
+}
+
+func (e *OrphanedMigrationsError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Applied migrations "%s" are missing from the list of migrations`, strings.Join(e.IDs, `", "`))
+}
+
+// Unwrap returns ErrUnknownAppliedMigration.
+func (e *OrphanedMigrationsError) Unwrap() error {
+	return ErrUnknownAppliedMigration
+}
+
+// Hint implements Hinter.
+func (e *OrphanedMigrationsError) Hint() string {
+	return "Deploy the code containing these migrations, or remove their rows with Repair, or `sqlxmigrate repair -remove-unknown`, if they were deleted on purpose."
+}
+
+// InvalidIDError is returned when the ID of a migration doesn't match
+// Options.IDPattern.
+type InvalidIDError struct {
+	ID      string
+	Pattern *regexp.Regexp
+}
+
+func (e *InvalidIDError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migration ID "%s" doesn't match the pattern "%s"`, e.ID, e.Pattern)
+}
+
+// IDTooLongError is returned when the ID of a migration is longer than the
+// ID column of the migration table, so it can't be recorded.
+type IDTooLongError struct {
+	ID   string
+	Size int
+}
+
+func (e *IDTooLongError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Migration ID "%s" is longer than the %d characters of the ID column`, e.ID, e.Size)
+}
+
+// OutOfOrderError is returned when pending migrations sort before the last
+// applied migration and Options.OutOfOrder is SeverityError.
+type OutOfOrderError struct {
+	IDs         []string
+	LastApplied string
+}
+
+func (e *OutOfOrderError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Pending migrations "%s" sort before the last applied migration "%s"`, strings.Join(e.IDs, `", "`), e.LastApplied)
+}
+
+// Unwrap returns ErrOutOfOrderMigration.
+func (e *OutOfOrderError) Unwrap() error {
+	return ErrOutOfOrderMigration
+}
+
+// Hint implements Hinter.
+func (e *OutOfOrderError) Hint() string {
+	return "Run RenumberPending, or `sqlxmigrate renumber`, to give the pending migrations timestamps after the last applied one, or set Options.OutOfOrder to apply them anyway."
+}
+
+// Validate runs the static checks on the list of migrations and the
+// migration table without executing any migration, so it can be used in CI
+// to fail fast before a deploy: missing, reserved, duplicated and malformed
+// IDs, timestamp conflicts, migrations gated on a flag without
+// Options.Flags, migrations without rollback with Options.RequireRollback,
+// applied migrations missing from the list, out of order and changed
+// migrations, and statements violating Options.Policies.
+func (g *Sqlxmigrate) Validate() error {
+	return g.validate(context.Background())
+}
+
+func (g *Sqlxmigrate) validate(ctx context.Context) error {
+	if !g.hasMigrations() {
+		return ErrNoMigrationDefined
+	}
+
+	if err := g.checkMissingID(); err != nil {
+		return err
+	}
+
+	if err := g.checkReservedID(); err != nil {
+		return err
+	}
+
+	if err := g.checkDuplicatedID(); err != nil {
+		return err
+	}
+
+	if err := g.checkIDFormat(); err != nil {
+		return err
+	}
+
+	if err := g.checkIDOrder(); err != nil {
+		return err
+	}
+
+	if err := g.checkDependencies(); err != nil {
+		return err
+	}
+
+	if err := g.checkGroups(); err != nil {
+		return err
+	}
+
+	if err := g.checkRollbacks(); err != nil {
+		return err
+	}
+
+	if err := g.checkTimestampConflict(); err != nil {
+		return err
+	}
+
+	if err := g.checkFlags(); err != nil {
+		return err
+	}
+
+	if err := g.compatibilityCheck(ctx); err != nil {
+		return err
+	}
+
+	if err := g.checkOrphanedMigrations(ctx); err != nil {
+		return err
+	}
+
+	if err := g.checkOutOfOrder(ctx, ""); err != nil {
+		return err
+	}
+
+	if err := g.checkInitSchemaChecksum(ctx); err != nil {
+		return err
+	}
+
+	if err := g.checkSquashed(ctx); err != nil {
+		return err
+	}
+
+	if err := g.checkChecksums(ctx); err != nil {
+		return err
+	}
+
+	if err := g.checkPolicies(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (g *Sqlxmigrate) checkMissingID() error {
+	for _, m := range g.migrations {
+		if len(m.ID) == 0 {
+			return ErrMissingID
+		}
+	}
+	return nil
+}
+
+// Check whether the IDs of the migrations match Options.IDPattern and fit in
+// the ID column.
+func (g *Sqlxmigrate) checkIDFormat() error {
+	for _, m := range g.migrations {
+		if len(m.ID) > g.options.IDColumnSize {
+			return &IDTooLongError{ID: m.ID, Size: g.options.IDColumnSize}
+		}
+		// The IDs of the contract migrations are generated, their expand
+		// migration is checked.
+		if g.options.IDPattern != nil && m.expandID == "" && !g.options.IDPattern.MatchString(m.ID) {
+			return &InvalidIDError{ID: m.ID, Pattern: g.options.IDPattern}
+		}
+	}
+	return nil
+}
+
+// Check whether the migrations gated on a flag can be checked.
+func (g *Sqlxmigrate) checkFlags() error {
+	if g.options.Flags != nil {
+		return nil
+	}
+	for _, m := range g.migrations {
+		if m.Flag != "" {
+			return fmt.Errorf(`sqlxmigrate: Migration "%s" is gated on the flag "%s" but Options.Flags is nil`, m.ID, m.Flag)
+		}
+	}
+	return nil
+}
+
+// Check whether two different migrations share the same timestamp prefix,
+// ie. "201906121200_create_people" and "201906121200_create_pets".
+func (g *Sqlxmigrate) checkTimestampConflict() error {
+	var prefixes []string
+	lookup := make(map[string][]string, len(g.migrations))
+	for _, m := range g.migrations {
+		prefix := timestampPrefix(m.ID)
+		// The contract migrations share the prefix of their expand migration.
+		if prefix == "" || m.expandID != "" {
+			continue
+		}
+		if _, ok := lookup[prefix]; !ok {
+			prefixes = append(prefixes, prefix)
+		}
+		lookup[prefix] = append(lookup[prefix], m.ID)
+	}
+
+	for _, prefix := range prefixes {
+		if ids := lookup[prefix]; len(ids) > 1 {
+			return &TimestampConflictError{Prefix: prefix, IDs: ids}
+		}
+	}
+	return nil
+}
+
+// Check whether the migration table contains IDs that are not in the list
+// of migrations anymore.
+func (g *Sqlxmigrate) checkOrphanedMigrations(ctx context.Context) error {
+	if g.options.OrphanedMigrations == SeverityIgnore {
+		return nil
+	}
+
+	unknown, err := g.unknownAppliedIDs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	err = &OrphanedMigrationsError{IDs: unknown}
+	if g.options.OrphanedMigrations == SeverityError {
+		return err
+	}
+	g.logf("Validate - %v", err)
+	return nil
+}
+
+// timestampPrefix returns the leading digits of a migration ID.
+func timestampPrefix(id string) string {
+	for i, r := range id {
+		if r < '0' || r > '9' {
+			return id[:i]
+		}
+	}
+	return id
+}
+
+// checkChecksums verifies the applied migrations with a checksum didn't
+// change since they were recorded.
+func (g *Sqlxmigrate) checkChecksums(ctx context.Context) error {
+	metadata, err := g.appliedMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range g.migrations {
+		stored, computed := metadata[m.ID].checksum, m.scriptChecksum()
+		if computed != "" && stored != "" && stored != computed {
+			return &ChecksumMismatchError{ID: m.ID, Stored: stored, Computed: computed}
+		}
+	}
+	return nil
+}
+
+// Check whether pending migrations up to the migration that matches
+// `migrationID` sort before the last applied migration. The migrations the
+// runs defer are expected to.
+func (g *Sqlxmigrate) checkOutOfOrder(ctx context.Context, migrationID string) error {
+	if g.options.OutOfOrder != SeverityError {
+		return nil
+	}
+
+	appliedIDs, err := g.appliedIDs(ctx)
+	if err != nil {
+		return err
+	}
+	applied := make(map[string]struct{}, len(appliedIDs))
+	for _, id := range appliedIDs {
+		applied[id] = struct{}{}
+	}
+	lastApplied := g.lastAppliedID(appliedIDs)
+
+	var ids []string
+	for _, m := range g.migrations {
+		if _, ok := applied[m.ID]; !ok && lastApplied != "" && g.compareIDs(m.ID, lastApplied) < 0 && !m.gated() {
+			ids = append(ids, m.ID)
+		}
+		if migrationID != "" && m.ID == migrationID {
+			break
+		}
+	}
+	if len(ids) > 0 {
+		return &OutOfOrderError{IDs: ids, LastApplied: lastApplied}
+	}
+	return nil
+}
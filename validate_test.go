@@ -0,0 +1,196 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	noop := func(tx *sql.Tx) error {
+		return nil
+	}
+
+	forEachDatabase(t, func(db *sqlx.DB) {
+		t.Run("valid", func(t *testing.T) {
+			m := New(db, DefaultOptions, extendedMigrations)
+			assert.NoError(t, m.Validate())
+		})
+
+		t.Run("no migrations", func(t *testing.T) {
+			m := New(db, DefaultOptions, nil)
+			assert.Equal(t, ErrNoMigrationDefined, m.Validate())
+		})
+
+		t.Run("missing id", func(t *testing.T) {
+			m := New(db, DefaultOptions, []*Migration{{Migrate: noop}})
+			assert.Equal(t, ErrMissingID, m.Validate())
+		})
+
+		t.Run("timestamp conflict", func(t *testing.T) {
+			m := New(db, DefaultOptions, []*Migration{
+				{ID: "201906121200_create_people", Migrate: noop},
+				{ID: "201906121215_create_pets", Migrate: noop},
+				{ID: "201906121200_create_books", Migrate: noop},
+			})
+
+			err := m.Validate()
+			require.IsType(t, &TimestampConflictError{}, err)
+			conflict := err.(*TimestampConflictError)
+			assert.Equal(t, "201906121200", conflict.Prefix)
+			assert.Equal(t, []string{"201906121200_create_people", "201906121200_create_books"}, conflict.IDs)
+		})
+
+		t.Run("ids without timestamp", func(t *testing.T) {
+			m := New(db, DefaultOptions, []*Migration{
+				{ID: "create_people", Migrate: noop},
+				{ID: "create_pets", Migrate: noop},
+			})
+			assert.NoError(t, m.Validate())
+		})
+
+		t.Run("id pattern", func(t *testing.T) {
+			options := *DefaultOptions
+			options.IDPattern = regexp.MustCompile(`^\d{12}_[a-z_]+$`)
+			m := New(db, &options, []*Migration{
+				{ID: "201906121200_create_people", Migrate: noop},
+				{ID: "201906121215-create-pets", Migrate: noop},
+			})
+			assert.Equal(t, &InvalidIDError{ID: "201906121215-create-pets", Pattern: options.IDPattern}, m.Validate())
+			assert.Equal(t, &InvalidIDError{ID: "201906121215-create-pets", Pattern: options.IDPattern}, m.Migrate())
+		})
+
+		t.Run("id too long", func(t *testing.T) {
+			options := *DefaultOptions
+			options.IDColumnSize = 16
+			m := New(db, &options, []*Migration{{ID: "201906121200_create_people", Migrate: noop}})
+			assert.EqualError(t, m.Validate(), `sqlxmigrate: Migration ID "201906121200_create_people" is longer than the 16 characters of the ID column`)
+		})
+
+		t.Run("flag without provider", func(t *testing.T) {
+			m := New(db, DefaultOptions, []*Migration{{ID: "201906121200", Flag: "people", Migrate: noop}})
+			assert.EqualError(t, m.Validate(), `sqlxmigrate: Migration "201906121200" is gated on the flag "people" but Options.Flags is nil`)
+		})
+	})
+}
+
+func TestValidateOrphanedMigrations(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.Migrate())
+
+		m = New(db, &Options{OrphanedMigrations: SeverityWarn}, migrations)
+		assert.NoError(t, m.Validate())
+
+		m = New(db, &Options{OrphanedMigrations: SeverityIgnore}, migrations)
+		assert.NoError(t, m.Validate())
+
+		m = New(db, &Options{OrphanedMigrations: SeverityError}, migrations)
+		err := m.Validate()
+		require.IsType(t, &OrphanedMigrationsError{}, err)
+		assert.Equal(t, []string{"201807221927"}, err.(*OrphanedMigrationsError).IDs)
+
+		// The runs fail before applying anything.
+		failing := append(migrations[:2:2], &Migration{
+			ID: "201906121200",
+			Migrate: func(tx *sql.Tx) error {
+				t.Fatal("migration applied despite the orphaned migration")
+				return nil
+			},
+		})
+		m = New(db, &Options{OrphanedMigrations: SeverityError}, failing)
+		err = m.Migrate()
+		assert.True(t, errors.Is(err, ErrUnknownAppliedMigration))
+		assert.Equal(t, &OrphanedMigrationsError{IDs: []string{"201807221927"}}, err)
+
+		m = New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.RollbackTo("201608301400"))
+	})
+}
+
+func TestOutOfOrder(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, extendedMigrations)
+		require.NoError(t, m.Migrate())
+
+		merged := &Migration{
+			ID: "201608301415",
+			Migrate: func(tx *sql.Tx) error {
+				return nil
+			},
+			Rollback: func(tx *sql.Tx) error {
+				return nil
+			},
+		}
+		mergedMigrations := []*Migration{migrations[0], merged, migrations[1], extendedMigrations[2]}
+
+		options := *DefaultOptions
+		options.OutOfOrder = SeverityError
+		m = New(db, &options, mergedMigrations)
+		err := m.Validate()
+		require.IsType(t, &OutOfOrderError{}, err)
+		assert.Equal(t, &OutOfOrderError{IDs: []string{"201608301415"}, LastApplied: "201807221927"}, err)
+
+		err = m.Migrate()
+		assert.True(t, errors.Is(err, ErrOutOfOrderMigration))
+		applied, err := m.appliedIDs(context.Background())
+		require.NoError(t, err)
+		assert.NotContains(t, applied, "201608301415")
+
+		// Up to a migration applied before, nothing is out of order.
+		assert.NoError(t, m.MigrateTo("201608301400"))
+
+		options.OutOfOrder = SeverityIgnore
+		m = New(db, &options, mergedMigrations)
+		res, err := m.MigrateWithResult()
+		require.NoError(t, err)
+		assert.Equal(t, "201608301415", res.Migrations[0].ID)
+		for _, w := range res.Warnings {
+			assert.NotEqual(t, WarningOutOfOrder, w.Code)
+		}
+
+		require.NoError(t, m.RollbackTo("201608301400"))
+	})
+}
+
+func TestRequireRollback(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		options := *DefaultOptions
+		options.RequireRollback = true
+		irreversible := []*Migration{
+			{
+				ID: "201608301500",
+				Migrate: func(tx *sql.Tx) error {
+					_, err := tx.Exec(`CREATE TABLE cars (id int PRIMARY KEY)`)
+					return err
+				},
+			},
+		}
+
+		m := New(db, &options, irreversible)
+		err := m.Validate()
+		var missing *MissingRollbackError
+		require.True(t, errors.As(err, &missing))
+		assert.Equal(t, "201608301500", missing.ID)
+		assert.NotEmpty(t, Hint(err))
+		assert.True(t, errors.As(m.Migrate(), &missing))
+		assert.False(t, m.hasTable("cars"))
+
+		irreversible[0].Rollback = NoRollback
+		m = New(db, &options, irreversible)
+		require.NoError(t, m.Validate())
+		require.NoError(t, m.Migrate())
+		assert.True(t, m.hasTable("cars"))
+
+		assert.True(t, errors.Is(m.RollbackLast(), ErrRollbackImpossible))
+		assert.True(t, m.hasTable("cars"))
+
+		require.NoError(t, New(db, &options, migrations).Validate())
+	})
+}
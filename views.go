@@ -0,0 +1,106 @@
+package sqlxmigrate
+
+import (
+	"fmt"
+)
+
+// DependentView is a view depending on a table, directly or through other
+// views, with the definition it is recreated from.
+type DependentView struct {
+	// Name is the qualified and quoted name of the view.
+	Name string
+	// Materialized is whether the view is a materialized view.
+	Materialized bool
+	// Definition is the query of the view.
+	Definition string
+}
+
+func (v DependentView) kind() string {
+	if v.Materialized {
+		return "MATERIALIZED VIEW"
+	}
+	return "VIEW"
+}
+
+// dependentViewsQuery walks the rewrite rules of the views down from the
+// table. A view is ordered by its longest path to the table, so it follows
+// the views it depends on.
+const dependentViewsQuery = `WITH RECURSIVE deps (oid, depth) AS (
+	SELECT DISTINCT r.ev_class, 1
+	FROM pg_depend d
+	JOIN pg_rewrite r ON r.oid = d.objid
+	WHERE d.classid = 'pg_rewrite'::regclass AND d.refobjid = ?::regclass AND r.ev_class <> d.refobjid
+	UNION
+	SELECT r.ev_class, deps.depth + 1
+	FROM deps
+	JOIN pg_depend d ON d.refobjid = deps.oid
+	JOIN pg_rewrite r ON r.oid = d.objid
+	WHERE d.classid = 'pg_rewrite'::regclass AND r.ev_class <> deps.oid
+)
+SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname), c.relkind = 'm', pg_get_viewdef(c.oid, true)
+FROM deps
+JOIN pg_class c ON c.oid = deps.oid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('v', 'm')
+GROUP BY c.oid, n.nspname, c.relname, c.relkind
+ORDER BY max(deps.depth), n.nspname, c.relname`
+
+// DependentViews returns the views depending on a table, directly or
+// through other views, in the order they are created. It is only supported
+// by PostgreSQL.
+func DependentViews(tx *Tx, table string) ([]DependentView, error) {
+	if !tx.g.isPostgres() {
+		return nil, ErrUnsupportedDriver
+	}
+
+	query := tx.g.rebind(dependentViewsQuery)
+	rows, err := tx.Query(query, table)
+	if err != nil {
+		err = queryError(err, query)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []DependentView
+	for rows.Next() {
+		var v DependentView
+		if err := rows.Scan(&v.Name, &v.Materialized, &v.Definition); err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+// WithDependentViews drops the views depending on a table, calls fn to
+// change the table, ie. the type of a column the views reference, then
+// recreates the views from their definitions, so PostgreSQL doesn't reject
+// the change. It is only supported by PostgreSQL.
+//
+// The views are recreated with their query only: their grants, comments and
+// the indexes of the materialized views must be recreated by fn.
+func WithDependentViews(tx *Tx, table string, fn func() error) error {
+	views, err := DependentViews(tx, table)
+	if err != nil {
+		return err
+	}
+
+	for i := len(views) - 1; i >= 0; i-- {
+		tx.g.logf("Migration %s - dropping %s %s", tx.migrationID, views[i].kind(), views[i].Name)
+		if _, err := tx.Exec(fmt.Sprintf("DROP %s %s", views[i].kind(), views[i].Name)); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	for _, v := range views {
+		tx.g.logf("Migration %s - recreating %s %s", tx.migrationID, v.kind(), v.Name)
+		if _, err := tx.Exec(fmt.Sprintf("CREATE %s %s AS %s", v.kind(), v.Name, v.Definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
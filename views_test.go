@@ -0,0 +1,71 @@
+package sqlxmigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDependentViews(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		require.NoError(t, New(db, DefaultOptions, migrations[:1]).Migrate())
+		defer func() {
+			_, err := db.Exec(`DROP VIEW IF EXISTS people_names_upper; DROP MATERIALIZED VIEW IF EXISTS people_names_count; DROP VIEW IF EXISTS people_names`)
+			assert.NoError(t, err)
+		}()
+		_, err := db.Exec(`CREATE VIEW people_names AS SELECT id, name FROM people;
+CREATE VIEW people_names_upper AS SELECT id, upper(name) AS name FROM people_names;
+CREATE MATERIALIZED VIEW people_names_count AS SELECT count(*) AS n FROM people_names`)
+		require.NoError(t, err)
+
+		var views []DependentView
+		widen := &Migration{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				var err error
+				if views, err = DependentViews(tx, "people"); err != nil {
+					return err
+				}
+				return WithDependentViews(tx, "people", func() error {
+					_, err := tx.Exec(`ALTER TABLE people ALTER COLUMN name TYPE varchar(200)`)
+					return err
+				})
+			},
+			RollbackTx: func(tx *Tx) error {
+				return nil
+			},
+		}
+		m := New(db, DefaultOptions, append(migrations[:1:1], widen))
+		require.NoError(t, m.Migrate())
+
+		require.Len(t, views, 3)
+		assert.Equal(t, "public.people_names", views[0].Name)
+		assert.False(t, views[0].Materialized)
+		assert.Equal(t, "public.people_names_count", views[1].Name)
+		assert.True(t, views[1].Materialized)
+		assert.Equal(t, "public.people_names_upper", views[2].Name)
+
+		var typ string
+		require.NoError(t, db.Get(&typ, `SELECT data_type FROM information_schema.columns WHERE table_name = 'people_names' AND column_name = 'name'`))
+		assert.Equal(t, "character varying", typ)
+
+		require.NoError(t, m.RollbackLast())
+	}, "postgres")
+}
+
+func TestDependentViewsUnsupported(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		m := New(db, DefaultOptions, []*Migration{{
+			ID: "201608301400",
+			MigrateTx: func(tx *Tx) error {
+				return WithDependentViews(tx, "people", func() error {
+					return nil
+				})
+			},
+		}})
+		assert.True(t, errors.Is(m.Migrate(), ErrUnsupportedDriver))
+	}, "sqlite3", "mysql", "sqlserver")
+}
@@ -0,0 +1,116 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// WALBudgetError is returned by Batched and BatchedDB once their batches
+// generated more WAL, or binary log on MySQL, than BatchOptions.WALBudget.
+type WALBudgetError struct {
+	Table string
+	// Bytes are the bytes generated since the first batch.
+	Bytes  int64
+	Budget int64
+}
+
+func (e *WALBudgetError) Error() string {
+	return fmt.Sprintf(`sqlxmigrate: Batches of "%s" generated %d bytes of WAL, over the budget of %d bytes`, e.Table, e.Bytes, e.Budget)
+}
+
+// Hint implements Hinter.
+func (e *WALBudgetError) Hint() string {
+	return "Split the backfill over several runs so the replicas and the WAL archive keep up, or raise BatchOptions.WALBudget."
+}
+
+// walPosition returns the position of the WAL in bytes: the insert position
+// on PostgreSQL, the size of the binary logs on MySQL. The binary log of a
+// transaction is only written once it commits. Only PostgreSQL and MySQL are
+// supported, and the query fails when the server doesn't expose it, ie. on
+// CockroachDB or without binary logging.
+func walPosition(ctx context.Context, q Execer, driverName string) (int64, error) {
+	switch {
+	case isPostgresDriver(driverName):
+		query := "SELECT pg_wal_lsn_diff(pg_current_wal_insert_lsn(), '0/0')::bigint"
+		var pos int64
+		if err := q.QueryRowContext(ctx, query).Scan(&pos); err != nil {
+			err = queryError(err, query)
+			return 0, err
+		}
+		return pos, nil
+	case isMySQLDriver(driverName):
+		return mysqlBinaryLogsSize(ctx, q)
+	default:
+		return 0, ErrUnsupportedDriver
+	}
+}
+
+// mysqlBinaryLogsSize sums the File_size of SHOW BINARY LOGS, whose other
+// columns depend on the version of the server.
+func mysqlBinaryLogsSize(ctx context.Context, q Execer) (int64, error) {
+	query := "SHOW BINARY LOGS"
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		err = queryError(err, query)
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var size int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return 0, err
+		}
+		for i, c := range columns {
+			if c != "File_size" {
+				continue
+			}
+			n, err := strconv.ParseInt(string(values[i]), 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			size += n
+		}
+	}
+	return size, rows.Err()
+}
+
+// trackWAL reads the WAL position at the start of a run. The returned
+// function sets the bytes generated since on the result of the run, left to
+// 0 when the server doesn't expose the position.
+func (g *Sqlxmigrate) trackWAL(ctx context.Context) func(*Result) {
+	driverName := g.db.DriverName()
+	start, err := walPosition(ctx, g.db, driverName)
+	if err != nil {
+		if err != ErrUnsupportedDriver {
+			g.logf("WAL - not tracked - %v", err)
+		}
+		return func(*Result) {}
+	}
+
+	return func(res *Result) {
+		if res == nil {
+			return
+		}
+		// The run can end because its context is done.
+		end, err := walPosition(context.Background(), g.db, driverName)
+		if err != nil {
+			g.logf("WAL - not tracked - %v", err)
+			return
+		}
+		res.WALBytes = end - start
+		g.logf("Run generated %d bytes of WAL", res.WALBytes)
+	}
+}
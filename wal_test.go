@@ -0,0 +1,65 @@
+package sqlxmigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALBudget(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		_, err := db.Exec(`CREATE TABLE cars (id int PRIMARY KEY, name varchar(20))`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO cars (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e')`)
+		require.NoError(t, err)
+
+		var affected int64
+		m := New(db, DefaultOptions, []*Migration{{
+			ID: "201608301500",
+			MigrateTx: func(tx *Tx) error {
+				var err error
+				affected, err = Batched(tx, "UPDATE cars SET name = 'backfilled' WHERE id > ? AND id <= ?", &BatchOptions{
+					Table:     "cars",
+					KeyColumn: "id",
+					BatchSize: 2,
+					WALBudget: 1,
+				})
+				return err
+			},
+		}})
+
+		res, err := m.MigrateWithResult()
+		var budgetErr *WALBudgetError
+		require.True(t, errors.As(err, &budgetErr))
+		assert.Equal(t, "cars", budgetErr.Table)
+		assert.True(t, budgetErr.Bytes > 1)
+		assert.NotEmpty(t, Hint(err))
+		// The batches stopped after the first one.
+		assert.Equal(t, int64(2), affected)
+		assert.True(t, res.WALBytes > 0)
+	}, "postgres")
+}
+
+func TestWALBudgetUnsupported(t *testing.T) {
+	forEachDatabase(t, func(db *sqlx.DB) {
+		_, err := db.Exec(`CREATE TABLE cars (id int PRIMARY KEY, name varchar(20))`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO cars (id, name) VALUES (1, 'a')`)
+		require.NoError(t, err)
+
+		_, err = BatchedDB(context.Background(), db, "UPDATE cars SET name = 'backfilled' WHERE id > ? AND id <= ?", &BatchOptions{
+			Table:     "cars",
+			KeyColumn: "id",
+			WALBudget: 1,
+		})
+		assert.True(t, errors.Is(err, ErrUnsupportedDriver))
+
+		res, err := New(db, DefaultOptions, migrations).MigrateWithResult()
+		require.NoError(t, err)
+		assert.Zero(t, res.WALBytes)
+	}, "sqlite3", "sqlserver")
+}